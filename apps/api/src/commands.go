@@ -0,0 +1,185 @@
+package main
+
+import (
+	"api/src/internal/models"
+	"api/src/internal/utils"
+	"api/src/services"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: api [command]
+
+commands:
+  serve                      run the API server (default)
+  migrate <command>          manage schema migrations; see "api migrate"
+  seed [-sample]             seed required data, and sample data with -sample
+  user promote <user>        grant admin
+  user demote <user>         revoke admin
+  user delete -yes <user>    delete a user and everything they own
+  sessions revoke <user>     sign a user out of every device
+  cache flush                drop cached values (sessions are kept)
+  tags import <file>         upsert tags from a YAML or JSON list of {slug, label, category}
+
+<user> is a user ID, email address or username.
+`
+
+// runCommand dispatches the command line to a subcommand and returns the process exit
+// code. Operational commands go through the service layer so they behave exactly like
+// the matching API calls.
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		serve()
+		return 0
+	}
+
+	switch args[0] {
+	case "serve":
+		serve()
+		return 0
+	case "migrate":
+		return runMigrateCommand(args[1:])
+	case "seed":
+		return runSeedCommand(args[1:])
+	case "user":
+		return runUserCommand(args[1:])
+	case "sessions":
+		return runSessionsCommand(args[1:])
+	case "cache":
+		return runCacheCommand(args[1:])
+	case "tags":
+		return runTagsCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+func runSeedCommand(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	sample := fs.Bool("sample", false, "also add sample restaurants and users to empty tables")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db := mustConnectToDatabase()
+	if err := utils.SeedRequiredData(db); err != nil {
+		return fail(err)
+	}
+	if *sample {
+		if err := utils.SeedSampleData(db); err != nil {
+			return fail(err)
+		}
+	}
+	return 0
+}
+
+func runUserCommand(args []string) int {
+	if len(args) < 1 || (args[0] != "promote" && args[0] != "demote" && args[0] != "delete") {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm deletion")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	ctx := context.Background()
+	db := mustConnectToDatabase()
+	user, err := services.FindUser(ctx, db, fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+
+	switch args[0] {
+	case "promote", "demote":
+		admin := args[0] == "promote"
+		if err := services.SetUserAdmin(ctx, db, user.ID, admin); err != nil {
+			return fail(err)
+		}
+		fmt.Printf("%s: admin=%t\n", describeUser(user), admin)
+		return 0
+	default: // delete
+		if !*yes {
+			fmt.Fprintf(os.Stderr, "refusing to delete %s without -yes\n", describeUser(user))
+			return 1
+		}
+		if err := services.DeleteUserAccount(ctx, db, mustConnectCache(), user.ID); err != nil {
+			return fail(err)
+		}
+		fmt.Printf("deleted %s\n", describeUser(user))
+		return 0
+	}
+}
+
+func runSessionsCommand(args []string) int {
+	if len(args) != 2 || args[0] != "revoke" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	ctx := context.Background()
+	db := mustConnectToDatabase()
+	user, err := services.FindUser(ctx, db, args[1])
+	if err != nil {
+		return fail(err)
+	}
+	if err := services.RevokeUserSessions(ctx, db, mustConnectCache(), user.ID); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("signed out %s everywhere\n", describeUser(user))
+	return 0
+}
+
+func runCacheCommand(args []string) int {
+	if len(args) != 1 || args[0] != "flush" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	removed, err := services.FlushCaches(context.Background(), mustConnectCache())
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Printf("removed %d cached keys\n", removed)
+	return 0
+}
+
+func runTagsCommand(args []string) int {
+	if len(args) != 2 || args[0] != "import" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	raw, err := os.ReadFile(args[1])
+	if err != nil {
+		return fail(err)
+	}
+	tags, err := utils.ParseTags(raw)
+	if err != nil {
+		return fail(fmt.Errorf("%s: %w", args[1], err))
+	}
+	if err := services.ImportTags(context.Background(), mustConnectToDatabase(), mustConnectCache(), tags); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("imported %d tags\n", len(tags))
+	return 0
+}
+
+func describeUser(u *models.User) string {
+	if u.Email != nil {
+		return fmt.Sprintf("%s (%s)", u.ID, *u.Email)
+	}
+	return u.ID
+}
+
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
@@ -0,0 +1,223 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names. Notification emails reuse the notification type as their name.
+const (
+	TemplateFriendRequestReceived       = "friend_request_received"
+	TemplateFriendRequestAccepted       = "friend_request_accepted"
+	TemplateFriendReviewedWishlistPlace = "friend_reviewed_wishlist_place"
+	TemplateWeeklyDigest                = "weekly_digest"
+)
+
+// DefaultLocale is used for users without a supported DefaultLanguage.
+const DefaultLocale = "en"
+
+// maxDigestLines caps each digest section; the rest is summarised as "…and N more".
+const maxDigestLines = 10
+
+//go:embed messages/*.json
+var messageFiles embed.FS
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var (
+	catalogs     = mustLoadCatalogs()
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/layout.html.tmpl"))
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/layout.txt.tmpl"))
+)
+
+// Params holds everything a template may need. Fields a template doesn't use are ignored.
+type Params struct {
+	Locale            string
+	RecipientName     string
+	ActorName         string
+	PlaceName         string
+	ActionURL         string
+	UnsubscribeURL    string
+	UnsubscribeAllURL string
+	Digest            *Digest
+}
+
+// Digest is the content of a weekly digest email.
+type Digest struct {
+	Since        time.Time
+	WishlistHits []DigestEntry
+	Reviews      []DigestEntry
+}
+
+// DigestEntry is one friend review mentioned in a digest.
+type DigestEntry struct {
+	ActorName string
+	PlaceName string
+	Rating    float64
+}
+
+// Empty reports whether there is nothing worth sending.
+func (d *Digest) Empty() bool {
+	return d == nil || (len(d.WishlistHits) == 0 && len(d.Reviews) == 0)
+}
+
+// Rendered is a localized email body. From and To are filled in by the sender.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type section struct {
+	Heading string
+	Lines   []string
+}
+
+type layoutData struct {
+	Locale              string
+	Subject             string
+	Greeting            string
+	Paragraphs          []string
+	Sections            []section
+	ActionLabel         string
+	ActionURL           string
+	FooterReason        string
+	UnsubscribeLabel    string
+	UnsubscribeURL      string
+	UnsubscribeAllLabel string
+	UnsubscribeAllURL   string
+}
+
+// Render produces the subject and text/HTML bodies of the named template.
+func Render(name string, p Params) (Rendered, error) {
+	locale := NormalizeLocale(p.Locale)
+	t := func(key string, vars map[string]string) string { return Translate(locale, key, vars) }
+	vars := map[string]string{"actor": p.ActorName, "place": p.PlaceName}
+
+	data := layoutData{
+		Locale:              locale,
+		Greeting:            t("greeting_anonymous", nil),
+		ActionURL:           p.ActionURL,
+		FooterReason:        t("footer_reason", nil),
+		UnsubscribeLabel:    t("footer_unsubscribe", nil),
+		UnsubscribeURL:      p.UnsubscribeURL,
+		UnsubscribeAllLabel: t("footer_unsubscribe_all", nil),
+		UnsubscribeAllURL:   p.UnsubscribeAllURL,
+	}
+	if p.RecipientName != "" {
+		data.Greeting = t("greeting", map[string]string{"name": p.RecipientName})
+	}
+
+	switch name {
+	case TemplateFriendRequestReceived, TemplateFriendRequestAccepted, TemplateFriendReviewedWishlistPlace:
+		data.Subject = t(name+"_subject", vars)
+		data.Paragraphs = []string{t(name+"_body", vars)}
+		data.ActionLabel = t(name+"_action", nil)
+	case TemplateWeeklyDigest:
+		if p.Digest.Empty() {
+			return Rendered{}, fmt.Errorf("weekly digest has no content")
+		}
+		data.Subject = t("weekly_digest_subject", nil)
+		data.Paragraphs = []string{t("weekly_digest_intro", map[string]string{"since": p.Digest.Since.Format("2006-01-02")})}
+		if len(p.Digest.WishlistHits) > 0 {
+			data.Sections = append(data.Sections, digestSection(locale, "weekly_digest_wishlist_heading", p.Digest.WishlistHits))
+		}
+		if len(p.Digest.Reviews) > 0 {
+			data.Sections = append(data.Sections, digestSection(locale, "weekly_digest_reviews_heading", p.Digest.Reviews))
+		}
+		data.ActionLabel = t("weekly_digest_action", nil)
+	default:
+		return Rendered{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return Rendered{}, err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{Subject: data.Subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+func digestSection(locale, headingKey string, entries []DigestEntry) section {
+	s := section{Heading: Translate(locale, headingKey, nil)}
+	for i, e := range entries {
+		if i == maxDigestLines {
+			s.Lines = append(s.Lines, Translate(locale, "weekly_digest_more", map[string]string{"count": strconv.Itoa(len(entries) - i)}))
+			break
+		}
+		s.Lines = append(s.Lines, Translate(locale, "weekly_digest_review_line", map[string]string{
+			"actor":  e.ActorName,
+			"place":  e.PlaceName,
+			"rating": formatRating(locale, e.Rating),
+		}))
+	}
+	return s
+}
+
+func formatRating(locale string, rating float64) string {
+	s := strconv.FormatFloat(rating, 'f', -1, 64)
+	if locale == "pl" {
+		s = strings.ReplaceAll(s, ".", ",")
+	}
+	return s
+}
+
+// NormalizeLocale maps a user's DefaultLanguage ("pl", "pl-PL", "en_US", "") to a
+// supported locale, falling back to DefaultLocale.
+func NormalizeLocale(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return DefaultLocale
+}
+
+// Translate looks up key in the locale's catalog (falling back to DefaultLocale) and
+// substitutes {placeholders} from vars. Unknown keys are returned as-is.
+func Translate(locale, key string, vars map[string]string) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	for k, v := range vars {
+		msg = strings.ReplaceAll(msg, "{"+k+"}", v)
+	}
+	return msg
+}
+
+// mustLoadCatalogs reads the embedded inlang message files (same format as apps/web/messages).
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := messageFiles.ReadDir("messages")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		raw, err := messageFiles.ReadFile("messages/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(raw, &catalog); err != nil {
+			panic(fmt.Sprintf("email messages %s: %v", entry.Name(), err))
+		}
+		delete(catalog, "$schema")
+		out[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	return out
+}
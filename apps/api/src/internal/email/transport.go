@@ -0,0 +1,94 @@
+// Package email renders and delivers transactional emails.
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a rendered email ready to be handed to a Transport.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Extra headers such as List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Transport delivers messages. Implementations must be safe for concurrent use.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewTransportFromEnv builds the transport selected by EMAIL_TRANSPORT:
+// "smtp", "file" (writes .eml files to EMAIL_FILE_DIR) or "log" (the default).
+func NewTransportFromEnv() (Transport, error) {
+	switch kind := os.Getenv("EMAIL_TRANSPORT"); kind {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when EMAIL_TRANSPORT=smtp")
+		}
+		port := 587
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			var err error
+			if port, err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q: %w", p, err)
+			}
+		}
+		return &SMTPTransport{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}, nil
+	case "file":
+		dir := os.Getenv("EMAIL_FILE_DIR")
+		if dir == "" {
+			dir = filepath.Join("tmp", "emails")
+		}
+		return &FileTransport{Dir: dir}, nil
+	case "", "log":
+		return LogTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_TRANSPORT %q", kind)
+	}
+}
+
+// LogTransport logs messages instead of sending them. Useful in development.
+type LogTransport struct{}
+
+func (LogTransport) Send(_ context.Context, msg Message) error {
+	slog.Info("Email (log transport)",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("text", msg.Text))
+	return nil
+}
+
+// FileTransport writes each message as an .eml file into Dir, which can be opened
+// in any mail client to check rendering.
+type FileTransport struct {
+	Dir string
+}
+
+func (t *FileTransport) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+	raw, err := buildMIME(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405") + "-" + uuid.NewString() + ".eml"
+	return os.WriteFile(filepath.Join(t.Dir, name), raw, 0o644)
+}
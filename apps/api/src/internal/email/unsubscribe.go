@@ -0,0 +1,49 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// UnsubscribeAll is the scope that turns off every email for a user.
+const UnsubscribeAll = "all"
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// SignUnsubscribeToken returns a token that lets its holder turn off emails of the given
+// scope (a template name or UnsubscribeAll) for userID without signing in. Tokens don't
+// expire, so links in old emails keep working.
+func SignUnsubscribeToken(secret []byte, userID, scope string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID + "|" + scope))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(unsubscribeMAC(secret, payload))
+}
+
+// ParseUnsubscribeToken verifies a token created by SignUnsubscribeToken.
+func ParseUnsubscribeToken(secret []byte, token string) (userID, scope string, err error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, unsubscribeMAC(secret, payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	userID, scope, ok = strings.Cut(string(raw), "|")
+	if !ok || userID == "" || scope == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return userID, scope, nil
+}
+
+func unsubscribeMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("unsubscribe:" + payload))
+	return mac.Sum(nil)
+}
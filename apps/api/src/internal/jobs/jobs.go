@@ -0,0 +1,127 @@
+// Package jobs runs background work from a Postgres-backed queue. Any number of API
+// replicas can run a Runner against the same database: jobs are claimed with
+// SELECT … FOR UPDATE SKIP LOCKED and recurring jobs are fired by whichever replica
+// first advances the schedule row.
+package jobs
+
+import (
+	"api/src/internal/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultTimeout     = 5 * time.Minute
+)
+
+// Kind names a type of job and the payload it carries. Declare one per job type and
+// use it both to enqueue and to register the handler, so the two can't disagree.
+type Kind[T any] struct {
+	Name string
+	// Defaults to 5.
+	MaxAttempts int32
+	// How long a single attempt may run; also bounds how long a crashed replica holds
+	// the job. Defaults to 5 minutes.
+	Timeout time.Duration
+	// Delay before the next attempt after the given number of failed ones.
+	// Defaults to DefaultBackoff.
+	Backoff func(attempts int) time.Duration
+}
+
+func (k Kind[T]) maxAttempts() int32 {
+	if k.MaxAttempts > 0 {
+		return k.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+func (k Kind[T]) timeout() time.Duration {
+	if k.Timeout > 0 {
+		return k.Timeout
+	}
+	return defaultTimeout
+}
+
+func (k Kind[T]) backoff() func(int) time.Duration {
+	if k.Backoff != nil {
+		return k.Backoff
+	}
+	return DefaultBackoff
+}
+
+// EnqueueOption customises a single enqueued job.
+type EnqueueOption func(*models.Job)
+
+// RunAt delays the job until t.
+func RunAt(t time.Time) EnqueueOption {
+	return func(j *models.Job) { j.RunAt = t }
+}
+
+// Dedupe makes the enqueue a no-op while another job with the same key is still
+// pending. Once that job starts running, the key is free again.
+func Dedupe(key string) EnqueueOption {
+	return func(j *models.Job) { j.DedupeKey = &key }
+}
+
+// Enqueue adds a job to the queue. Pass the transaction of the write that produced
+// the work so the job only exists if that write commits.
+func (k Kind[T]) Enqueue(tx *gorm.DB, payload T, opts ...EnqueueOption) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	job := models.Job{
+		Kind:        k.Name,
+		Payload:     raw,
+		Status:      models.JobStatusPending,
+		MaxAttempts: k.maxAttempts(),
+		RunAt:       time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+	if job.DedupeKey == nil {
+		return tx.Create(&job).Error
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error
+}
+
+// DefaultBackoff waits 30 seconds after the first failure, doubling each time up to an hour.
+func DefaultBackoff(attempts int) time.Duration {
+	const maxDelay = time.Hour
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 8 {
+		return maxDelay
+	}
+	delay := 30 * time.Second << (attempts - 1)
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying; the job fails immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,364 @@
+package jobs
+
+import (
+	"api/src/internal/models"
+	"api/src/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job outcomes, as reported by Process and the jobs_processed_total metric.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeRetried   = "retried"
+	OutcomeFailed    = "failed"
+)
+
+// leaseGrace is added to a kind's timeout before another replica may reclaim the job.
+const leaseGrace = time.Minute
+
+// Config tunes a Runner; zero values pick the defaults.
+type Config struct {
+	// Jobs run at once by this process. Defaults to 4.
+	Concurrency int
+	// How often to look for due jobs and schedules. Defaults to 1 second.
+	PollInterval time.Duration
+	// Finished jobs older than this are deleted. Defaults to 7 days.
+	Retention time.Duration
+}
+
+type handler struct {
+	timeout time.Duration
+	backoff func(int) time.Duration
+	run     func(ctx context.Context, payload json.RawMessage) error
+}
+
+type recurring struct {
+	name     string
+	spec     string
+	schedule Schedule
+	enqueue  func(tx *gorm.DB) error
+}
+
+// Runner claims and runs jobs for the kinds registered on it.
+type Runner struct {
+	DB      *gorm.DB
+	Metrics *utils.JobMetrics
+	cfg     Config
+	// Identifies this process in jobs.locked_by.
+	id        string
+	handlers  map[string]*handler
+	recurring []*recurring
+	inFlight  sync.WaitGroup
+}
+
+// purgeJob deletes finished jobs past the retention period.
+var purgeJob = Kind[struct{}]{Name: "jobs.purge", MaxAttempts: 3}
+
+func NewRunner(db *gorm.DB, metrics *utils.JobMetrics, cfg Config) *Runner {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	host, _ := os.Hostname()
+	r := &Runner{
+		DB:       db,
+		Metrics:  metrics,
+		cfg:      cfg,
+		id:       host + ":" + strconv.Itoa(os.Getpid()) + ":" + uuid.NewString()[:8],
+		handlers: make(map[string]*handler),
+	}
+	Handle(r, purgeJob, func(ctx context.Context, _ struct{}) error {
+		return r.DB.WithContext(ctx).
+			Where("status IN ? AND finished_at < ?", []string{models.JobStatusSucceeded, models.JobStatusFailed}, time.Now().Add(-r.cfg.Retention)).
+			Delete(&models.Job{}).Error
+	})
+	if err := Every(r, "jobs.purge", "@hourly", purgeJob, struct{}{}); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Handle registers the handler for a kind. Registering a kind twice panics.
+func Handle[T any](r *Runner, k Kind[T], fn func(ctx context.Context, payload T) error) {
+	if _, dup := r.handlers[k.Name]; dup {
+		panic("jobs: duplicate handler for " + k.Name)
+	}
+	r.handlers[k.Name] = &handler{
+		timeout: k.timeout(),
+		backoff: k.backoff(),
+		run: func(ctx context.Context, raw json.RawMessage) error {
+			var payload T
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return Permanent(fmt.Errorf("decode payload: %w", err))
+			}
+			return fn(ctx, payload)
+		},
+	}
+}
+
+// Every enqueues a k job with payload on the given schedule (see ParseSchedule). A
+// scheduled job is skipped while the previous one is still pending.
+func Every[T any](r *Runner, name, spec string, k Kind[T], payload T) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	r.recurring = append(r.recurring, &recurring{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		enqueue: func(tx *gorm.DB) error {
+			return k.Enqueue(tx, payload, Dedupe("schedule:"+name))
+		},
+	})
+	return nil
+}
+
+// Run processes jobs and fires schedules until ctx is cancelled, then waits for running
+// jobs to finish.
+func (r *Runner) Run(ctx context.Context) {
+	if err := r.syncSchedules(ctx, time.Now()); err != nil {
+		slog.Error("Failed to sync job schedules", slog.Any("error", err))
+	}
+
+	slots := make(chan struct{}, r.cfg.Concurrency)
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.inFlight.Wait()
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		r.fireSchedules(ctx, now)
+		if err := r.ReapExpiredLeases(ctx, now); err != nil {
+			slog.Error("Failed to reclaim abandoned jobs", slog.Any("error", err))
+		}
+
+		free := cap(slots) - len(slots)
+		if free == 0 {
+			continue
+		}
+		claimed, err := r.claim(ctx, now, free)
+		if err != nil {
+			slog.Error("Failed to claim jobs", slog.Any("error", err))
+			continue
+		}
+		for i := range claimed {
+			job := claimed[i]
+			slots <- struct{}{}
+			r.inFlight.Add(1)
+			go func() {
+				defer func() { <-slots; r.inFlight.Done() }()
+				r.Process(context.WithoutCancel(ctx), &job)
+				if err := r.finish(&job); err != nil {
+					slog.Error("Failed to record job result", slog.String("id", job.ID), slog.String("kind", job.Kind), slog.Any("error", err))
+				}
+			}()
+		}
+	}
+}
+
+// Process runs a claimed job's handler and records the outcome on job: succeeded,
+// failed, or pending again with RunAt pushed back. It does not write to the database.
+func (r *Runner) Process(ctx context.Context, job *models.Job) string {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		job.Status = models.JobStatusFailed
+		job.LastError = "no handler registered for " + job.Kind
+		now := time.Now()
+		job.FinishedAt = &now
+		return OutcomeFailed
+	}
+
+	if r.Metrics != nil {
+		r.Metrics.Started(job.Kind)
+	}
+	start := time.Now()
+	err := r.runHandler(ctx, h, job)
+	now := time.Now()
+
+	outcome := OutcomeSucceeded
+	switch {
+	case err == nil:
+		job.Status = models.JobStatusSucceeded
+		job.LastError = ""
+		job.FinishedAt = &now
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		outcome = OutcomeFailed
+		job.Status = models.JobStatusFailed
+		job.LastError = err.Error()
+		job.FinishedAt = &now
+		slog.Warn("Job failed", slog.String("id", job.ID), slog.String("kind", job.Kind),
+			slog.Int("attempts", int(job.Attempts)), slog.Any("error", err))
+	default:
+		outcome = OutcomeRetried
+		job.Status = models.JobStatusPending
+		job.LastError = err.Error()
+		job.RunAt = now.Add(h.backoff(int(job.Attempts)))
+	}
+	if r.Metrics != nil {
+		r.Metrics.Finished(job.Kind, outcome, now.Sub(start))
+	}
+	return outcome
+}
+
+func (r *Runner) runHandler(ctx context.Context, h *handler, job *models.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	return h.run(ctx, job.Payload)
+}
+
+func (r *Runner) kinds() []string {
+	kinds := make([]string, 0, len(r.handlers))
+	for k := range r.handlers {
+		kinds = append(kinds, k)
+	}
+	return kinds
+}
+
+// claim locks up to limit due jobs of kinds this runner handles and marks them running.
+func (r *Runner) claim(ctx context.Context, now time.Time, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND kind IN ?", models.JobStatusPending, now, r.kinds()).
+			Order("run_at").Limit(limit).Find(&jobs).Error; err != nil {
+			return err
+		}
+		for i := range jobs {
+			job := &jobs[i]
+			lease := now.Add(r.handlers[job.Kind].timeout + leaseGrace)
+			job.Status = models.JobStatusRunning
+			job.Attempts++
+			job.LockedBy = r.id
+			job.LeaseExpiresAt = &lease
+			// The key only guards pending jobs. Dropping it here lets the next job with
+			// the key be enqueued, and this one go back to pending later without a conflict.
+			job.DedupeKey = nil
+			if err := tx.Model(job).Updates(map[string]any{
+				"status":           job.Status,
+				"attempts":         job.Attempts,
+				"locked_by":        job.LockedBy,
+				"lease_expires_at": lease,
+				"dedupe_key":       nil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+// finish stores the outcome of Process, unless the lease was lost to another replica.
+func (r *Runner) finish(job *models.Job) error {
+	return r.DB.Model(&models.Job{}).
+		Where("id = ? AND locked_by = ? AND status = ?", job.ID, r.id, models.JobStatusRunning).
+		Updates(map[string]any{
+			"status":           job.Status,
+			"run_at":           job.RunAt,
+			"last_error":       job.LastError,
+			"finished_at":      job.FinishedAt,
+			"locked_by":        "",
+			"lease_expires_at": nil,
+		}).Error
+}
+
+// ReapExpiredLeases returns jobs abandoned by a crashed replica to the queue, or fails
+// them if that was their last attempt. Jobs are reaped one at a time, so one that cannot
+// be updated does not hold back the rest.
+func (r *Runner) ReapExpiredLeases(ctx context.Context, now time.Time) error {
+	var expired []models.Job
+	if err := r.DB.WithContext(ctx).Select("id", "attempts", "max_attempts").
+		Where("status = ? AND lease_expires_at < ?", models.JobStatusRunning, now).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+	var errs []error
+	for _, job := range expired {
+		updates := map[string]any{
+			"status":           models.JobStatusPending,
+			"last_error":       "lease expired",
+			"run_at":           now,
+			"locked_by":        "",
+			"lease_expires_at": nil,
+			// Jobs claimed before keys were dropped on claim may still hold one.
+			"dedupe_key": nil,
+		}
+		if job.Attempts >= job.MaxAttempts {
+			updates["status"] = models.JobStatusFailed
+			updates["finished_at"] = now
+		}
+		if err := r.DB.WithContext(ctx).Model(&models.Job{}).
+			Where("id = ? AND status = ? AND lease_expires_at < ?", job.ID, models.JobStatusRunning, now).
+			Updates(updates).Error; err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", job.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncSchedules creates schedule rows for new recurring jobs and reschedules those
+// whose spec changed.
+func (r *Runner) syncSchedules(ctx context.Context, now time.Time) error {
+	for _, rec := range r.recurring {
+		row := models.JobSchedule{Name: rec.name, Spec: rec.spec, NextRunAt: rec.schedule.Next(now)}
+		if err := r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"spec", "next_run_at"}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "job_schedules.spec <> excluded.spec"}}},
+		}).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fireSchedules enqueues every recurring job that is due. Replicas race on a
+// conditional UPDATE of the schedule row, so each activation is enqueued once.
+func (r *Runner) fireSchedules(ctx context.Context, now time.Time) {
+	for _, rec := range r.recurring {
+		err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.JobSchedule{}).
+				Where("name = ? AND next_run_at <= ?", rec.name, now).
+				Updates(map[string]any{"next_run_at": rec.schedule.Next(now), "last_run_at": now})
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			if r.Metrics != nil {
+				r.Metrics.Scheduled(rec.name)
+			}
+			return rec.enqueue(tx)
+		})
+		if err != nil {
+			slog.Error("Failed to fire job schedule", slog.String("schedule", rec.name), slog.Any("error", err))
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	v1 "api/src/generated/google_maps/v1"
 
 	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"google.golang.org/genproto/googleapis/geo/type/viewport"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 func boolPtr(b *bool) bool {
@@ -74,6 +76,56 @@ func PriceLevelsToSDK(levels []v1.PriceLevel) []placespb.PriceLevel {
 	return result
 }
 
+var rankPreferenceReverseMap = map[v1.RankPreference]placespb.SearchTextRequest_RankPreference{
+	v1.RankPreference_RANK_PREFERENCE_UNSPECIFIED: placespb.SearchTextRequest_RANK_PREFERENCE_UNSPECIFIED,
+	v1.RankPreference_RANK_PREFERENCE_DISTANCE:    placespb.SearchTextRequest_DISTANCE,
+	v1.RankPreference_RANK_PREFERENCE_RELEVANCE:   placespb.SearchTextRequest_RELEVANCE,
+}
+
+func RankPreferenceToSDK(preference v1.RankPreference) placespb.SearchTextRequest_RankPreference {
+	return rankPreferenceReverseMap[preference]
+}
+
+func LatLngToSDK(point *v1.LatLng) *latlng.LatLng {
+	if point == nil {
+		return nil
+	}
+	return &latlng.LatLng{Latitude: point.Lat, Longitude: point.Lng}
+}
+
+func CircleToSDK(circle *v1.Circle) *placespb.Circle {
+	if circle == nil {
+		return nil
+	}
+	return &placespb.Circle{Center: LatLngToSDK(circle.Center), Radius: circle.Radius}
+}
+
+func RectangleToSDK(rect *v1.Rectangle) *viewport.Viewport {
+	if rect == nil {
+		return nil
+	}
+	return &viewport.Viewport{Low: LatLngToSDK(rect.Low), High: LatLngToSDK(rect.High)}
+}
+
+func LocationBiasToSDK(bias *v1.LocationBias) *placespb.SearchTextRequest_LocationBias {
+	switch t := bias.GetType().(type) {
+	case *v1.LocationBias_Circle:
+		return &placespb.SearchTextRequest_LocationBias{Type: &placespb.SearchTextRequest_LocationBias_Circle{Circle: CircleToSDK(t.Circle)}}
+	case *v1.LocationBias_Rectangle:
+		return &placespb.SearchTextRequest_LocationBias{Type: &placespb.SearchTextRequest_LocationBias_Rectangle{Rectangle: RectangleToSDK(t.Rectangle)}}
+	}
+	return nil
+}
+
+// LocationRestrictionToSDK maps a rectangle restriction; text search cannot be restricted
+// to a circle, so a circle maps to nil.
+func LocationRestrictionToSDK(restriction *v1.LocationRestriction) *placespb.SearchTextRequest_LocationRestriction {
+	if rect := restriction.GetRectangle(); rect != nil {
+		return &placespb.SearchTextRequest_LocationRestriction{Type: &placespb.SearchTextRequest_LocationRestriction_Rectangle{Rectangle: RectangleToSDK(rect)}}
+	}
+	return nil
+}
+
 func SearchTextResponseToProto(resp *placespb.SearchTextResponse) *v1.SearchTextResponse {
 	if resp == nil {
 		return nil
@@ -241,6 +293,21 @@ func regionCodeToCountry(code string) string {
 	return code
 }
 
+// CountryName returns the English name of an ISO 3166-1 alpha-2 region code, or the code
+// itself when it is unknown.
+func CountryName(regionCode string) string {
+	return regionCodeToCountry(strings.ToUpper(regionCode))
+}
+
+// PlaceCityCountry returns the locality and country name of place's postal address.
+func PlaceCityCountry(place *placespb.Place) (city, country string) {
+	addr := place.GetPostalAddress()
+	if addr == nil {
+		return "", ""
+	}
+	return addr.GetLocality(), regionCodeToCountry(addr.GetRegionCode())
+}
+
 func PlaceToProto(place *placespb.Place) *v1.Place {
 	if place == nil {
 		return nil
@@ -0,0 +1,328 @@
+// Package migrations applies the versioned SQL files in sql/ to Postgres. Each file pair
+// NNNN_name.up.sql / NNNN_name.down.sql is one migration; applied versions are recorded
+// in schema_migrations, and a session advisory lock keeps concurrent replicas from
+// migrating at the same time.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// Files returns the migrations compiled into the binary.
+func Files() fs.FS {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// lockKey identifies the advisory lock held while migrating ("rr_migr" in ASCII).
+const lockKey int64 = 0x72725f6d69677200
+
+// noTransactionDirective on the first line of a file runs it outside a transaction,
+// which statements such as CREATE INDEX CONCURRENTLY require.
+const noTransactionDirective = "-- migrate:no-transaction"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Empty when the migration can't be reverted.
+	Down string
+}
+
+// Status describes one migration known to the files or the database.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Applied to the database but not present in the files, e.g. after a rollback of
+	// the binary.
+	Missing bool
+}
+
+// Load reads and validates the migrations in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %q must be named NNNN_name.up.sql or NNNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// New loads the migrations in fsys for db.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns those it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			slog.Info("Applying migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			if err := run(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+	byVersion := make(map[int64]Migration, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions[:min(steps, len(versions))] {
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but its files are missing", v)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+			slog.Info("Reverting migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			if err := run(ctx, conn, mig.Down, "DELETE FROM schema_migrations WHERE version = $1", mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every migration, applied or not, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		known := make(map[int64]bool, len(m.Migrations))
+		for _, mig := range m.Migrations {
+			known[mig.Version] = true
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := done[mig.Version]; ok {
+				s.AppliedAt = &a.at
+			}
+			out = append(out, s)
+		}
+		for v, a := range done {
+			if !known[v] {
+				out = append(out, Status{Version: v, Name: a.name, AppliedAt: &a.at, Missing: true})
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+		return nil
+	})
+	return out, err
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock, after
+// making sure schema_migrations exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled.
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			slog.Error("Failed to release migration lock", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now()
+)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]appliedMigration{}
+	for rows.Next() {
+		var v int64
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.name, &a.at); err != nil {
+			return nil, err
+		}
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+// run executes a migration script and its bookkeeping statement, in one transaction
+// unless the script opts out.
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	if strings.HasPrefix(strings.TrimSpace(script), noTransactionDirective) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create writes an empty up/down pair for a new migration in dir, numbered after the
+// highest existing version, and returns the paths.
+func Create(dir, name string) ([]string, error) {
+	slug := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+	existing, err := Load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	next := int64(1)
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, slug)
+	paths := []string{filepath.Join(dir, base+".up.sql"), filepath.Join(dir, base+".down.sql")}
+	for _, p := range paths {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		_, err = fmt.Fprintf(f, "-- %s\n", filepath.Base(p))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS job_schedules;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS push_outboxes;
+DROP TABLE IF EXISTS push_subscriptions;
+DROP TABLE IF EXISTS email_outboxes;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS shared_lists;
+DROP TABLE IF EXISTS friend_requests;
+DROP TABLE IF EXISTS wishlist_item_circles;
+DROP TABLE IF EXISTS wishlist_items;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS review_circles;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS circle_members;
+DROP TABLE IF EXISTS circles;
+DROP TABLE IF EXISTS privacy_settings;
+DROP TABLE IF EXISTS restaurants;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,272 @@
+-- Baseline: the schema previously created by GORM AutoMigrate. Every statement is
+-- IF NOT EXISTS so databases created by AutoMigrate adopt it without changes.
+
+CREATE TABLE IF NOT EXISTS users (
+    id text PRIMARY KEY,
+    google_id text,
+    email text,
+    username text,
+    name text NOT NULL,
+    is_dark_mode_enabled boolean DEFAULT false,
+    default_region text DEFAULT '',
+    default_language text DEFAULT '',
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users (google_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS restaurants (
+    id text PRIMARY KEY,
+    google_id text,
+    address text,
+    name text NOT NULL,
+    city text,
+    country text,
+    photo_reference text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurants_google_id ON restaurants (google_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurants_address ON restaurants (address);
+CREATE INDEX IF NOT EXISTS idx_restaurants_city ON restaurants (city);
+CREATE INDEX IF NOT EXISTS idx_restaurants_country ON restaurants (country);
+
+CREATE TABLE IF NOT EXISTS privacy_settings (
+    user_id text PRIMARY KEY,
+    discoverable_by_username boolean NOT NULL,
+    discoverable_by_email boolean NOT NULL,
+    show_email_to_friends boolean NOT NULL,
+    friend_request_policy text NOT NULL,
+    default_visibility text NOT NULL,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS circles (
+    id text PRIMARY KEY,
+    owner_id text NOT NULL,
+    name text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_circles_owner_id ON circles (owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_circle_owner_name ON circles (owner_id, name);
+
+CREATE TABLE IF NOT EXISTS circle_members (
+    id text PRIMARY KEY,
+    circle_id text NOT NULL,
+    member_id text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_circles_members FOREIGN KEY (circle_id) REFERENCES circles (id)
+);
+CREATE INDEX IF NOT EXISTS idx_circle_members_circle_id ON circle_members (circle_id);
+CREATE INDEX IF NOT EXISTS idx_circle_members_member_id ON circle_members (member_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_circle_member ON circle_members (circle_id, member_id);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id text PRIMARY KEY,
+    restaurant_id text NOT NULL,
+    user_id text NOT NULL,
+    google_places_id text,
+    comment text,
+    rating numeric NOT NULL,
+    tags text,
+    visited_at timestamptz,
+    price_paid_per_person integer,
+    would_visit_again integer,
+    dish_highlights text,
+    visibility text NOT NULL DEFAULT 'friends',
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_reviews_restaurant FOREIGN KEY (restaurant_id) REFERENCES restaurants (id),
+    CONSTRAINT fk_reviews_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_reviews_restaurant_id ON reviews (restaurant_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_restaurant_user ON reviews (restaurant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_google_places_id ON reviews (google_places_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_visibility ON reviews (visibility);
+
+CREATE TABLE IF NOT EXISTS review_circles (
+    review_id text NOT NULL,
+    circle_id text NOT NULL,
+    PRIMARY KEY (review_id, circle_id),
+    CONSTRAINT fk_review_circles_review FOREIGN KEY (review_id) REFERENCES reviews (id),
+    CONSTRAINT fk_review_circles_circle FOREIGN KEY (circle_id) REFERENCES circles (id)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id text PRIMARY KEY,
+    slug text NOT NULL,
+    label text NOT NULL,
+    category text NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_slug ON tags (slug);
+CREATE INDEX IF NOT EXISTS idx_tags_category ON tags (category);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    restaurant_id text NOT NULL,
+    google_places_id text NOT NULL,
+    tags text,
+    visibility text NOT NULL DEFAULT 'friends',
+    created_at timestamptz,
+    CONSTRAINT fk_wishlist_items_restaurant FOREIGN KEY (restaurant_id) REFERENCES restaurants (id)
+);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_user_id ON wishlist_items (user_id);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_restaurant_id ON wishlist_items (restaurant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlist_user_restaurant ON wishlist_items (user_id, restaurant_id);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_google_places_id ON wishlist_items (google_places_id);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_visibility ON wishlist_items (visibility);
+
+CREATE TABLE IF NOT EXISTS wishlist_item_circles (
+    wishlist_item_id text NOT NULL,
+    circle_id text NOT NULL,
+    PRIMARY KEY (wishlist_item_id, circle_id),
+    CONSTRAINT fk_wishlist_item_circles_wishlist_item FOREIGN KEY (wishlist_item_id) REFERENCES wishlist_items (id),
+    CONSTRAINT fk_wishlist_item_circles_circle FOREIGN KEY (circle_id) REFERENCES circles (id)
+);
+
+CREATE TABLE IF NOT EXISTS friend_requests (
+    id text PRIMARY KEY,
+    sender_id text NOT NULL,
+    receiver_id text NOT NULL,
+    pair_key text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_friend_requests_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_friend_requests_receiver FOREIGN KEY (receiver_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_sender_id ON friend_requests (sender_id);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_receiver_id ON friend_requests (receiver_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friend_requests_pair_key ON friend_requests (pair_key);
+
+CREATE TABLE IF NOT EXISTS shared_lists (
+    id text PRIMARY KEY,
+    owner_id text NOT NULL,
+    slug text NOT NULL,
+    title text NOT NULL,
+    description text,
+    mode text NOT NULL,
+    filter text,
+    review_ids text,
+    view_count bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_shared_lists_owner FOREIGN KEY (owner_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_shared_lists_owner_id ON shared_lists (owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shared_lists_slug ON shared_lists (slug);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    type text NOT NULL,
+    subject_id text NOT NULL,
+    subject_name text,
+    actor_ids text,
+    actor_count integer NOT NULL DEFAULT 0,
+    read_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notification_subject ON notifications (user_id, type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_read_at ON notifications (read_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_updated_at ON notifications (updated_at);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id text NOT NULL,
+    type text NOT NULL,
+    in_app boolean NOT NULL,
+    email boolean NOT NULL DEFAULT true,
+    push boolean NOT NULL DEFAULT true,
+    updated_at timestamptz,
+    PRIMARY KEY (user_id, type)
+);
+
+CREATE TABLE IF NOT EXISTS email_outboxes (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    template text NOT NULL,
+    params text,
+    dedupe_key text,
+    status text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text,
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_outboxes_user_id ON email_outboxes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_outboxes_dedupe_key ON email_outboxes (dedupe_key);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outboxes (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    endpoint text NOT NULL,
+    p256dh text NOT NULL,
+    auth text NOT NULL,
+    user_agent text,
+    session_hash text NOT NULL,
+    last_used_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user_id ON push_subscriptions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_push_subscriptions_endpoint ON push_subscriptions (endpoint);
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_session_hash ON push_subscriptions (session_hash);
+
+CREATE TABLE IF NOT EXISTS push_outboxes (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    subscription_id text NOT NULL,
+    template text NOT NULL,
+    params text,
+    status text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text,
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_push_outboxes_user_id ON push_outboxes (user_id);
+CREATE INDEX IF NOT EXISTS idx_push_outboxes_subscription_id ON push_outboxes (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_push_outbox_due ON push_outboxes (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id text PRIMARY KEY,
+    kind text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    max_attempts integer NOT NULL,
+    run_at timestamptz NOT NULL,
+    dedupe_key text,
+    locked_by text,
+    lease_expires_at timestamptz,
+    last_error text,
+    finished_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_kind ON jobs (kind);
+CREATE INDEX IF NOT EXISTS idx_job_due ON jobs (status, run_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_pending_dedupe ON jobs (dedupe_key) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs (finished_at);
+
+CREATE TABLE IF NOT EXISTS job_schedules (
+    name text PRIMARY KEY,
+    spec text NOT NULL,
+    next_run_at timestamptz NOT NULL,
+    last_run_at timestamptz,
+    updated_at timestamptz
+);
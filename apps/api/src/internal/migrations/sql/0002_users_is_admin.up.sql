@@ -0,0 +1,2 @@
+-- Operators grant admin with `api user promote`; it is not exposed over the API.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin boolean NOT NULL DEFAULT false;
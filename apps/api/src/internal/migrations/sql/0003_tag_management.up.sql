@@ -0,0 +1,4 @@
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS deprecated_at timestamptz;
+
+-- Tags that used to be deleted on every startup, before tags were managed at runtime.
+DELETE FROM tags WHERE slug IN ('fast-service', 'outdoor-seating', 'delivery', 'takeaway', 'reservations', 'dog-friendly');
@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS tag_translations (
+    tag_slug text NOT NULL REFERENCES tags (slug) ON UPDATE CASCADE ON DELETE CASCADE,
+    locale text NOT NULL,
+    label text NOT NULL,
+    PRIMARY KEY (tag_slug, locale)
+);
+
+-- Polish labels for the default tags of databases seeded before translations existed.
+-- Fresh databases get them from seed/tags.yaml instead.
+INSERT INTO tag_translations (tag_slug, locale, label)
+SELECT v.tag_slug, v.locale, v.label
+FROM (VALUES
+    ('italian', 'pl', 'Włoska'),
+    ('japanese', 'pl', 'Japońska'),
+    ('mexican', 'pl', 'Meksykańska'),
+    ('chinese', 'pl', 'Chińska'),
+    ('indian', 'pl', 'Indyjska'),
+    ('french', 'pl', 'Francuska'),
+    ('thai', 'pl', 'Tajska'),
+    ('american', 'pl', 'Amerykańska'),
+    ('mediterranean', 'pl', 'Śródziemnomorska'),
+    ('korean', 'pl', 'Koreańska'),
+    ('romantic', 'pl', 'Romantycznie'),
+    ('casual', 'pl', 'Na luzie'),
+    ('family-friendly', 'pl', 'Przyjazne rodzinom'),
+    ('date-night', 'pl', 'Na randkę'),
+    ('business-lunch', 'pl', 'Lunch biznesowy'),
+    ('lively', 'pl', 'Gwarnie'),
+    ('quiet', 'pl', 'Cicho'),
+    ('trendy', 'pl', 'Modnie'),
+    ('budget', 'pl', 'Budżetowo'),
+    ('mid-range', 'pl', 'Średnia półka'),
+    ('expensive', 'pl', 'Drogo'),
+    ('splurge', 'pl', 'Na bogato'),
+    ('vegan', 'pl', 'Wegańskie'),
+    ('vegetarian', 'pl', 'Wegetariańskie'),
+    ('gluten-free', 'pl', 'Bezglutenowe'),
+    ('halal', 'pl', 'Halal'),
+    ('kosher', 'pl', 'Koszerne'),
+    ('dairy-free', 'pl', 'Bez nabiału'),
+    ('solo', 'pl', 'Solo'),
+    ('couple', 'pl', 'We dwoje'),
+    ('small-group', 'pl', 'Mała grupa'),
+    ('large-group', 'pl', 'Duża grupa'),
+    ('birthday', 'pl', 'Urodziny'),
+    ('anniversary', 'pl', 'Rocznica'),
+    ('brunch', 'pl', 'Brunch'),
+    ('late-night', 'pl', 'Późnym wieczorem'),
+    ('celebration', 'pl', 'Świętowanie'),
+    ('quick-bite', 'pl', 'Na szybko')
+) AS v (tag_slug, locale, label)
+JOIN tags ON tags.slug = v.tag_slug
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS tags text;
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS tags text;
+
+UPDATE reviews r SET tags = (
+    SELECT COALESCE(json_agg(t.slug ORDER BY t.slug), '[]'::json)::text
+    FROM review_tags rt JOIN tags t ON t.id = rt.tag_id
+    WHERE rt.review_id = r.id
+);
+UPDATE wishlist_items w SET tags = (
+    SELECT COALESCE(json_agg(t.slug ORDER BY t.slug), '[]'::json)::text
+    FROM wishlist_item_tags wt JOIN tags t ON t.id = wt.tag_id
+    WHERE wt.wishlist_item_id = w.id
+);
+
+DROP TABLE IF EXISTS wishlist_item_tags;
+DROP TABLE IF EXISTS review_tags;
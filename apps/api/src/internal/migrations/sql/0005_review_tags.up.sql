@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS review_tags (
+    review_id text NOT NULL,
+    tag_id text NOT NULL,
+    PRIMARY KEY (review_id, tag_id),
+    CONSTRAINT fk_review_tags_review FOREIGN KEY (review_id) REFERENCES reviews (id),
+    CONSTRAINT fk_review_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_review_tags_tag_id ON review_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS wishlist_item_tags (
+    wishlist_item_id text NOT NULL,
+    tag_id text NOT NULL,
+    PRIMARY KEY (wishlist_item_id, tag_id),
+    CONSTRAINT fk_wishlist_item_tags_wishlist_item FOREIGN KEY (wishlist_item_id) REFERENCES wishlist_items (id),
+    CONSTRAINT fk_wishlist_item_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_wishlist_item_tags_tag_id ON wishlist_item_tags (tag_id);
+
+-- Copy the JSON tag arrays. Slugs without a matching tag (deleted or never valid) are dropped.
+INSERT INTO review_tags (review_id, tag_id)
+SELECT r.id, t.id
+FROM reviews r
+CROSS JOIN LATERAL json_array_elements_text(CASE WHEN r.tags LIKE '[%' THEN r.tags::json ELSE '[]'::json END) AS s (slug)
+JOIN tags t ON t.slug = s.slug
+ON CONFLICT DO NOTHING;
+
+INSERT INTO wishlist_item_tags (wishlist_item_id, tag_id)
+SELECT w.id, t.id
+FROM wishlist_items w
+CROSS JOIN LATERAL json_array_elements_text(CASE WHEN w.tags LIKE '[%' THEN w.tags::json ELSE '[]'::json END) AS s (slug)
+JOIN tags t ON t.slug = s.slug
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS tags;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,4 @@
+ALTER TABLE privacy_settings DROP COLUMN IF EXISTS share_personal_tags;
+DROP TABLE IF EXISTS wishlist_item_personal_tags;
+DROP TABLE IF EXISTS review_personal_tags;
+DROP TABLE IF EXISTS personal_tags;
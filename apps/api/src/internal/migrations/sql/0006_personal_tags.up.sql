@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS personal_tags (
+    id text PRIMARY KEY,
+    owner_id text NOT NULL,
+    slug text NOT NULL,
+    label text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_personal_tags_owner_id ON personal_tags (owner_id);
+CREATE INDEX IF NOT EXISTS idx_personal_tags_slug ON personal_tags (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_tag_owner_slug ON personal_tags (owner_id, slug);
+
+CREATE TABLE IF NOT EXISTS review_personal_tags (
+    review_id text NOT NULL,
+    personal_tag_id text NOT NULL,
+    PRIMARY KEY (review_id, personal_tag_id),
+    CONSTRAINT fk_review_personal_tags_review FOREIGN KEY (review_id) REFERENCES reviews (id),
+    CONSTRAINT fk_review_personal_tags_personal_tag FOREIGN KEY (personal_tag_id) REFERENCES personal_tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_review_personal_tags_personal_tag_id ON review_personal_tags (personal_tag_id);
+
+CREATE TABLE IF NOT EXISTS wishlist_item_personal_tags (
+    wishlist_item_id text NOT NULL,
+    personal_tag_id text NOT NULL,
+    PRIMARY KEY (wishlist_item_id, personal_tag_id),
+    CONSTRAINT fk_wishlist_item_personal_tags_wishlist_item FOREIGN KEY (wishlist_item_id) REFERENCES wishlist_items (id),
+    CONSTRAINT fk_wishlist_item_personal_tags_personal_tag FOREIGN KEY (personal_tag_id) REFERENCES personal_tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_wishlist_item_personal_tags_personal_tag_id ON wishlist_item_personal_tags (personal_tag_id);
+
+-- Opt-in: existing users keep their personal tags to themselves.
+ALTER TABLE privacy_settings ADD COLUMN IF NOT EXISTS share_personal_tags boolean NOT NULL DEFAULT false;
+ALTER TABLE privacy_settings ALTER COLUMN share_personal_tags DROP DEFAULT;
@@ -0,0 +1,5 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS drinks_score;
+ALTER TABLE reviews DROP COLUMN IF EXISTS value_score;
+ALTER TABLE reviews DROP COLUMN IF EXISTS ambience_score;
+ALTER TABLE reviews DROP COLUMN IF EXISTS service_score;
+ALTER TABLE reviews DROP COLUMN IF EXISTS food_score;
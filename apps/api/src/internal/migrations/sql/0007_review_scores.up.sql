@@ -0,0 +1,5 @@
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS food_score numeric;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS service_score numeric;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS ambience_score numeric;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS value_score numeric;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS drinks_score numeric;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_dishes;
+DROP TABLE IF EXISTS dishes;
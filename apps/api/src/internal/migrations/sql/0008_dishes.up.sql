@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS dishes (
+    id text PRIMARY KEY,
+    restaurant_id text NOT NULL,
+    name text NOT NULL,
+    normalized_name text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_dishes_restaurant FOREIGN KEY (restaurant_id) REFERENCES restaurants (id)
+);
+CREATE INDEX IF NOT EXISTS idx_dishes_restaurant_id ON dishes (restaurant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dish_restaurant_name ON dishes (restaurant_id, normalized_name);
+
+CREATE TABLE IF NOT EXISTS review_dishes (
+    review_id text NOT NULL,
+    dish_id text NOT NULL,
+    position integer NOT NULL,
+    rating numeric NOT NULL,
+    price integer NOT NULL,
+    would_order_again boolean,
+    PRIMARY KEY (review_id, dish_id),
+    CONSTRAINT fk_review_dishes_review FOREIGN KEY (review_id) REFERENCES reviews (id),
+    CONSTRAINT fk_review_dishes_dish FOREIGN KEY (dish_id) REFERENCES dishes (id)
+);
+CREATE INDEX IF NOT EXISTS idx_review_dishes_dish_id ON review_dishes (dish_id);
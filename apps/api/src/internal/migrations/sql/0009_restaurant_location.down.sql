@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_restaurants_geohash;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS geohash;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS longitude;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,6 @@
+-- Plain columns and a geohash rather than PostGIS, which stock Postgres images lack.
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS latitude double precision;
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS longitude double precision;
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS geohash text;
+-- text_pattern_ops lets ListNearby's geohash LIKE 'prefix%' lookups use the index.
+CREATE INDEX IF NOT EXISTS idx_restaurants_geohash ON restaurants (geohash text_pattern_ops);
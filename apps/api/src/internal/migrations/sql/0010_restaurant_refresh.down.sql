@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_restaurants_refreshed_at;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS refreshed_at;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS business_status;
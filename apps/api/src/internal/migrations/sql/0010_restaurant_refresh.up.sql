@@ -0,0 +1,3 @@
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS business_status text NOT NULL DEFAULT '';
+ALTER TABLE restaurants ADD COLUMN IF NOT EXISTS refreshed_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_restaurants_refreshed_at ON restaurants (refreshed_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_place_links_osm_name;
+DROP TABLE IF EXISTS place_links;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS place_links (
+    google_name text PRIMARY KEY,
+    osm_name text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_place_links_osm_name ON place_links (osm_name);
@@ -0,0 +1 @@
+ALTER TABLE shared_lists DROP COLUMN IF EXISTS snapshot;
@@ -0,0 +1 @@
+ALTER TABLE shared_lists ADD COLUMN IF NOT EXISTS snapshot bytea;
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS restaurant_refresh_usages (
+    day date PRIMARY KEY,
+    requests bigint NOT NULL DEFAULT 0
+);
@@ -0,0 +1,91 @@
+package models
+
+import (
+	circlesv1 "api/src/generated/circles/v1"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Visibility values stored on reviews and wishlist items.
+const (
+	VisibilityPrivate = "private"
+	VisibilityCircles = "circles"
+	VisibilityFriends = "friends"
+)
+
+// Circle is a user-defined group of friends ("close friends", "work") that
+// reviews and wishlist items can be shared with.
+type Circle struct {
+	UUIDv7
+	OwnerID   string         `gorm:"not null;index;uniqueIndex:idx_circle_owner_name"`
+	Name      string         `gorm:"not null;uniqueIndex:idx_circle_owner_name"`
+	Members   []CircleMember `gorm:"foreignKey:CircleID"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (c *Circle) BeforeCreate(tx *gorm.DB) (err error) {
+	return c.UUIDv7.BeforeCreate(tx)
+}
+
+// ToProto converts a Circle to its proto representation.
+// Members must be preloaded for member_ids to be populated.
+func (c *Circle) ToProto() *circlesv1.CircleProto {
+	memberIDs := make([]string, len(c.Members))
+	for i, m := range c.Members {
+		memberIDs[i] = m.MemberID
+	}
+	return &circlesv1.CircleProto{
+		Id:        c.ID,
+		Name:      c.Name,
+		MemberIds: memberIDs,
+		CreatedAt: c.CreatedAt.Unix(),
+		UpdatedAt: c.UpdatedAt.Unix(),
+	}
+}
+
+type CircleMember struct {
+	UUIDv7
+	CircleID  string    `gorm:"not null;index;uniqueIndex:idx_circle_member"`
+	MemberID  string    `gorm:"not null;index;uniqueIndex:idx_circle_member"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (m *CircleMember) BeforeCreate(tx *gorm.DB) (err error) {
+	return m.UUIDv7.BeforeCreate(tx)
+}
+
+// VisibilityToProto maps a stored visibility value to its proto enum.
+// Empty values predate the column and are treated as friends-visible.
+func VisibilityToProto(v string) circlesv1.Visibility {
+	switch v {
+	case VisibilityPrivate:
+		return circlesv1.Visibility_VISIBILITY_PRIVATE
+	case VisibilityCircles:
+		return circlesv1.Visibility_VISIBILITY_CIRCLES
+	default:
+		return circlesv1.Visibility_VISIBILITY_FRIENDS
+	}
+}
+
+// VisibilityFromProto maps a proto visibility to its stored value.
+// UNSPECIFIED falls back to friends-visible for backwards compatibility.
+func VisibilityFromProto(v circlesv1.Visibility) string {
+	switch v {
+	case circlesv1.Visibility_VISIBILITY_PRIVATE:
+		return VisibilityPrivate
+	case circlesv1.Visibility_VISIBILITY_CIRCLES:
+		return VisibilityCircles
+	default:
+		return VisibilityFriends
+	}
+}
+
+func circleIDs(circles []Circle) []string {
+	ids := make([]string, len(circles))
+	for i, c := range circles {
+		ids[i] = c.ID
+	}
+	return ids
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	reviewspb "api/src/generated/reviews/v1"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dish is a dish on a restaurant's menu, created the first time a review mentions it.
+// Reviews refer to it through ReviewDish.
+type Dish struct {
+	UUIDv7
+	RestaurantID string `gorm:"not null;index;uniqueIndex:idx_dish_restaurant_name"`
+	// Name as first written; NormalizedName is what matching uses.
+	Name           string    `gorm:"not null"`
+	NormalizedName string    `gorm:"not null;uniqueIndex:idx_dish_restaurant_name"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (d *Dish) BeforeCreate(tx *gorm.DB) (err error) {
+	return d.UUIDv7.BeforeCreate(tx)
+}
+
+// ReviewDish is a dish recorded on a review, with the author's verdict on it.
+type ReviewDish struct {
+	ReviewID string `gorm:"primaryKey"`
+	DishID   string `gorm:"primaryKey;index"`
+	Dish     Dish   `gorm:"foreignKey:DishID"`
+	// Order in which the author listed the dishes.
+	Position int32 `gorm:"not null"`
+	// 1–5, or 0 when not rated.
+	Rating          float64 `gorm:"not null"`
+	Price           int32   `gorm:"not null"`
+	WouldOrderAgain *bool
+}
+
+// ToProto converts a ReviewDish; Dish must be preloaded for the name.
+func (d *ReviewDish) ToProto() *reviewspb.ReviewDishProto {
+	return &reviewspb.ReviewDishProto{
+		DishId:          d.DishID,
+		Name:            d.Dish.Name,
+		Rating:          d.Rating,
+		Price:           d.Price,
+		WouldOrderAgain: d.WouldOrderAgain,
+	}
+}
+
+// CleanDishName trims a dish name and collapses runs of whitespace.
+func CleanDishName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizeDishName returns the form dish names are matched by.
+func NormalizeDishName(name string) string {
+	return strings.ToLower(CleanDishName(name))
+}
+
+// reviewDishProtos converts dishes to protos in the order the author listed them.
+func reviewDishProtos(dishes []ReviewDish) []*reviewspb.ReviewDishProto {
+	sorted := make([]ReviewDish, len(dishes))
+	copy(sorted, dishes)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+	protos := make([]*reviewspb.ReviewDishProto, len(sorted))
+	for i := range sorted {
+		protos[i] = sorted[i].ToProto()
+	}
+	return protos
+}
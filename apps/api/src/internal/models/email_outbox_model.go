@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Email outbox statuses.
+const (
+	EmailStatusPending = "pending"
+	// Claimed by a dispatcher until NextAttemptAt, which is when the claim runs out.
+	EmailStatusSending = "sending"
+	EmailStatusSent    = "sent"
+	// Gave up after too many attempts, or the message can never be sent (e.g. no address).
+	EmailStatusFailed = "failed"
+	// Dropped on purpose at send time: the user unsubscribed or there was nothing to say.
+	EmailStatusSkipped = "skipped"
+)
+
+// EmailOutbox is a queued email. Rows store the template and its inputs rather than the
+// rendered message, so rendering uses the recipient's current name, locale and
+// preferences at send time.
+type EmailOutbox struct {
+	UUIDv7
+	UserID   string            `gorm:"not null;index"`
+	Template string            `gorm:"not null"`
+	Params   map[string]string `gorm:"serializer:json"`
+	// Optional; a second email with the same key is never queued (e.g. one digest per week).
+	DedupeKey     *string   `gorm:"uniqueIndex"`
+	Status        string    `gorm:"not null;index:idx_email_outbox_due"`
+	Attempts      int32     `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_email_outbox_due"`
+	LastError     string
+	SentAt        *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (e *EmailOutbox) BeforeCreate(tx *gorm.DB) (err error) {
+	return e.UUIDv7.BeforeCreate(tx)
+}
@@ -41,7 +41,11 @@ func (f *FriendRequest) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
-func (f *FriendRequest) ToProto() *friendshippb.FriendRequestProto {
+// ToProto converts a FriendRequest to its proto representation as seen by viewerID.
+// The viewer's own email is always included; the counterpart's only once the request
+// is accepted and their privacy settings allow it. Users missing from privacy get
+// DefaultPrivacySettings.
+func (f *FriendRequest) ToProto(viewerID string, privacy map[string]PrivacySettings) *friendshippb.FriendRequestProto {
 	status := friendshippb.FriendRequestStatus_FRIEND_REQUEST_STATUS_UNSPECIFIED
 	switch f.Status {
 	case FriendRequestStatusPending:
@@ -59,7 +63,23 @@ func (f *FriendRequest) ToProto() *friendshippb.FriendRequestProto {
 		CreatedAt:     f.CreatedAt.Unix(),
 		SenderName:    f.Sender.Name,
 		ReceiverName:  f.Receiver.Name,
-		SenderEmail:   derefString(f.Sender.Email),
-		ReceiverEmail: derefString(f.Receiver.Email),
+		SenderEmail:      f.emailFor(f.SenderID, f.Sender.Email, viewerID, privacy),
+		ReceiverEmail:    f.emailFor(f.ReceiverID, f.Receiver.Email, viewerID, privacy),
+		SenderUsername:   derefString(f.Sender.Username),
+		ReceiverUsername: derefString(f.Receiver.Username),
 	}
 }
+
+func (f *FriendRequest) emailFor(userID string, email *string, viewerID string, privacy map[string]PrivacySettings) string {
+	if userID == viewerID {
+		return derefString(email)
+	}
+	settings, ok := privacy[userID]
+	if !ok {
+		settings = DefaultPrivacySettings(userID)
+	}
+	if !settings.EmailVisibleTo(f.Status == FriendRequestStatusAccepted) {
+		return ""
+	}
+	return derefString(email)
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	earthRadiusMeters = 6371008.8
+	geohashAlphabet   = "0123456789bcdefghjkmnpqrstuvwxyz"
+	// GeohashPrecision is the length of the geohash stored on restaurants (cells of about 5 m).
+	GeohashPrecision = 9
+	// GeohashCover uses the longest prefixes that need at most this many cells per box.
+	maxGeohashCells = 16
+)
+
+// ValidCoordinates reports whether lat and lng are a point on Earth in degrees.
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// Geohash encodes a point as a geohash of the given length.
+func Geohash(lat, lng float64, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lngLo, lngHi := -180.0, 180.0
+	hash := make([]byte, precision)
+	even := true
+	for i := range hash {
+		var idx byte
+		for range 5 {
+			idx <<= 1
+			if even {
+				if mid := (lngLo + lngHi) / 2; lng >= mid {
+					idx |= 1
+					lngLo = mid
+				} else {
+					lngHi = mid
+				}
+			} else {
+				if mid := (latLo + latHi) / 2; lat >= mid {
+					idx |= 1
+					latLo = mid
+				} else {
+					latHi = mid
+				}
+			}
+			even = !even
+		}
+		hash[i] = geohashAlphabet[idx]
+	}
+	return string(hash)
+}
+
+// DistanceMeters is the great-circle distance between two points.
+func DistanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	rad1, rad2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dLat := rad2 - rad1
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad1)*math.Cos(rad2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox is an area in degrees. West > East means it crosses the antimeridian.
+type BoundingBox struct {
+	South, West, North, East float64
+}
+
+// BoundingBoxAround returns the smallest box holding every point within radius meters of
+// (lat, lng).
+func BoundingBoxAround(lat, lng, radius float64) BoundingBox {
+	dLat := radius / earthRadiusMeters * 180 / math.Pi
+	box := BoundingBox{South: math.Max(-90, lat-dLat), North: math.Min(90, lat+dLat), West: -180, East: 180}
+	if box.South == -90 || box.North == 90 {
+		return box // a pole is inside, so every longitude is
+	}
+	dLng := dLat / math.Cos(math.Max(math.Abs(box.South), math.Abs(box.North))*math.Pi/180)
+	if dLng >= 180 {
+		return box
+	}
+	box.West, box.East = lng-dLng, lng+dLng
+	if box.West < -180 {
+		box.West += 360
+	}
+	if box.East > 180 {
+		box.East -= 360
+	}
+	return box
+}
+
+// Valid reports whether every corner is a valid coordinate and south is not above north.
+func (b BoundingBox) Valid() bool {
+	return ValidCoordinates(b.South, b.West) && ValidCoordinates(b.North, b.East) && b.South <= b.North
+}
+
+// Center returns the middle of the box.
+func (b BoundingBox) Center() (lat, lng float64) {
+	lng = (b.West + b.East) / 2
+	if b.West > b.East {
+		if lng += 180; lng > 180 {
+			lng -= 360
+		}
+	}
+	return (b.South + b.North) / 2, lng
+}
+
+// Contains reports whether the point lies in the box.
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West > b.East {
+		return lng >= b.West || lng <= b.East
+	}
+	return lng >= b.West && lng <= b.East
+}
+
+// Split returns the box as one or two boxes that don't cross the antimeridian.
+func (b BoundingBox) Split() []BoundingBox {
+	if b.West <= b.East {
+		return []BoundingBox{b}
+	}
+	return []BoundingBox{
+		{South: b.South, West: b.West, North: b.North, East: 180},
+		{South: b.South, West: -180, North: b.North, East: b.East},
+	}
+}
+
+// GeohashCover returns geohash prefixes whose cells together cover b, sorted. It returns nil
+// when b is so large that only single-character prefixes would do and filtering by them is
+// pointless.
+func GeohashCover(b BoundingBox) []string {
+	seen := map[string]bool{}
+	for _, part := range b.Split() {
+		cells := geohashCells(part)
+		if cells == nil {
+			return nil
+		}
+		for _, c := range cells {
+			seen[c] = true
+		}
+	}
+	cover := make([]string, 0, len(seen))
+	for c := range seen {
+		cover = append(cover, c)
+	}
+	sort.Strings(cover)
+	return cover
+}
+
+// geohashCells covers a box that doesn't cross the antimeridian with the longest cells that
+// need at most maxGeohashCells.
+func geohashCells(b BoundingBox) []string {
+	for precision := GeohashPrecision - 1; precision >= 2; precision-- {
+		lngCells := 1 << ((5*precision + 1) / 2)
+		latCells := 1 << (5 * precision / 2)
+		w, h := 360/float64(lngCells), 180/float64(latCells)
+		cell := func(deg, origin, size float64, n int) int {
+			return min(n-1, max(0, int(math.Floor((deg-origin)/size))))
+		}
+		x0, x1 := cell(b.West, -180, w, lngCells), cell(b.East, -180, w, lngCells)
+		y0, y1 := cell(b.South, -90, h, latCells), cell(b.North, -90, h, latCells)
+		if (x1-x0+1)*(y1-y0+1) > maxGeohashCells {
+			continue
+		}
+		var cells []string
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				cells = append(cells, Geohash(-90+(float64(y)+0.5)*h, -180+(float64(x)+0.5)*w, precision))
+			}
+		}
+		return cells
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job statuses.
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	// Out of attempts, or the handler reported a permanent failure.
+	JobStatusFailed = "failed"
+)
+
+// Job is a unit of background work claimed by any replica's runner with
+// SELECT … FOR UPDATE SKIP LOCKED.
+type Job struct {
+	UUIDv7
+	Kind    string          `gorm:"not null;index"`
+	Payload json.RawMessage `gorm:"type:jsonb;not null"`
+	Status  string          `gorm:"not null;index:idx_job_due"`
+	// Attempts counts claims, including the one in progress.
+	Attempts    int32     `gorm:"not null;default:0"`
+	MaxAttempts int32     `gorm:"not null"`
+	RunAt       time.Time `gorm:"not null;index:idx_job_due"`
+	// Optional; while a job with this key is pending, enqueueing another is a no-op.
+	DedupeKey *string `gorm:"uniqueIndex:idx_job_pending_dedupe,where:status = 'pending'"`
+	LockedBy  string
+	// Set when claimed; a running job whose lease expired is assumed abandoned.
+	LeaseExpiresAt *time.Time
+	LastError      string
+	FinishedAt     *time.Time `gorm:"index"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (j *Job) BeforeCreate(tx *gorm.DB) (err error) {
+	return j.UUIDv7.BeforeCreate(tx)
+}
+
+// JobSchedule tracks when a recurring job next fires. Replicas race to advance
+// NextRunAt with a conditional UPDATE, and only the winner enqueues the job.
+type JobSchedule struct {
+	Name      string    `gorm:"primaryKey"`
+	Spec      string    `gorm:"not null"`
+	NextRunAt time.Time `gorm:"not null"`
+	LastRunAt *time.Time
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
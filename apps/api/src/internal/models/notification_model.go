@@ -0,0 +1,160 @@
+package models
+
+import (
+	notificationsv1 "api/src/generated/notifications/v1"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification types stored on notifications and preferences.
+const (
+	NotificationTypeFriendRequestReceived       = "friend_request_received"
+	NotificationTypeFriendRequestAccepted       = "friend_request_accepted"
+	NotificationTypeFriendReviewedWishlistPlace = "friend_reviewed_wishlist_place"
+	// Email only; never stored as an in-app notification or pushed.
+	NotificationTypeWeeklyDigest = "weekly_digest"
+)
+
+// NotificationTypes lists every notification type, in proto enum order.
+var NotificationTypes = []string{
+	NotificationTypeFriendRequestReceived,
+	NotificationTypeFriendRequestAccepted,
+	NotificationTypeFriendReviewedWishlistPlace,
+	NotificationTypeWeeklyDigest,
+}
+
+// MaxNotificationActors caps how many actor IDs a coalesced notification keeps.
+// ActorCount keeps counting beyond it.
+const MaxNotificationActors = 10
+
+// Notification is an entry in a user's in-app inbox. Events with the same UserID, Type
+// and SubjectID are coalesced into the newest unread notification instead of creating
+// a new one.
+type Notification struct {
+	UUIDv7
+	UserID      string `gorm:"not null;index:idx_notification_subject"`
+	Type        string `gorm:"not null;index:idx_notification_subject"`
+	SubjectID   string `gorm:"not null;index:idx_notification_subject"`
+	SubjectName string
+	// Most recent first.
+	ActorIDs   []string   `gorm:"serializer:json"`
+	ActorCount int32      `gorm:"not null;default:0"`
+	ReadAt     *time.Time `gorm:"index"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime;index"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+	return n.UUIDv7.BeforeCreate(tx)
+}
+
+// AddActor records another occurrence of the event by actorID, moving them to the front.
+// It reports whether the actor is new to this notification.
+func (n *Notification) AddActor(actorID string) bool {
+	for i, id := range n.ActorIDs {
+		if id == actorID {
+			n.ActorIDs = append(append([]string{actorID}, n.ActorIDs[:i]...), n.ActorIDs[i+1:]...)
+			return false
+		}
+	}
+	n.ActorIDs = append([]string{actorID}, n.ActorIDs...)
+	if len(n.ActorIDs) > MaxNotificationActors {
+		n.ActorIDs = n.ActorIDs[:MaxNotificationActors]
+	}
+	n.ActorCount++
+	return true
+}
+
+// ToProto converts a Notification to its proto representation. Actors missing from
+// users (e.g. deleted accounts) are left out of actors but still counted.
+func (n *Notification) ToProto(users map[string]User) *notificationsv1.NotificationProto {
+	actors := make([]*notificationsv1.NotificationActor, 0, len(n.ActorIDs))
+	for _, id := range n.ActorIDs {
+		u, ok := users[id]
+		if !ok {
+			continue
+		}
+		actors = append(actors, &notificationsv1.NotificationActor{
+			UserId:   u.ID,
+			Name:     u.Name,
+			Username: derefString(u.Username),
+		})
+	}
+	return &notificationsv1.NotificationProto{
+		Id:          n.ID,
+		Type:        NotificationTypeToProto(n.Type),
+		Actors:      actors,
+		ActorCount:  n.ActorCount,
+		SubjectId:   n.SubjectID,
+		SubjectName: n.SubjectName,
+		Read:        n.ReadAt != nil,
+		CreatedAt:   n.CreatedAt.Unix(),
+		UpdatedAt:   n.UpdatedAt.Unix(),
+	}
+}
+
+// NotificationPreference stores a user's choice for one notification type.
+// Types without a row use DefaultNotificationPreference. Email and Push have DB defaults
+// so the columns could be added to existing rows; writes must Select them explicitly,
+// otherwise GORM omits a false value and the default wins.
+type NotificationPreference struct {
+	UserID    string    `gorm:"primaryKey"`
+	Type      string    `gorm:"primaryKey"`
+	InApp     bool      `gorm:"not null"`
+	Email     bool      `gorm:"not null;default:true"`
+	Push      bool      `gorm:"not null;default:true"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (p *NotificationPreference) ToProto() *notificationsv1.NotificationPreferenceProto {
+	return &notificationsv1.NotificationPreferenceProto{
+		Type:  NotificationTypeToProto(p.Type),
+		InApp: p.InApp,
+		Email: p.Email,
+		Push:  p.Push,
+	}
+}
+
+// DefaultNotificationPreference returns the preference applied when a user never changed a type.
+// Everything is on, except in-app and push for the email-only weekly digest.
+func DefaultNotificationPreference(userID, notificationType string) NotificationPreference {
+	return NotificationPreference{
+		UserID: userID,
+		Type:   notificationType,
+		InApp:  notificationType != NotificationTypeWeeklyDigest,
+		Email:  true,
+		Push:   notificationType != NotificationTypeWeeklyDigest,
+	}
+}
+
+func NotificationTypeToProto(v string) notificationsv1.NotificationType {
+	switch v {
+	case NotificationTypeFriendRequestReceived:
+		return notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REQUEST_RECEIVED
+	case NotificationTypeFriendRequestAccepted:
+		return notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REQUEST_ACCEPTED
+	case NotificationTypeFriendReviewedWishlistPlace:
+		return notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REVIEWED_WISHLIST_PLACE
+	case NotificationTypeWeeklyDigest:
+		return notificationsv1.NotificationType_NOTIFICATION_TYPE_WEEKLY_DIGEST
+	default:
+		return notificationsv1.NotificationType_NOTIFICATION_TYPE_UNSPECIFIED
+	}
+}
+
+// NotificationTypeFromProto maps a proto type to its stored value; unspecified maps to "".
+func NotificationTypeFromProto(v notificationsv1.NotificationType) string {
+	switch v {
+	case notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REQUEST_RECEIVED:
+		return NotificationTypeFriendRequestReceived
+	case notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REQUEST_ACCEPTED:
+		return NotificationTypeFriendRequestAccepted
+	case notificationsv1.NotificationType_NOTIFICATION_TYPE_FRIEND_REVIEWED_WISHLIST_PLACE:
+		return NotificationTypeFriendReviewedWishlistPlace
+	case notificationsv1.NotificationType_NOTIFICATION_TYPE_WEEKLY_DIGEST:
+		return NotificationTypeWeeklyDigest
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	tagsv1 "api/src/generated/tags/v1"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalTag is a tag a user made for their own reviews and wishlist items, kept apart
+// from the global tags. Admins can promote popular ones to global tags.
+type PersonalTag struct {
+	UUIDv7
+	OwnerID   string    `gorm:"not null;index;uniqueIndex:idx_personal_tag_owner_slug"`
+	Slug      string    `gorm:"not null;index;uniqueIndex:idx_personal_tag_owner_slug"`
+	Label     string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (t *PersonalTag) BeforeCreate(tx *gorm.DB) (err error) {
+	return t.UUIDv7.BeforeCreate(tx)
+}
+
+func (t *PersonalTag) ToProto() *tagsv1.PersonalTagProto {
+	return &tagsv1.PersonalTagProto{
+		Id:    t.ID,
+		Slug:  t.Slug,
+		Label: t.Label,
+	}
+}
+
+// PersonalTagSlug derives a slug from a label: lowercase letters and digits, with every
+// other run of characters turned into a single dash ("Near office!" → "near-office").
+// It returns "" if the label has no letters or digits.
+func PersonalTagSlug(label string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// personalTagProtos converts personal tags to protos in slug order.
+func personalTagProtos(tags []PersonalTag) []*tagsv1.PersonalTagProto {
+	protos := make([]*tagsv1.PersonalTagProto, len(tags))
+	for i := range tags {
+		protos[i] = tags[i].ToProto()
+	}
+	sort.Slice(protos, func(i, j int) bool { return protos[i].Slug < protos[j].Slug })
+	return protos
+}
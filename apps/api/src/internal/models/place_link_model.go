@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PlaceLink records the OpenStreetMap element a Google place was matched to, so that
+// restaurants stored under Google place names still resolve when the OSM places provider
+// is in use. Names are Places resource names: "places/ChIJ…" and "places/osm-node-123".
+type PlaceLink struct {
+	GoogleName string    `gorm:"primaryKey"`
+	OSMName    string    `gorm:"column:osm_name;not null;index"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
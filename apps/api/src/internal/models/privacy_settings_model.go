@@ -0,0 +1,89 @@
+package models
+
+import (
+	circlesv1 "api/src/generated/circles/v1"
+	userpb "api/src/generated/users/v1"
+	"time"
+)
+
+const (
+	FriendRequestPolicyEveryone         = "everyone"
+	FriendRequestPolicyFriendsOfFriends = "friends_of_friends"
+	FriendRequestPolicyNobody           = "nobody"
+)
+
+// PrivacySettings holds a user's discovery and disclosure preferences.
+// Users without a row get DefaultPrivacySettings, so the table only stores deviations
+// that a user has explicitly saved. Booleans have no DB default on purpose: GORM skips
+// zero values on insert, which would silently turn an explicit false into the default.
+type PrivacySettings struct {
+	UserID                 string    `gorm:"primaryKey"`
+	DiscoverableByUsername bool      `gorm:"not null"`
+	DiscoverableByEmail    bool      `gorm:"not null"`
+	ShowEmailToFriends     bool      `gorm:"not null"`
+	FriendRequestPolicy    string    `gorm:"not null"`
+	DefaultVisibility      string    `gorm:"not null"`
+	SharePersonalTags      bool      `gorm:"not null"`
+	UpdatedAt              time.Time `gorm:"autoUpdateTime"`
+}
+
+// DefaultPrivacySettings returns the settings applied to users who never saved any.
+// They match the behaviour from before privacy settings existed.
+func DefaultPrivacySettings(userID string) PrivacySettings {
+	return PrivacySettings{
+		UserID:                 userID,
+		DiscoverableByUsername: true,
+		DiscoverableByEmail:    true,
+		ShowEmailToFriends:     true,
+		FriendRequestPolicy:    FriendRequestPolicyEveryone,
+		DefaultVisibility:      VisibilityFriends,
+	}
+}
+
+func (p *PrivacySettings) ToProto() *userpb.PrivacySettingsProto {
+	out := &userpb.PrivacySettingsProto{
+		DiscoverableByUsername: p.DiscoverableByUsername,
+		DiscoverableByEmail:    p.DiscoverableByEmail,
+		ShowEmailToFriends:     p.ShowEmailToFriends,
+		FriendRequestPolicy:    FriendRequestPolicyToProto(p.FriendRequestPolicy),
+		DefaultVisibility:      VisibilityToProto(p.DefaultVisibility),
+		SharePersonalTags:      p.SharePersonalTags,
+	}
+	if !p.UpdatedAt.IsZero() {
+		out.UpdatedAt = p.UpdatedAt.Unix()
+	}
+	return out
+}
+
+// DefaultVisibilityProto returns the audience for new content created without one.
+func (p *PrivacySettings) DefaultVisibilityProto() circlesv1.Visibility {
+	return VisibilityToProto(p.DefaultVisibility)
+}
+
+// EmailVisibleTo reports whether this user's email may be shown to a viewer.
+// Only accepted friends can ever see it, and only if the user allows it.
+func (p *PrivacySettings) EmailVisibleTo(viewerIsFriend bool) bool {
+	return viewerIsFriend && p.ShowEmailToFriends
+}
+
+func FriendRequestPolicyToProto(v string) userpb.FriendRequestPolicy {
+	switch v {
+	case FriendRequestPolicyFriendsOfFriends:
+		return userpb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS
+	case FriendRequestPolicyNobody:
+		return userpb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY
+	default:
+		return userpb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_EVERYONE
+	}
+}
+
+func FriendRequestPolicyFromProto(v userpb.FriendRequestPolicy) string {
+	switch v {
+	case userpb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS:
+		return FriendRequestPolicyFriendsOfFriends
+	case userpb.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY:
+		return FriendRequestPolicyNobody
+	default:
+		return FriendRequestPolicyEveryone
+	}
+}
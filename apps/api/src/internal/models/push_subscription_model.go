@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PushSubscription is one browser's Web Push subscription. A user has one per device
+// they enabled notifications on.
+type PushSubscription struct {
+	UUIDv7
+	UserID string `gorm:"not null;index"`
+	// The push service URL; unique because a browser subscription belongs to one user.
+	Endpoint  string `gorm:"not null;uniqueIndex"`
+	P256dh    string `gorm:"not null"`
+	Auth      string `gorm:"not null"`
+	UserAgent string
+	// SHA-256 of the session token it was registered under, so signing out of that
+	// session (or all of them) removes it. The token itself is never stored.
+	SessionHash string `gorm:"not null;index"`
+	LastUsedAt  *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (p *PushSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	return p.UUIDv7.BeforeCreate(tx)
+}
+
+// Push outbox statuses.
+const (
+	PushStatusPending = "pending"
+	PushStatusSent    = "sent"
+	// Gave up after too many attempts.
+	PushStatusFailed = "failed"
+	// Dropped on purpose: the subscription is gone or the user turned push off.
+	PushStatusSkipped = "skipped"
+)
+
+// PushOutbox is a queued push message for a single subscription. Like EmailOutbox it
+// stores the notification type and its inputs, and is rendered at send time.
+type PushOutbox struct {
+	UUIDv7
+	UserID         string            `gorm:"not null;index"`
+	SubscriptionID string            `gorm:"not null;index"`
+	Template       string            `gorm:"not null"`
+	Params         map[string]string `gorm:"serializer:json"`
+	Status         string            `gorm:"not null;index:idx_push_outbox_due"`
+	Attempts       int32             `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time         `gorm:"not null;index:idx_push_outbox_due"`
+	LastError      string
+	SentAt         *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (p *PushOutbox) BeforeCreate(tx *gorm.DB) (err error) {
+	return p.UUIDv7.BeforeCreate(tx)
+}
@@ -7,16 +7,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// Google Places business statuses, as stored in Restaurant.BusinessStatus.
+const (
+	BusinessStatusOperational       = "OPERATIONAL"
+	BusinessStatusClosedTemporarily = "CLOSED_TEMPORARILY"
+	BusinessStatusClosedPermanently = "CLOSED_PERMANENTLY"
+)
+
+var businessStatusToProto = map[string]restaurantpb.RestaurantStatus{
+	BusinessStatusOperational:       restaurantpb.RestaurantStatus_RESTAURANT_STATUS_OPERATIONAL,
+	BusinessStatusClosedTemporarily: restaurantpb.RestaurantStatus_RESTAURANT_STATUS_CLOSED_TEMPORARILY,
+	BusinessStatusClosedPermanently: restaurantpb.RestaurantStatus_RESTAURANT_STATUS_CLOSED_PERMANENTLY,
+}
+
 type Restaurant struct {
 	UUIDv7
-	GoogleID       string    `gorm:"uniqueIndex" json:"googleId"`
-	Address        string    `gorm:"uniqueIndex" json:"email"`
-	Name           string    `gorm:"not null" json:"name"`
-	City           string    `gorm:"index"`
-	Country        string    `gorm:"index"`
+	GoogleID       string `gorm:"uniqueIndex" json:"googleId"`
+	Address        string `gorm:"uniqueIndex" json:"email"`
+	Name           string `gorm:"not null" json:"name"`
+	City           string `gorm:"index"`
+	Country        string `gorm:"index"`
 	PhotoReference string
-	CreatedAt      time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+	Latitude       *float64   `gorm:"type:double precision"`
+	Longitude      *float64   `gorm:"type:double precision"`
+	Geohash        string     `gorm:"index:idx_restaurants_geohash"` // empty without coordinates
+	BusinessStatus string     `gorm:"not null;default:''"`           // a BusinessStatus* value, or empty
+	RefreshedAt    *time.Time `gorm:"index"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
 func (r *Restaurant) BeforeCreate(tx *gorm.DB) (err error) {
@@ -27,7 +45,7 @@ func (r *Restaurant) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 func (r *Restaurant) ToProto() *restaurantpb.RestaurantProto {
-	return &restaurantpb.RestaurantProto{
+	p := &restaurantpb.RestaurantProto{
 		Id:             r.ID,
 		GooglePlacesId: r.GoogleID,
 		Address:        r.Address,
@@ -35,7 +53,30 @@ func (r *Restaurant) ToProto() *restaurantpb.RestaurantProto {
 		City:           r.City,
 		Country:        r.Country,
 		PhotoReference: r.PhotoReference,
+		Latitude:       r.Latitude,
+		Longitude:      r.Longitude,
+		Status:         businessStatusToProto[r.BusinessStatus],
 		CreatedAt:      r.CreatedAt.Unix(),
 		UpdatedAt:      r.UpdatedAt.Unix(),
 	}
+	if r.RefreshedAt != nil {
+		p.RefreshedAt = r.RefreshedAt.Unix()
+	}
+	return p
+}
+
+// SetLocation stores the restaurant's coordinates and their geohash.
+func (r *Restaurant) SetLocation(lat, lng float64) {
+	r.Latitude, r.Longitude = &lat, &lng
+	r.Geohash = Geohash(lat, lng, GeohashPrecision)
+}
+
+// HasLocation reports whether the restaurant's coordinates are known.
+func (r *Restaurant) HasLocation() bool {
+	return r.Latitude != nil && r.Longitude != nil
+}
+
+// Closed reports whether Google Places lists the restaurant as closed permanently.
+func (r *Restaurant) Closed() bool {
+	return r.BusinessStatus == BusinessStatusClosedPermanently
 }
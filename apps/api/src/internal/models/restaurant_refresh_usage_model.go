@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RestaurantRefreshUsage counts the Google Places requests the restaurant refresher made
+// on one UTC day, which is what its daily budget limits. Lookups made for users are not
+// counted.
+type RestaurantRefreshUsage struct {
+	Day      time.Time `gorm:"primaryKey;type:date"`
+	Requests int       `gorm:"not null;default:0"`
+}
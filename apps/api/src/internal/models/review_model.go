@@ -1,7 +1,10 @@
 package models
 
 import (
+	circlesv1 "api/src/generated/circles/v1"
 	reviewspb "api/src/generated/reviews/v1"
+	tagsv1 "api/src/generated/tags/v1"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,11 +19,21 @@ type Review struct {
 	GooglePlacesID     string     `gorm:"index"`
 	Comment            string
 	Rating             float64  `gorm:"not null"`
-	Tags               []string `gorm:"serializer:json"`
+	Tags               []Tag    `gorm:"many2many:review_tags"`
+	PersonalTags       []PersonalTag `gorm:"many2many:review_personal_tags"`
 	VisitedAt          *time.Time
 	PricePaidPerPerson int32
 	WouldVisitAgain    int32
 	DishHighlights     string
+	Dishes             []ReviewDish `gorm:"foreignKey:ReviewID"`
+	// Optional 1–5 sub-scores; nil when not scored.
+	FoodScore          *float64
+	ServiceScore       *float64
+	AmbienceScore      *float64
+	ValueScore         *float64
+	DrinksScore        *float64
+	Visibility         string    `gorm:"not null;default:'friends';index"`
+	Circles            []Circle  `gorm:"many2many:review_circles"`
 	CreatedAt          time.Time `gorm:"autoCreateTime"`
 	UpdatedAt          time.Time `gorm:"autoUpdateTime"`
 }
@@ -31,11 +44,10 @@ func (r *Review) BeforeCreate(tx *gorm.DB) (err error) {
 
 // ToProto converts a Review to its proto representation.
 // Restaurant must be preloaded (or assigned) for restaurant fields to be populated.
+// Tags and Dishes.Dish must be preloaded too; they are always visible. Personal tags are only preloaded
+// for viewers allowed to see them.
+// Circles are only preloaded for the author, so friends never see circle IDs.
 func (r *Review) ToProto() *reviewspb.ReviewProto {
-	tags := r.Tags
-	if tags == nil {
-		tags = []string{}
-	}
 	p := &reviewspb.ReviewProto{
 		Id:                 r.ID,
 		UserId:             r.UserID,
@@ -43,7 +55,7 @@ func (r *Review) ToProto() *reviewspb.ReviewProto {
 		GooglePlacesId:     r.GooglePlacesID,
 		Comment:            r.Comment,
 		Rating:             r.Rating,
-		Tags:               tags,
+		Tags:               tagSlugs(r.Tags),
 		CreatedAt:          r.CreatedAt.Unix(),
 		UpdatedAt:          r.UpdatedAt.Unix(),
 		RestaurantName:           r.Restaurant.Name,
@@ -51,13 +63,121 @@ func (r *Review) ToProto() *reviewspb.ReviewProto {
 		RestaurantCity:           r.Restaurant.City,
 		RestaurantCountry:        r.Restaurant.Country,
 		RestaurantPhotoReference: r.Restaurant.PhotoReference,
+		RestaurantClosed:         r.Restaurant.Closed(),
 		AuthorName:               r.User.Name,
 		PricePaidPerPerson: r.PricePaidPerPerson,
 		WouldVisitAgain:    reviewspb.WouldVisitAgain(r.WouldVisitAgain),
 		DishHighlights:     r.DishHighlights,
+		Visibility:         VisibilityToProto(r.Visibility),
+		CircleIds:          circleIDs(r.Circles),
+		PersonalTags:       personalTagProtos(r.PersonalTags),
+		Scores:             r.ScoresProto(),
+		Dishes:             reviewDishProtos(r.Dishes),
 	}
 	if r.VisitedAt != nil {
 		p.VisitedAt = r.VisitedAt.Unix()
 	}
 	return p
 }
+
+// ToPublicProto converts a Review for display outside the author's friend graph,
+// e.g. on a shared list. User ID, visibility, circles and personal tags are left out.
+func (r *Review) ToPublicProto() *reviewspb.ReviewProto {
+	p := r.ToProto()
+	p.UserId = ""
+	p.Visibility = circlesv1.Visibility_VISIBILITY_UNSPECIFIED
+	p.CircleIds = []string{}
+	p.PersonalTags = []*tagsv1.PersonalTagProto{}
+	return p
+}
+
+// ScoreWeights are the weights of the sub-scores when the rating is computed from them.
+var ScoreWeights = map[reviewspb.ScoreDimension]float64{
+	reviewspb.ScoreDimension_SCORE_DIMENSION_FOOD:     0.4,
+	reviewspb.ScoreDimension_SCORE_DIMENSION_SERVICE:  0.2,
+	reviewspb.ScoreDimension_SCORE_DIMENSION_AMBIENCE: 0.15,
+	reviewspb.ScoreDimension_SCORE_DIMENSION_VALUE:    0.15,
+	reviewspb.ScoreDimension_SCORE_DIMENSION_DRINKS:   0.1,
+}
+
+// ScoreColumn returns the reviews column of a sub-score dimension, or "" for an unknown one.
+func ScoreColumn(d reviewspb.ScoreDimension) string {
+	switch d {
+	case reviewspb.ScoreDimension_SCORE_DIMENSION_FOOD:
+		return "food_score"
+	case reviewspb.ScoreDimension_SCORE_DIMENSION_SERVICE:
+		return "service_score"
+	case reviewspb.ScoreDimension_SCORE_DIMENSION_AMBIENCE:
+		return "ambience_score"
+	case reviewspb.ScoreDimension_SCORE_DIMENSION_VALUE:
+		return "value_score"
+	case reviewspb.ScoreDimension_SCORE_DIMENSION_DRINKS:
+		return "drinks_score"
+	default:
+		return ""
+	}
+}
+
+// Scores returns the set sub-scores by dimension.
+func (r *Review) Scores() map[reviewspb.ScoreDimension]float64 {
+	out := map[reviewspb.ScoreDimension]float64{}
+	for d, v := range map[reviewspb.ScoreDimension]*float64{
+		reviewspb.ScoreDimension_SCORE_DIMENSION_FOOD:     r.FoodScore,
+		reviewspb.ScoreDimension_SCORE_DIMENSION_SERVICE:  r.ServiceScore,
+		reviewspb.ScoreDimension_SCORE_DIMENSION_AMBIENCE: r.AmbienceScore,
+		reviewspb.ScoreDimension_SCORE_DIMENSION_VALUE:    r.ValueScore,
+		reviewspb.ScoreDimension_SCORE_DIMENSION_DRINKS:   r.DrinksScore,
+	} {
+		if v != nil {
+			out[d] = *v
+		}
+	}
+	return out
+}
+
+// SetScores replaces all sub-scores; zero values clear them.
+func (r *Review) SetScores(s *reviewspb.ReviewScores) {
+	r.FoodScore = scorePtr(s.GetFood())
+	r.ServiceScore = scorePtr(s.GetService())
+	r.AmbienceScore = scorePtr(s.GetAmbience())
+	r.ValueScore = scorePtr(s.GetValue())
+	r.DrinksScore = scorePtr(s.GetDrinks())
+}
+
+func (r *Review) ScoresProto() *reviewspb.ReviewScores {
+	return &reviewspb.ReviewScores{
+		Food:     scoreValue(r.FoodScore),
+		Service:  scoreValue(r.ServiceScore),
+		Ambience: scoreValue(r.AmbienceScore),
+		Value:    scoreValue(r.ValueScore),
+		Drinks:   scoreValue(r.DrinksScore),
+	}
+}
+
+// RatingFromScores returns the mean of the set sub-scores weighted by ScoreWeights,
+// rounded to one decimal. It reports false when no sub-score is set.
+func (r *Review) RatingFromScores() (float64, bool) {
+	var sum, weights float64
+	for d, v := range r.Scores() {
+		sum += ScoreWeights[d] * v
+		weights += ScoreWeights[d]
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return math.Round(sum/weights*10) / 10, true
+}
+
+func scorePtr(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func scoreValue(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	reviewspb "api/src/generated/reviews/v1"
+	sharedlistsv1 "api/src/generated/shared_lists/v1"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shared list modes.
+const (
+	SharedListModeSnapshot = "snapshot"
+	SharedListModeLive     = "live"
+)
+
+// SharedList is a read-only, publicly linkable selection of one user's reviews.
+// It is either hand-picked (ReviewIDs only) or defined by a saved Filter; snapshot
+// lists additionally freeze the matching ReviewIDs at publish time, and Snapshot holds
+// the public content of those reviews as it was then.
+type SharedList struct {
+	UUIDv7
+	OwnerID     string `gorm:"not null;index"`
+	Owner       User   `gorm:"foreignKey:OwnerID"`
+	Slug        string `gorm:"not null;uniqueIndex"`
+	Title       string `gorm:"not null"`
+	Description string
+	Mode        string            `gorm:"not null"`
+	Filter      *SharedListFilter `gorm:"serializer:json"`
+	ReviewIDs   []string          `gorm:"serializer:json"`
+	Snapshot    []byte            // a marshaled sharedlistsv1.SharedListSnapshot; nil for live and older lists
+	ViewCount   int64             `gorm:"not null;default:0"`
+	ExpiresAt   *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// SharedListFilter mirrors the ListReviews filters that can be saved on a shared list.
+type SharedListFilter struct {
+	TagSlugs      []string `json:"tag_slugs,omitempty"`
+	TagFilterMode int32    `json:"tag_filter_mode,omitempty"`
+	MinRating     float64  `json:"min_rating,omitempty"`
+	MaxRating     float64  `json:"max_rating,omitempty"`
+	City          string   `json:"city,omitempty"`
+	Country       string   `json:"country,omitempty"`
+	SortBy        int32    `json:"sort_by,omitempty"`
+}
+
+func (l *SharedList) BeforeCreate(tx *gorm.DB) (err error) {
+	return l.UUIDv7.BeforeCreate(tx)
+}
+
+// IsAccessible reports whether the public link still works at the given time.
+func (l *SharedList) IsAccessible(now time.Time) bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	return l.ExpiresAt == nil || now.Before(*l.ExpiresAt)
+}
+
+// ToProto converts a SharedList to its proto representation for the owner.
+func (l *SharedList) ToProto() *sharedlistsv1.SharedListProto {
+	reviewIDs := l.ReviewIDs
+	if reviewIDs == nil {
+		reviewIDs = []string{}
+	}
+	p := &sharedlistsv1.SharedListProto{
+		Id:          l.ID,
+		Slug:        l.Slug,
+		Title:       l.Title,
+		Description: l.Description,
+		Mode:        SharedListModeToProto(l.Mode),
+		Filter:      l.Filter.ToProto(),
+		ReviewIds:   reviewIDs,
+		ViewCount:   l.ViewCount,
+		Revoked:     l.RevokedAt != nil,
+		CreatedAt:   l.CreatedAt.Unix(),
+		UpdatedAt:   l.UpdatedAt.Unix(),
+	}
+	if l.ExpiresAt != nil {
+		p.ExpiresAt = l.ExpiresAt.Unix()
+	}
+	return p
+}
+
+// ToProto converts the filter to its proto representation; nil stays nil.
+func (f *SharedListFilter) ToProto() *sharedlistsv1.SharedListFilter {
+	if f == nil {
+		return nil
+	}
+	tagSlugs := f.TagSlugs
+	if tagSlugs == nil {
+		tagSlugs = []string{}
+	}
+	return &sharedlistsv1.SharedListFilter{
+		TagSlugs:      tagSlugs,
+		TagFilterMode: reviewspb.TagFilterMode(f.TagFilterMode),
+		MinRating:     f.MinRating,
+		MaxRating:     f.MaxRating,
+		City:          f.City,
+		Country:       f.Country,
+		SortBy:        reviewspb.ReviewSortBy(f.SortBy),
+	}
+}
+
+// SharedListFilterFromProto converts a proto filter for storage; nil stays nil.
+func SharedListFilterFromProto(f *sharedlistsv1.SharedListFilter) *SharedListFilter {
+	if f == nil {
+		return nil
+	}
+	return &SharedListFilter{
+		TagSlugs:      f.TagSlugs,
+		TagFilterMode: int32(f.TagFilterMode),
+		MinRating:     f.MinRating,
+		MaxRating:     f.MaxRating,
+		City:          f.City,
+		Country:       f.Country,
+		SortBy:        int32(f.SortBy),
+	}
+}
+
+// SharedListModeToProto maps a stored mode to its proto enum.
+func SharedListModeToProto(v string) sharedlistsv1.SharedListMode {
+	if v == SharedListModeLive {
+		return sharedlistsv1.SharedListMode_SHARED_LIST_MODE_LIVE
+	}
+	return sharedlistsv1.SharedListMode_SHARED_LIST_MODE_SNAPSHOT
+}
+
+// SharedListModeFromProto maps a proto mode to its stored value. Unspecified means snapshot,
+// so nothing published is re-evaluated unless the owner explicitly asked for it.
+func SharedListModeFromProto(v sharedlistsv1.SharedListMode) string {
+	if v == sharedlistsv1.SharedListMode_SHARED_LIST_MODE_LIVE {
+		return SharedListModeLive
+	}
+	return SharedListModeSnapshot
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	tagsv1 "api/src/generated/tags/v1"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +16,18 @@ type Tag struct {
 	Label     string    `gorm:"not null"`
 	Category  string    `gorm:"not null;index"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
+	// Set when an admin retires the tag; existing reviews keep it.
+	DeprecatedAt *time.Time
+	// Label is English; these are the other languages.
+	Translations []TagTranslation `gorm:"foreignKey:TagSlug;references:Slug"`
+}
+
+// TagTranslation is a tag's label in a language other than English.
+type TagTranslation struct {
+	TagSlug string `gorm:"primaryKey"`
+	// Lowercase language code without region, e.g. "pl".
+	Locale string `gorm:"primaryKey"`
+	Label  string `gorm:"not null"`
 }
 
 func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
@@ -21,9 +36,38 @@ func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
 
 func (t *Tag) ToProto() *tagsv1.TagProto {
 	return &tagsv1.TagProto{
-		Id:       t.ID,
-		Slug:     t.Slug,
-		Label:    t.Label,
-		Category: t.Category,
+		Id:         t.ID,
+		Slug:       t.Slug,
+		Label:      t.Label,
+		Category:   t.Category,
+		Deprecated: t.DeprecatedAt != nil,
+	}
+}
+
+// tagSlugs returns the slugs of tags in alphabetical order.
+func tagSlugs(tags []Tag) []string {
+	slugs := make([]string, len(tags))
+	for i, t := range tags {
+		slugs[i] = t.Slug
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// DefaultTagLocale is the language of Tag.Label.
+const DefaultTagLocale = "en"
+
+var tagLocalePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// NormalizeTagLocale reduces a language tag ("pl-PL", "PL", "pl_pl") to the lowercase
+// language code used by TagTranslation, or returns "" if it isn't one.
+func NormalizeTagLocale(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if !tagLocalePattern.MatchString(lang) {
+		return ""
 	}
+	return lang
 }
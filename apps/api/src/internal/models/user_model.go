@@ -16,6 +16,7 @@ type User struct {
 	IsDarkModeEnabled  bool      `gorm:"default:false"`
 	DefaultRegion      string    `gorm:"default:''"`
 	DefaultLanguage    string    `gorm:"default:''"`
+	IsAdmin            bool      `gorm:"not null;default:false"`
 	CreatedAt          time.Time `gorm:"autoCreateTime"`
 	UpdatedAt          time.Time `gorm:"autoUpdateTime"`
 }
@@ -42,6 +43,22 @@ func (u *User) ToProto() *userpb.UserProto {
 	}
 }
 
+// ToPublicProto converts a User to the proto shown to other users. Provider IDs and
+// preferences are never included; the email only when showEmail is true (see
+// PrivacySettings.EmailVisibleTo).
+func (u *User) ToPublicProto(showEmail bool) *userpb.UserProto {
+	p := &userpb.UserProto{
+		Id:        u.ID,
+		Username:  derefString(u.Username),
+		Name:      u.Name,
+		CreatedAt: u.CreatedAt.Unix(),
+	}
+	if showEmail {
+		p.Email = derefString(u.Email)
+	}
+	return p
+}
+
 // StringPtr returns a pointer to s, or nil if s is empty.
 func StringPtr(s string) *string {
 	if s == "" {
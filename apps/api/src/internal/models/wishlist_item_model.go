@@ -9,12 +9,15 @@ import (
 
 type WishlistItem struct {
 	UUIDv7
-	UserID         string     `gorm:"not null;index;uniqueIndex:idx_wishlist_user_restaurant"`
-	RestaurantID   string     `gorm:"not null;index;uniqueIndex:idx_wishlist_user_restaurant"`
-	Restaurant     Restaurant `gorm:"foreignKey:RestaurantID"`
-	GooglePlacesID string     `gorm:"not null;index"`
-	Tags           []string   `gorm:"serializer:json"`
-	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UserID         string        `gorm:"not null;index;uniqueIndex:idx_wishlist_user_restaurant"`
+	RestaurantID   string        `gorm:"not null;index;uniqueIndex:idx_wishlist_user_restaurant"`
+	Restaurant     Restaurant    `gorm:"foreignKey:RestaurantID"`
+	GooglePlacesID string        `gorm:"not null;index"`
+	Tags           []Tag         `gorm:"many2many:wishlist_item_tags"`
+	Visibility     string        `gorm:"not null;default:'friends';index"`
+	Circles        []Circle      `gorm:"many2many:wishlist_item_circles"`
+	PersonalTags   []PersonalTag `gorm:"many2many:wishlist_item_personal_tags"`
+	CreatedAt      time.Time     `gorm:"autoCreateTime"`
 }
 
 func (w *WishlistItem) BeforeCreate(tx *gorm.DB) (err error) {
@@ -22,10 +25,6 @@ func (w *WishlistItem) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 func (w *WishlistItem) ToProto() *wishlistv1.WishlistItemProto {
-	tags := w.Tags
-	if tags == nil {
-		tags = []string{}
-	}
 	return &wishlistv1.WishlistItemProto{
 		Id:                       w.ID,
 		GooglePlacesId:           w.GooglePlacesID,
@@ -34,7 +33,11 @@ func (w *WishlistItem) ToProto() *wishlistv1.WishlistItemProto {
 		City:                     w.Restaurant.City,
 		Country:                  w.Restaurant.Country,
 		RestaurantPhotoReference: w.Restaurant.PhotoReference,
+		RestaurantClosed:         w.Restaurant.Closed(),
 		CreatedAt:                w.CreatedAt.Unix(),
-		Tags:                     tags,
+		Tags:                     tagSlugs(w.Tags),
+		Visibility:               VisibilityToProto(w.Visibility),
+		CircleIds:                circleIDs(w.Circles),
+		PersonalTags:             personalTagProtos(w.PersonalTags),
 	}
 }
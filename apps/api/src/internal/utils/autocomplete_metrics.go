@@ -0,0 +1,34 @@
+package utils
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Outcomes recorded by AutocompleteSessionMetrics.
+const (
+	AutocompleteSessionStarted   = "started"
+	AutocompleteSessionCompleted = "completed" // ended by a details call
+	AutocompleteSessionAbandoned = "abandoned" // replaced or expired before a details call
+)
+
+// AutocompleteSessionMetrics counts Places autocomplete sessions. Google bills a completed
+// session once; the requests of an abandoned one are billed one by one.
+type AutocompleteSessionMetrics struct {
+	sessions *prometheus.CounterVec
+}
+
+func NewAutocompleteSessionMetrics(reg prometheus.Registerer) *AutocompleteSessionMetrics {
+	m := &AutocompleteSessionMetrics{
+		sessions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "places_autocomplete_sessions_total",
+				Help: "Places autocomplete sessions by outcome (started, completed, abandoned).",
+			},
+			[]string{"outcome"},
+		),
+	}
+	reg.MustRegister(m.sessions)
+	return m
+}
+
+func (m *AutocompleteSessionMetrics) Observe(outcome string) {
+	m.sessions.WithLabelValues(outcome).Inc()
+}
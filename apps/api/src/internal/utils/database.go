@@ -2,126 +2,89 @@ package utils
 
 import (
 	"api/src/internal/models"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
 
+	"gopkg.in/yaml.v3"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-// RequiredTags is the canonical list of predefined tags seeded into the DB.
-// Exported so tests can verify the list without a DB connection.
-var RequiredTags = []models.Tag{
-	// Cuisine
-	{Slug: "italian", Label: "Italian", Category: "Cuisine"},
-	{Slug: "japanese", Label: "Japanese", Category: "Cuisine"},
-	{Slug: "mexican", Label: "Mexican", Category: "Cuisine"},
-	{Slug: "chinese", Label: "Chinese", Category: "Cuisine"},
-	{Slug: "indian", Label: "Indian", Category: "Cuisine"},
-	{Slug: "french", Label: "French", Category: "Cuisine"},
-	{Slug: "thai", Label: "Thai", Category: "Cuisine"},
-	{Slug: "american", Label: "American", Category: "Cuisine"},
-	{Slug: "mediterranean", Label: "Mediterranean", Category: "Cuisine"},
-	{Slug: "korean", Label: "Korean", Category: "Cuisine"},
-	// Vibe
-	{Slug: "romantic", Label: "Romantic", Category: "Vibe"},
-	{Slug: "casual", Label: "Casual", Category: "Vibe"},
-	{Slug: "family-friendly", Label: "Family Friendly", Category: "Vibe"},
-	{Slug: "date-night", Label: "Date Night", Category: "Vibe"},
-	{Slug: "business-lunch", Label: "Business Lunch", Category: "Vibe"},
-	{Slug: "lively", Label: "Lively", Category: "Vibe"},
-	{Slug: "quiet", Label: "Quiet", Category: "Vibe"},
-	{Slug: "trendy", Label: "Trendy", Category: "Vibe"},
-	// Price
-	{Slug: "budget", Label: "Budget", Category: "Price"},
-	{Slug: "mid-range", Label: "Mid-Range", Category: "Price"},
-	{Slug: "expensive", Label: "Expensive", Category: "Price"},
-	{Slug: "splurge", Label: "Splurge", Category: "Price"},
-	// Dietary
-	{Slug: "vegan", Label: "Vegan", Category: "Dietary"},
-	{Slug: "vegetarian", Label: "Vegetarian", Category: "Dietary"},
-	{Slug: "gluten-free", Label: "Gluten-Free", Category: "Dietary"},
-	{Slug: "halal", Label: "Halal", Category: "Dietary"},
-	{Slug: "kosher", Label: "Kosher", Category: "Dietary"},
-	{Slug: "dairy-free", Label: "Dairy-Free", Category: "Dietary"},
-	// Group
-	{Slug: "solo", Label: "Solo", Category: "Group"},
-	{Slug: "couple", Label: "Couple", Category: "Group"},
-	{Slug: "small-group", Label: "Small Group", Category: "Group"},
-	{Slug: "large-group", Label: "Large Group", Category: "Group"},
-	// Occasion
-	{Slug: "birthday", Label: "Birthday", Category: "Occasion"},
-	{Slug: "anniversary", Label: "Anniversary", Category: "Occasion"},
-	{Slug: "brunch", Label: "Brunch", Category: "Occasion"},
-	{Slug: "late-night", Label: "Late Night", Category: "Occasion"},
-	{Slug: "celebration", Label: "Celebration", Category: "Occasion"},
-	{Slug: "quick-bite", Label: "Quick Bite", Category: "Occasion"},
-}
+//go:embed seed/tags.yaml
+var defaultTagsFile []byte
 
-// removedSlugs lists tag slugs that were once seeded but have since been
-// removed. SeedRequiredData deletes them on every startup so they don't
-// linger in existing databases.
-var removedSlugs = []string{
-	"fast-service", "outdoor-seating", "delivery", "takeaway", "reservations", "dog-friendly",
+// RequiredTags are the tags seeded into an empty database, read from seed/tags.yaml.
+// Exported so tests can verify the list without a DB connection.
+var RequiredTags = mustParseTags(defaultTagsFile)
+
+type tagEntry struct {
+	Slug     string `yaml:"slug"`
+	Label    string `yaml:"label"`
+	Category string `yaml:"category"`
+	// Labels in other languages, keyed by language code.
+	Labels map[string]string `yaml:"labels"`
 }
 
-// SeedRequiredData seeds production-required data unconditionally using upsert.
-// Safe to call on every startup — idempotent by slug.
-func SeedRequiredData(db *gorm.DB) error {
-	slog.Info("Seeding required data (tags)...")
-
-	// Remove tags that are no longer part of the canonical list.
-	if len(removedSlugs) > 0 {
-		if err := db.Where("slug IN ?", removedSlugs).Delete(&models.Tag{}).Error; err != nil {
-			return err
-		}
+// ParseTags reads a YAML or JSON list of {slug, label, category, labels} objects.
+func ParseTags(raw []byte) ([]models.Tag, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	var entries []tagEntry
+	if err := dec.Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
 	}
-
-	// Copy the list so GORM's ID-setting side-effect doesn't mutate RequiredTags
-	tags := make([]models.Tag, len(RequiredTags))
-	copy(tags, RequiredTags)
-
-	result := db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "slug"}},
-		DoUpdates: clause.AssignmentColumns([]string{"label", "category"}),
-	}).Create(&tags)
-	if result.Error != nil {
-		return result.Error
+	tags := make([]models.Tag, len(entries))
+	for i, e := range entries {
+		tags[i] = models.Tag{Slug: e.Slug, Label: e.Label, Category: e.Category}
+		for locale, label := range e.Labels {
+			tags[i].Translations = append(tags[i].Translations, models.TagTranslation{TagSlug: e.Slug, Locale: locale, Label: label})
+		}
+		sort.Slice(tags[i].Translations, func(a, b int) bool {
+			return tags[i].Translations[a].Locale < tags[i].Translations[b].Locale
+		})
 	}
-
-	slog.Info("Required data seeded successfully", slog.Int64("tags", int64(len(tags))))
-	return nil
+	return tags, nil
 }
 
-func CreateSchema(db *gorm.DB) error {
-	slog.Info("Creating database schema...")
-
-	if err := db.AutoMigrate(&models.Restaurant{}); err != nil {
-		return err
-	}
-
-	if err := db.AutoMigrate(&models.User{}); err != nil {
-		return err
+func mustParseTags(raw []byte) []models.Tag {
+	tags, err := ParseTags(raw)
+	if err != nil {
+		panic(fmt.Sprintf("seed/tags.yaml: %v", err))
 	}
+	return tags
+}
 
-	if err := db.AutoMigrate(&models.Review{}); err != nil {
+// SeedRequiredData seeds production-required data. Tags are only seeded into an empty
+// table: once a database has tags, admins own them and a deploy must not undo their edits.
+func SeedRequiredData(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Tag{}).Count(&count).Error; err != nil {
 		return err
 	}
-
-	if err := db.AutoMigrate(&models.Tag{}); err != nil {
-		return err
+	if count > 0 {
+		slog.Info("Tags already present, skipping seed")
+		return nil
 	}
 
-	if err := db.AutoMigrate(&models.WishlistItem{}); err != nil {
-		return err
-	}
+	slog.Info("Seeding required data (tags)...")
+	// Copy the list so GORM's ID-setting side-effect doesn't mutate RequiredTags. Their
+	// translations are created along with them.
+	tags := make([]models.Tag, len(RequiredTags))
+	copy(tags, RequiredTags)
 
-	if err := db.AutoMigrate(&models.FriendRequest{}); err != nil {
-		return err
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags)
+	if result.Error != nil {
+		return result.Error
 	}
 
-	slog.Info("Database schema created successfully")
+	slog.Info("Required data seeded successfully", slog.Int64("tags", result.RowsAffected))
 	return nil
 }
 
@@ -169,16 +132,23 @@ func seedUsers(db *gorm.DB) error {
 	return nil
 }
 
+// SeedDatabase seeds sample data when running in dev with SEED=true.
 func SeedDatabase(db *gorm.DB) error {
 	if os.Getenv("ENV") == "dev" && strings.EqualFold(os.Getenv("SEED"), "true") {
 		slog.Info("Development environment detected with SEED=true, seeding database...")
-		if err := seedRestaurants(db); err != nil {
-			return err
-		}
-		if err := seedUsers(db); err != nil {
-			return err
-		}
-		slog.Info("Database seeding completed")
+		return SeedSampleData(db)
+	}
+	return nil
+}
+
+// SeedSampleData adds sample restaurants and users to tables that are still empty.
+func SeedSampleData(db *gorm.DB) error {
+	if err := seedRestaurants(db); err != nil {
+		return err
+	}
+	if err := seedUsers(db); err != nil {
+		return err
 	}
+	slog.Info("Database seeding completed")
 	return nil
 }
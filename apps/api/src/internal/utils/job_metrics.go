@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// JobMetrics instruments the background job runner.
+type JobMetrics struct {
+	processed *prometheus.CounterVec
+	running   *prometheus.GaugeVec
+	duration  *prometheus.HistogramVec
+	enqueued  *prometheus.CounterVec
+}
+
+func NewJobMetrics(reg prometheus.Registerer) *JobMetrics {
+	m := &JobMetrics{
+		processed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_processed_total",
+				Help: "Jobs processed by kind and outcome (succeeded, retried, failed).",
+			},
+			[]string{"kind", "outcome"},
+		),
+		running: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "jobs_running",
+				Help: "Jobs currently running in this process by kind.",
+			},
+			[]string{"kind"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "job_duration_seconds",
+				Help:    "Job handler duration by kind.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"kind"},
+		),
+		enqueued: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_scheduled_total",
+				Help: "Recurring jobs enqueued by this process by schedule name.",
+			},
+			[]string{"schedule"},
+		),
+	}
+	reg.MustRegister(m.processed, m.running, m.duration, m.enqueued)
+	return m
+}
+
+func (m *JobMetrics) Started(kind string) {
+	m.running.WithLabelValues(kind).Inc()
+}
+
+func (m *JobMetrics) Finished(kind, outcome string, d time.Duration) {
+	m.running.WithLabelValues(kind).Dec()
+	m.duration.WithLabelValues(kind).Observe(d.Seconds())
+	m.processed.WithLabelValues(kind, outcome).Inc()
+}
+
+func (m *JobMetrics) Scheduled(name string) {
+	m.enqueued.WithLabelValues(name).Inc()
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Results recorded by PlacePhotoMetrics.
+const (
+	PlacePhotoHit           = "hit"            // photo URI served from the cache
+	PlacePhotoMiss          = "miss"           // photo URI resolved through Google
+	PlacePhotoNotModified   = "not_modified"   // the client's cached redirect is still valid
+	PlacePhotoInvalid       = "invalid"        // bad name or size
+	PlacePhotoRateLimited   = "rate_limited"   // the client exceeded its rate
+	PlacePhotoCircuitOpen   = "circuit_open"   // Google was failing, so the call was not made
+	PlacePhotoUpstreamError = "upstream_error" // Google failed or timed out
+)
+
+// PlacePhotoMetrics counts place photo requests by how they were answered and times the
+// Google calls behind them.
+type PlacePhotoMetrics struct {
+	requests *prometheus.CounterVec
+	upstream prometheus.Histogram
+}
+
+func NewPlacePhotoMetrics(reg prometheus.Registerer) *PlacePhotoMetrics {
+	m := &PlacePhotoMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "place_photo_requests_total",
+				Help: "Place photo requests by result (hit, miss, not_modified, invalid, rate_limited, circuit_open, upstream_error).",
+			},
+			[]string{"result"},
+		),
+		upstream: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "place_photo_upstream_duration_seconds",
+				Help:    "Duration of Google Places photo media calls.",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+	}
+	reg.MustRegister(m.requests, m.upstream)
+	return m
+}
+
+func (m *PlacePhotoMetrics) Observe(result string) {
+	m.requests.WithLabelValues(result).Inc()
+}
+
+func (m *PlacePhotoMetrics) ObserveUpstream(d time.Duration) {
+	m.upstream.Observe(d.Seconds())
+}
@@ -0,0 +1,35 @@
+package utils
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Results recorded by PlacesCacheMetrics.
+const (
+	PlacesCacheHit    = "hit"    // served from the cache
+	PlacesCacheMiss   = "miss"   // fetched from Google
+	PlacesCacheShared = "shared" // waited on a concurrent fetch of the same key
+	PlacesCacheBypass = "bypass" // not cacheable, passed through to Google
+)
+
+// PlacesCacheMetrics counts how Google Places calls were served, so that the quota the cache
+// saves is visible.
+type PlacesCacheMetrics struct {
+	requests *prometheus.CounterVec
+}
+
+func NewPlacesCacheMetrics(reg prometheus.Registerer) *PlacesCacheMetrics {
+	m := &PlacesCacheMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "places_cache_requests_total",
+				Help: "Google Places calls by method and how they were served (hit, miss, shared, bypass).",
+			},
+			[]string{"method", "result"},
+		),
+	}
+	reg.MustRegister(m.requests)
+	return m
+}
+
+func (m *PlacesCacheMetrics) Observe(method, result string) {
+	m.requests.WithLabelValues(method, result).Inc()
+}
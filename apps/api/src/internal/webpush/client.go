@@ -0,0 +1,146 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrSubscriptionGone means the push service no longer knows the subscription
+// (404 or 410) and it should be deleted.
+var ErrSubscriptionGone = errors.New("webpush: subscription expired or unsubscribed")
+
+// ErrForbiddenAddress means a push endpoint resolved to an address the server must not
+// connect to, such as a loopback or private one.
+var ErrForbiddenAddress = errors.New("webpush: endpoint address is not public")
+
+// StatusError is returned for any other non-2xx response from the push service.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webpush: push service returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether retrying later may succeed (rate limiting or a server error).
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Subscription is the part of a browser PushSubscription needed to deliver to it.
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Options control how the push service treats a message.
+type Options struct {
+	// How long the push service should keep the message if the device is offline.
+	TTL time.Duration
+	// "very-low", "low", "normal" or "high"; empty leaves the push service default.
+	Urgency string
+	// Messages with the same topic replace each other while undelivered.
+	Topic string
+}
+
+// Client sends encrypted, VAPID-signed push messages.
+type Client struct {
+	HTTP *http.Client
+	Keys *VAPIDKeys
+}
+
+// NewClient returns a client that only connects to public addresses. Endpoints come from
+// browsers, so the check is made on every connection, after DNS resolution; redirects are
+// not followed and proxies are not used, as either would let the request go elsewhere.
+func NewClient(keys *VAPIDKeys) *Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: refuseNonPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Client{
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Keys: keys,
+	}
+}
+
+// PublicAddress reports whether addr is a public unicast address: not loopback, private,
+// link-local, multicast or unspecified.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// refuseNonPublic is a net.Dialer Control hook that fails connections to non-public addresses.
+func refuseNonPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !PublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// Send delivers payload to sub. It returns ErrSubscriptionGone for 404/410 responses and
+// a *StatusError for other failures reported by the push service.
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := Encrypt(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := c.Keys.AuthorizationHeader(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL.Seconds())))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+	}
+}
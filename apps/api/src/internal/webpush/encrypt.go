@@ -0,0 +1,132 @@
+// Package webpush sends Web Push messages (RFC 8030) with payload encryption
+// (RFC 8291) and VAPID authentication (RFC 8292).
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// recordSize is the aes128gcm record size advertised in the header. Payloads are
+// always sent as a single record, so it only needs to exceed the ciphertext length.
+const recordSize = 4096
+
+// maxPayload is the largest plaintext that fits a single 4096-byte record alongside
+// the header, delimiter and tag, per RFC 8291 section 4.
+const maxPayload = 3993
+
+var ErrPayloadTooLarge = errors.New("webpush: payload too large")
+
+// Encrypt encrypts payload for a subscription with the given p256dh public key and
+// auth secret (both base64url, as provided by PushSubscription.toJSON()).
+func Encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return encrypt(payload, p256dh, auth, asPrivate, salt)
+}
+
+// encrypt is Encrypt with the ephemeral key and salt supplied.
+func encrypt(payload []byte, p256dh, auth string, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > maxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+	uaPublicBytes, err := DecodeBase64(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid p256dh: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid p256dh: %w", err)
+	}
+	authSecret, err := DecodeBase64(auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("webpush: invalid auth secret")
+	}
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublicBytes)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single, final record: the plaintext followed by the 0x02 delimiter.
+	record := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// ValidateSubscriptionKeys checks that p256dh is a P-256 public key and auth a 16-byte
+// secret, so bad subscriptions are rejected when registered rather than on every send.
+func ValidateSubscriptionKeys(p256dh, auth string) error {
+	pub, err := DecodeBase64(p256dh)
+	if err != nil {
+		return errors.New("p256dh is not valid base64url")
+	}
+	if _, err := ecdh.P256().NewPublicKey(pub); err != nil {
+		return errors.New("p256dh is not a P-256 public key")
+	}
+	secret, err := DecodeBase64(auth)
+	if err != nil || len(secret) != 16 {
+		return errors.New("auth must be a 16-byte base64url secret")
+	}
+	return nil
+}
+
+// DecodeBase64 decodes base64url with or without padding, which is how browsers
+// and key generators variously encode Web Push keys.
+func DecodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,115 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"time"
+)
+
+// VAPIDKeys identify this application server to push services.
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+	// PublicKey is the uncompressed P-256 point, base64url encoded. Browsers need it as
+	// applicationServerKey when subscribing.
+	PublicKey string
+	// Subject is a mailto: or https: URL push services can use to contact the operator.
+	Subject string
+}
+
+// LoadVAPIDKeysFromEnv reads VAPID_PRIVATE_KEY (base64url 32-byte scalar, the format
+// printed by common web-push key generators) and VAPID_SUBJECT. It returns nil keys
+// and no error when VAPID_PRIVATE_KEY is unset, meaning Web Push is disabled.
+func LoadVAPIDKeysFromEnv() (*VAPIDKeys, error) {
+	raw := os.Getenv("VAPID_PRIVATE_KEY")
+	if raw == "" {
+		return nil, nil
+	}
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		return nil, errors.New("VAPID_SUBJECT is required when VAPID_PRIVATE_KEY is set")
+	}
+	keys, err := ParseVAPIDPrivateKey(raw, subject)
+	if err != nil {
+		return nil, err
+	}
+	if pub := os.Getenv("VAPID_PUBLIC_KEY"); pub != "" && pub != keys.PublicKey {
+		return nil, errors.New("VAPID_PUBLIC_KEY does not match VAPID_PRIVATE_KEY")
+	}
+	return keys, nil
+}
+
+// ParseVAPIDPrivateKey builds VAPIDKeys from a base64url-encoded P-256 private scalar.
+func ParseVAPIDPrivateKey(privateKey, subject string) (*VAPIDKeys, error) {
+	scalar, err := DecodeBase64(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	pub := ecdhKey.PublicKey().Bytes()
+	x, y := new(big.Int).SetBytes(pub[1:33]), new(big.Int).SetBytes(pub[33:])
+	return &VAPIDKeys{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+			D:         new(big.Int).SetBytes(scalar),
+		},
+		PublicKey: base64.RawURLEncoding.EncodeToString(pub),
+		Subject:   subject,
+	}, nil
+}
+
+// GenerateVAPIDKeys returns a new key pair and the base64url private scalar to store
+// in VAPID_PRIVATE_KEY.
+func GenerateVAPIDKeys(subject string) (*VAPIDKeys, string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(key.Bytes())
+	keys, err := ParseVAPIDPrivateKey(encoded, subject)
+	return keys, encoded, err
+}
+
+// AuthorizationHeader returns the "vapid t=…, k=…" header value for a push to endpoint.
+func (k *VAPIDKeys) AuthorizationHeader(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("webpush: invalid endpoint %q", endpoint)
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		// RFC 8292 allows at most 24 hours.
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": k.Subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS ES256 signatures are the fixed-width concatenation r || s.
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	jwt := signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	return "vapid t=" + jwt + ", k=" + k.PublicKey, nil
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	authv1connect "api/src/generated/auth/v1/v1connect"
+	circlesv1connect "api/src/generated/circles/v1/v1connect"
+	exportsv1connect "api/src/generated/exports/v1/v1connect"
 	googlemapsv1connect "api/src/generated/google_maps/v1/v1connect"
+	notificationsv1connect "api/src/generated/notifications/v1/v1connect"
 	restaurantsv1connect "api/src/generated/restaurants/v1/v1connect"
 	reviewsv1connect "api/src/generated/reviews/v1/v1connect"
+	sharedlistsv1connect "api/src/generated/shared_lists/v1/v1connect"
 	tagsv1connect "api/src/generated/tags/v1/v1connect"
 	usersv1connect "api/src/generated/users/v1/v1connect"
 	wishlistv1connect "api/src/generated/wishlist/v1/v1connect"
 	friendshipv1connect "api/src/generated/friendship/v1/v1connect"
 	"api/src/internal/cache"
+	"api/src/internal/email"
+	"api/src/internal/jobs"
 	"api/src/internal/utils"
+	"api/src/internal/webpush"
 	"api/src/services"
 	"log"
 	"log/slog"
@@ -20,11 +27,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 
+	places "cloud.google.com/go/maps/places/apiv1"
 	"connectrpc.com/connect"
 	"connectrpc.com/grpcreflect"
 	"golang.org/x/oauth2/google"
@@ -49,6 +55,12 @@ func main() {
 	if err := utils.SetupLogging(level); err != nil {
 		log.Fatalf("Failed to setup logging: %v", err)
 	}
+
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// serve runs the API server; it is the default command.
+func serve() {
 	slog.Info("Application starting...")
 
 	googleClientID := os.Getenv("GOOGLE_CLIENT_ID")
@@ -58,15 +70,15 @@ func main() {
 
 	db := mustConnectToDatabase()
 	valkeyClient := mustConnectCache()
-	mux := setupHTTPHandlers(initializeServiceHandlers(db, valkeyClient, googleClientID), db, valkeyClient)
+	vapidKeys := mustLoadVAPIDKeys()
+	placesProvider := mustCreatePlacesProvider(db)
+	cachedPlaces := services.NewCachedPlacesProvider(placesProvider, services.ValkeyPlacesCacheStores(valkeyClient),
+		utils.NewPlacesCacheMetrics(prometheus.DefaultRegisterer))
+	mux := setupHTTPHandlers(initializeServiceHandlers(db, valkeyClient, cachedPlaces, googleClientID, vapidKeys), db, valkeyClient)
 
-	err := utils.CreateSchema(db)
-	if err != nil {
-		slog.Error("Failed to create database schema", slog.Any("error", err))
-		os.Exit(1)
-	}
+	mustMigrate(db)
 
-	err = utils.SeedRequiredData(db)
+	err := utils.SeedRequiredData(db)
 	if err != nil {
 		slog.Error("Failed to seed required data", slog.Any("error", err))
 		os.Exit(1)
@@ -78,6 +90,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	jobRunner := jobs.NewRunner(db, utils.NewJobMetrics(prometheus.DefaultRegisterer), jobs.Config{})
+	emailConfig := services.EmailConfigFromEnv()
+	setupEmailDelivery(mux, db, emailConfig, jobRunner)
+	setupPushDelivery(db, vapidKeys, emailConfig.AppURL, jobRunner)
+	mustRegisterJobs(services.RegisterSessionJobs(jobRunner, valkeyClient))
+	setupRestaurantRefresh(db, placesProvider, jobRunner)
+	go jobRunner.Run(context.Background())
+
 	optionallySetupGRPCReflection(mux)
 	startServer(mux, getAPIPort())
 }
@@ -151,27 +171,24 @@ func mustConnectCache() valkey.Client {
 	return client
 }
 
-func initializeServiceHandlers(db *gorm.DB, valkeyClient valkey.Client, googleClientID string) []ServiceRegistration {
+func initializeServiceHandlers(db *gorm.DB, valkeyClient valkey.Client, placesProvider services.PlacesProvider, googleClientID string, vapidKeys *webpush.VAPIDKeys) []ServiceRegistration {
 	prometheusInterceptor := connectPrometheusInterceptor()
+	placeResolver := services.NewPlaceResolver(placesProvider)
 
 	return []ServiceRegistration{
 		func() ServiceRegistration {
-			svc := services.NewUserService(db)
+			svc := services.NewUserService(db, valkeyClient)
 			path, handler := usersv1connect.NewUsersServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
 		func() ServiceRegistration {
-			svc := services.NewRestaurantsService(db)
+			svc := services.NewRestaurantsService(db, valkeyClient)
 			path, handler := restaurantsv1connect.NewRestaurantsServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
 		func() ServiceRegistration {
-			gapic, err := services.NewGooglePlacesAPIClient()
-			if err != nil {
-				slog.Error("Failed to create Google Places API client", slog.Any("error", err))
-				os.Exit(1)
-			}
-			svc := services.NewGooglePlacesAPIService(gapic)
+			sessions := services.NewAutocompleteSessions(valkeyClient, utils.NewAutocompleteSessionMetrics(prometheus.DefaultRegisterer))
+			svc := services.NewGooglePlacesAPIService(placesProvider, sessions)
 			path, h := googlemapsv1connect.NewGoogleMapsServiceHandler(
 				svc,
 				connect.WithInterceptors(prometheusInterceptor),
@@ -184,7 +201,7 @@ func initializeServiceHandlers(db *gorm.DB, valkeyClient valkey.Client, googleCl
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
 		func() ServiceRegistration {
-			svc := services.NewReviewsService(db, valkeyClient)
+			svc := services.NewReviewsService(db, valkeyClient, placeResolver)
 			path, handler := reviewsv1connect.NewReviewsServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
@@ -194,7 +211,7 @@ func initializeServiceHandlers(db *gorm.DB, valkeyClient valkey.Client, googleCl
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
 		func() ServiceRegistration {
-			svc := services.NewWishlistService(db, valkeyClient)
+			svc := services.NewWishlistService(db, valkeyClient, placeResolver)
 			path, handler := wishlistv1connect.NewWishlistServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
@@ -203,6 +220,26 @@ func initializeServiceHandlers(db *gorm.DB, valkeyClient valkey.Client, googleCl
 			path, handler := friendshipv1connect.NewFriendshipServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
 			return ServiceRegistration{Path: path, Handler: handler}
 		}(),
+		func() ServiceRegistration {
+			svc := services.NewCirclesService(db, valkeyClient)
+			path, handler := circlesv1connect.NewCirclesServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
+			return ServiceRegistration{Path: path, Handler: handler}
+		}(),
+		func() ServiceRegistration {
+			svc := services.NewSharedListsService(db, valkeyClient)
+			path, handler := sharedlistsv1connect.NewSharedListsServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
+			return ServiceRegistration{Path: path, Handler: handler}
+		}(),
+		func() ServiceRegistration {
+			svc := services.NewNotificationsService(db, valkeyClient, vapidKeys)
+			path, handler := notificationsv1connect.NewNotificationsServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
+			return ServiceRegistration{Path: path, Handler: handler}
+		}(),
+		func() ServiceRegistration {
+			svc := services.NewExportService(db, valkeyClient)
+			path, handler := exportsv1connect.NewExportServiceHandler(svc, connect.WithInterceptors(prometheusInterceptor))
+			return ServiceRegistration{Path: path, Handler: handler}
+		}(),
 	}
 }
 
@@ -223,9 +260,12 @@ func setupHTTPHandlers(registrations []ServiceRegistration, db *gorm.DB, kv valk
 		slog.Info("Dev login available", slog.String("path", "/dev/login"))
 	}
 
-	mux.Handle("GET /place-photo", corsMiddleware(placePhotoHandler()))
+	mux.Handle("GET /place-photo", corsMiddleware(mustCreatePlacePhotoService(kv).Handler()))
 	slog.Info("Place photo proxy available", slog.String("path", "/place-photo"))
 
+	mux.Handle("GET /export/places.geojson", corsMiddleware(services.NewExportService(db, kv).GeoJSONHandler()))
+	slog.Info("GeoJSON export available", slog.String("path", "/export/places.geojson"))
+
 	return mux
 }
 
@@ -266,6 +306,87 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// setupEmailDelivery registers the email jobs and the unsubscribe endpoint.
+func setupEmailDelivery(mux *http.ServeMux, db *gorm.DB, cfg services.EmailConfig, runner *jobs.Runner) {
+	transport, err := email.NewTransportFromEnv()
+	if err != nil {
+		slog.Error("Failed to configure email transport", slog.Any("error", err))
+		os.Exit(1)
+	}
+	dispatcher := services.NewEmailDispatcher(db, transport, cfg)
+	mux.Handle("/email/unsubscribe", dispatcher.UnsubscribeHandler())
+	slog.Info("Email unsubscribe available", slog.String("path", "/email/unsubscribe"))
+	mustRegisterJobs(dispatcher.RegisterJobs(runner))
+}
+
+func mustRegisterJobs(err error) {
+	if err != nil {
+		slog.Error("Failed to register background jobs", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+// mustLoadVAPIDKeys returns nil when Web Push is not configured.
+func mustLoadVAPIDKeys() *webpush.VAPIDKeys {
+	keys, err := webpush.LoadVAPIDKeysFromEnv()
+	if err != nil {
+		slog.Error("Failed to load VAPID keys", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return keys
+}
+
+// mustCreatePlacesProvider returns the places backend PLACES_PROVIDER names: "google" (the
+// default) or "osm" for OpenStreetMap.
+func mustCreatePlacesProvider(db *gorm.DB) services.PlacesProvider {
+	switch name := os.Getenv("PLACES_PROVIDER"); name {
+	case "", "google":
+		return services.NewGooglePlacesProvider(mustCreatePlacesClient())
+	case "osm":
+		slog.Info("Using OpenStreetMap for places")
+		return services.NewOSMPlacesProvider(services.OSMConfigFromEnv(), services.NewPlaceLinkStore(db))
+	default:
+		slog.Error("Unknown PLACES_PROVIDER", slog.String("provider", name))
+		os.Exit(1)
+		return nil
+	}
+}
+
+func mustCreatePlacesClient() *places.Client {
+	client, err := services.NewGooglePlacesAPIClient()
+	if err != nil {
+		slog.Error("Failed to create Google Places API client", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return client
+}
+
+// setupRestaurantRefresh registers the job that refreshes restaurants from Google Places,
+// unless PLACES_REFRESH_DAILY_BUDGET is 0.
+func setupRestaurantRefresh(db *gorm.DB, provider services.PlacesProvider, runner *jobs.Runner) {
+	cfg, err := services.RestaurantRefreshConfigFromEnv()
+	if err != nil {
+		slog.Error("Invalid restaurant refresh settings", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if cfg.DailyBudget == 0 {
+		slog.Info("PLACES_REFRESH_DAILY_BUDGET is 0; restaurant refresh is disabled")
+		return
+	}
+	refresher := services.NewRestaurantRefresher(db, provider, cfg)
+	mustRegisterJobs(refresher.RegisterJobs(runner))
+}
+
+// setupPushDelivery registers the Web Push job when VAPID keys are configured.
+func setupPushDelivery(db *gorm.DB, vapidKeys *webpush.VAPIDKeys, appURL string, runner *jobs.Runner) {
+	if vapidKeys == nil {
+		slog.Info("VAPID_PRIVATE_KEY is not set; Web Push notifications are disabled")
+		return
+	}
+	dispatcher := services.NewPushDispatcher(db, webpush.NewClient(vapidKeys), appURL)
+	mustRegisterJobs(dispatcher.RegisterJobs(runner))
+}
+
 func optionallySetupGRPCReflection(mux *http.ServeMux) {
 	if os.Getenv("ENV") == "dev" {
 		slog.Info("gRPC reflection support enabled. We are not in production, right?")
@@ -278,6 +399,10 @@ func optionallySetupGRPCReflection(mux *http.ServeMux) {
 			tagsv1connect.TagsServiceName,
 			wishlistv1connect.WishlistServiceName,
 			friendshipv1connect.FriendshipServiceName,
+			circlesv1connect.CirclesServiceName,
+			sharedlistsv1connect.SharedListsServiceName,
+			notificationsv1connect.NotificationsServiceName,
+			exportsv1connect.ExportServiceName,
 		)
 		mux.Handle(grpcreflect.NewHandlerV1(reflector))
 		mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector))
@@ -316,79 +441,36 @@ func getWebUiPort() string {
 	return webUiPort
 }
 
-func placePhotoHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		name := r.URL.Query().Get("name")
-		if name == "" {
-			http.Error(w, "name is required", http.StatusBadRequest)
-			return
-		}
-		if len(name) < 7 || name[:7] != "places/" {
-			http.Error(w, "invalid name: must start with places/", http.StatusBadRequest)
-			return
-		}
-
-		params := url.Values{}
-		params.Set("maxWidthPx", "800")
-		params.Set("skipHttpRedirect", "true")
-
-		// Prefer API key from env; fall back to ADC bearer token (works with gcloud ADC).
-		apiKey := os.Getenv("GOOGLE_PLACES_API_KEY")
-		if apiKey != "" {
-			params.Set("key", apiKey)
-		}
-		googleURL := "https://places.googleapis.com/v1/" + name + "/media?" + params.Encode()
-
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, googleURL, nil)
-		if err != nil {
-			http.Error(w, "failed to build request", http.StatusInternalServerError)
-			return
-		}
-		if apiKey == "" {
-			// No API key — use ADC (same credentials the gRPC client uses).
-			tok, quotaProject, tokErr := getADCToken(r.Context())
-			if tokErr != nil {
-				slog.Error("place-photo: ADC token failed", slog.Any("error", tokErr))
-				http.Error(w, "auth unavailable", http.StatusInternalServerError)
-				return
-			}
-			req.Header.Set("Authorization", "Bearer "+tok)
-			if quotaProject != "" {
-				req.Header.Set("X-Goog-User-Project", quotaProject)
-			}
-		}
-
-		resp, err := http.DefaultClient.Do(req) //nolint:noctx
-		if err != nil {
-			slog.Error("place-photo: upstream request failed", slog.String("name", name), slog.Any("error", err))
-			http.Error(w, "upstream request failed", http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			slog.Error("place-photo: upstream error",
-				slog.String("name", name),
-				slog.Int("status", resp.StatusCode),
-				slog.String("body", string(body)),
-			)
-			http.Error(w, "upstream error", http.StatusBadGateway)
-			return
-		}
+// mustCreatePlacePhotoService returns the /place-photo handler's service, authenticating
+// with GOOGLE_PLACES_API_KEY or, without one, Application Default Credentials.
+func mustCreatePlacePhotoService(kv valkey.Client) *services.PlacePhotoService {
+	cfg, err := services.PlacePhotoConfigFromEnv()
+	if err != nil {
+		slog.Error("Invalid place photo settings", slog.Any("error", err))
+		os.Exit(1)
+	}
+	metrics := utils.NewPlacePhotoMetrics(prometheus.DefaultRegisterer)
+	return services.NewPlacePhotoService(cfg, services.ValkeyPhotoURIStore(kv), authorizePlacesRequest, metrics)
+}
 
-		// skipHttpRedirect=true returns JSON: {"name":"...","photoUri":"https://..."}
-		// Redirect the browser to the CDN URI so it fetches the image directly.
-		var photoResp struct {
-			PhotoURI string `json:"photoUri"`
-		}
-		if err := json.Unmarshal(body, &photoResp); err != nil || photoResp.PhotoURI == "" {
-			slog.Error("place-photo: failed to parse photoUri", slog.String("body", string(body)), slog.Any("error", err))
-			http.Error(w, "invalid photo response", http.StatusBadGateway)
-			return
-		}
-		http.Redirect(w, r, photoResp.PhotoURI, http.StatusFound)
-	})
+// authorizePlacesRequest adds the API key or an ADC bearer token to a Places API request.
+func authorizePlacesRequest(req *http.Request) error {
+	if apiKey := os.Getenv("GOOGLE_PLACES_API_KEY"); apiKey != "" {
+		q := req.URL.Query()
+		q.Set("key", apiKey)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+	// No API key — use ADC (same credentials the gRPC client uses).
+	tok, quotaProject, err := getADCToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if quotaProject != "" {
+		req.Header.Set("X-Goog-User-Project", quotaProject)
+	}
+	return nil
 }
 
 // getADCToken returns a bearer token and quota project from Application Default Credentials.
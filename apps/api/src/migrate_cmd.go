@@ -0,0 +1,141 @@
+package main
+
+import (
+	"api/src/internal/migrations"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [n]           revert the last n migrations (default 1)
+  status             list migrations and whether they are applied
+  create [-dir d] <name>
+                     write an empty up/down pair numbered after the latest
+`
+
+// mustMigrate applies the embedded migrations on startup. Replicas starting together
+// serialize on the migration advisory lock, so only the first one does any work.
+func mustMigrate(db *gorm.DB) {
+	m := mustNewMigrator(db)
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		slog.Error("Failed to migrate database", slog.Any("error", err))
+		os.Exit(1)
+	}
+	slog.Info("Database schema up to date", slog.Int("applied", len(applied)))
+}
+
+func mustNewMigrator(db *gorm.DB) *migrations.Migrator {
+	sqlDB, err := db.DB()
+	if err != nil {
+		slog.Error("Failed to get database handle", slog.Any("error", err))
+		os.Exit(1)
+	}
+	m, err := migrations.New(sqlDB, migrations.Files())
+	if err != nil {
+		slog.Error("Failed to load migrations", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return m
+}
+
+// runMigrateCommand implements `api migrate ...` and returns the process exit code.
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+		dir := fs.String("dir", "src/internal/migrations/sql", "directory holding the migration files")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+		paths, err := migrations.Create(*dir, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, p := range paths {
+			fmt.Println(p)
+		}
+		return 0
+
+	case "up":
+		applied, err := mustNewMigrator(mustConnectToDatabase()).Up(ctx)
+		for _, mig := range applied {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return 0
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid step count %q\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := mustNewMigrator(mustConnectToDatabase()).Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+
+	case "status":
+		statuses, err := mustNewMigrator(mustConnectToDatabase()).Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if s.Missing {
+				applied += " (file missing)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+		return 0
+
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
+	"gorm.io/gorm"
+)
+
+// ErrUserNotFound is returned by FindUser when no user matches the reference.
+var ErrUserNotFound = errors.New("user not found")
+
+// FindUser resolves an operator-supplied reference: a user ID, an email address or a
+// username.
+func FindUser(ctx context.Context, db *gorm.DB, ref string) (*models.User, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, ErrUserNotFound
+	}
+	var user models.User
+	err := db.WithContext(ctx).
+		Where("id = ? OR LOWER(email) = LOWER(?) OR username = ?", ref, ref, strings.TrimPrefix(ref, "@")).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetUserAdmin grants or revokes a user's admin flag.
+func SetUserAdmin(ctx context.Context, db *gorm.DB, userID string, admin bool) error {
+	res := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("is_admin", admin)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+	return nil
+}
+
+// requireAdmin returns the caller's user ID if they are signed in as an admin.
+func requireAdmin(ctx context.Context, h http.Header, db *gorm.DB, kv valkey.Client) (string, error) {
+	userID, err := getUserIDFromSession(ctx, h, kv)
+	if err != nil {
+		return "", err
+	}
+	var user models.User
+	err = db.WithContext(ctx).Select("id", "is_admin").First(&user, "id = ?", userID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if !user.IsAdmin {
+		return "", connect.NewError(connect.CodePermissionDenied, errors.New(errAdminRequired))
+	}
+	return userID, nil
+}
+
+// FlushCaches deletes every cached value from Valkey, leaving sessions alone, and returns
+// how many keys were removed.
+func FlushCaches(ctx context.Context, kv valkey.Client) (int, error) {
+	removed := 0
+	var cursor uint64
+	for {
+		page, err := kv.Do(ctx, kv.B().Scan().Cursor(cursor).Count(500).Build()).AsScanEntry()
+		if err != nil {
+			return removed, err
+		}
+		var keys []string
+		for _, key := range page.Elements {
+			if !isSessionKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			n, err := kv.Do(ctx, kv.B().Del().Key(keys...).Build()).AsInt64()
+			if err != nil {
+				return removed, err
+			}
+			removed += int(n)
+		}
+		if cursor = page.Cursor; cursor == 0 {
+			return removed, nil
+		}
+	}
+}
+
+func isSessionKey(key string) bool {
+	return strings.HasPrefix(key, "session:") || strings.HasPrefix(key, "user_sessions:")
+}
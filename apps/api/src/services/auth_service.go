@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -93,6 +94,13 @@ func (s *AuthService) Logout(
 			s.Valkey.Do(ctx, s.Valkey.B().Srem().Key("user_sessions:"+userID).Member(token).Build())
 		}
 		s.Valkey.Do(ctx, s.Valkey.B().Del().Key("session:"+token).Build())
+		// This device should stop receiving pushes for the account it signed out of.
+		if s.DB != nil {
+			if err := s.DB.WithContext(ctx).Where("session_hash = ?", hashSessionToken(token)).
+				Delete(&models.PushSubscription{}).Error; err != nil {
+				slog.Warn("Failed to remove push subscriptions on logout", slog.Any("error", err))
+			}
+		}
 	}
 
 	res := connect.NewResponse(&authv1.LogoutResponse{Success: true})
@@ -232,24 +240,11 @@ func (s *AuthService) DeleteMyAccount(
 		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid session data"))
 	}
 
-	// Wipe all sessions for this user
-	if err := s.wipeAllSessions(ctx, userID, token); err != nil {
+	// The current token goes first in case it predates session tracking.
+	if err := s.Valkey.Do(ctx, s.Valkey.B().Del().Key("session:"+token).Build()).Error(); err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
-
-	// Delete the user and dependent rows in a transaction (no DB-level cascade on these FKs).
-	if err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_id = ?", userID).Delete(&models.Review{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("user_id = ?", userID).Delete(&models.WishlistItem{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("sender_id = ? OR receiver_id = ?", userID, userID).Delete(&models.FriendRequest{}).Error; err != nil {
-			return err
-		}
-		return tx.Delete(&models.User{}, "id = ?", userID).Error
-	}); err != nil {
+	if err := DeleteUserAccount(ctx, s.DB, s.Valkey, userID); err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
@@ -276,7 +271,11 @@ func (s *AuthService) SignOutAllDevices(
 		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid session data"))
 	}
 
-	if err := s.wipeAllSessions(ctx, userID, token); err != nil {
+	// The current token goes first in case it predates session tracking.
+	if err := s.Valkey.Do(ctx, s.Valkey.B().Del().Key("session:"+token).Build()).Error(); err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	if err := RevokeUserSessions(ctx, s.DB, s.Valkey, userID); err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
@@ -305,26 +304,102 @@ func (s *AuthService) issueSession(ctx context.Context, userID, token string) er
 	return nil
 }
 
+// RevokeUserSessions signs a user out everywhere: every tracked session is deleted from
+// Valkey and their push subscriptions are removed. db may be nil in tests.
+func RevokeUserSessions(ctx context.Context, db *gorm.DB, kv valkey.Client, userID string) error {
+	if err := wipeAllSessions(ctx, kv, userID); err != nil {
+		return err
+	}
+	if db == nil {
+		return nil
+	}
+	return db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PushSubscription{}).Error
+}
+
+// DeleteUserAccount signs the user out everywhere and deletes them together with every
+// row they own.
+func DeleteUserAccount(ctx context.Context, db *gorm.DB, kv valkey.Client, userID string) error {
+	if err := wipeAllSessions(ctx, kv, userID); err != nil {
+		return err
+	}
+
+	// Delete the user and dependent rows in a transaction (no DB-level cascade on these FKs).
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM review_circles WHERE review_id IN (SELECT id FROM reviews WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM wishlist_item_circles WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_tags WHERE review_id IN (SELECT id FROM reviews WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM wishlist_item_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_personal_tags WHERE review_id IN (SELECT id FROM reviews WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM wishlist_item_personal_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.PersonalTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id IN (SELECT id FROM reviews WHERE user_id = ?)", userID).Delete(&models.ReviewDish{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("member_id = ? OR circle_id IN (SELECT id FROM circles WHERE owner_id = ?)", userID, userID).Delete(&models.CircleMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.Circle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PrivacySettings{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&models.SharedList{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NotificationPreference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.EmailOutbox{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PushOutbox{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PushSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sender_id = ? OR receiver_id = ?", userID, userID).Delete(&models.FriendRequest{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, "id = ?", userID).Error
+	})
+}
+
 // wipeAllSessions deletes every tracked session for the given user from Valkey.
-// currentToken is always deleted regardless of whether the tracking set exists,
-// ensuring the caller's own session is invalidated even for pre-tracking users.
-func (s *AuthService) wipeAllSessions(ctx context.Context, userID, currentToken string) error {
-	smembersResult := s.Valkey.Do(ctx, s.Valkey.B().Smembers().Key("user_sessions:"+userID).Build())
+func wipeAllSessions(ctx context.Context, kv valkey.Client, userID string) error {
+	smembersResult := kv.Do(ctx, kv.B().Smembers().Key("user_sessions:"+userID).Build())
 	tracked, _ := smembersResult.AsStrSlice()
 
-	// Deduplicate: always include the current token.
-	toDelete := make(map[string]struct{}, len(tracked)+1)
-	toDelete[currentToken] = struct{}{}
 	for _, t := range tracked {
-		toDelete[t] = struct{}{}
-	}
-
-	for t := range toDelete {
-		if err := s.Valkey.Do(ctx, s.Valkey.B().Del().Key("session:"+t).Build()).Error(); err != nil {
+		if err := kv.Do(ctx, kv.B().Del().Key("session:"+t).Build()).Error(); err != nil {
 			return fmt.Errorf("wipeAllSessions: DEL session:%s: %w", t, err)
 		}
 	}
-	if err := s.Valkey.Do(ctx, s.Valkey.B().Del().Key("user_sessions:"+userID).Build()).Error(); err != nil {
+	if err := kv.Do(ctx, kv.B().Del().Key("user_sessions:"+userID).Build()).Error(); err != nil {
 		return fmt.Errorf("wipeAllSessions: DEL user_sessions:%s: %w", userID, err)
 	}
 	return nil
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"api/src/internal/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	// Google bills an autocomplete session as one when a details call ends it within a few
+	// minutes of its first request; later, each request is billed on its own.
+	autocompleteSessionTTL = 3 * time.Minute
+	// Session records outlive their sessions so that expired ones are counted as abandoned.
+	autocompleteSessionRecordTTL = time.Hour
+)
+
+// AutocompleteSession is the open Places autocomplete session of one login session.
+type AutocompleteSession struct {
+	Token     string
+	StartedAt time.Time
+}
+
+// AutocompleteSessionStore keeps one open session per owner. ValkeyAutocompleteSessionStore
+// keeps them in Valkey.
+type AutocompleteSessionStore interface {
+	Get(ctx context.Context, owner string) (AutocompleteSession, bool, error)
+	Put(ctx context.Context, owner string, s AutocompleteSession) error
+	// Delete removes owner's session, reporting whether there was one, so that concurrent
+	// callers end it once.
+	Delete(ctx context.Context, owner string) (bool, error)
+}
+
+type valkeyAutocompleteSessions struct {
+	kv valkey.Client
+}
+
+func ValkeyAutocompleteSessionStore(kv valkey.Client) AutocompleteSessionStore {
+	return valkeyAutocompleteSessions{kv: kv}
+}
+
+func autocompleteSessionKey(owner string) string {
+	return "places_autocomplete:" + owner
+}
+
+func (s valkeyAutocompleteSessions) Get(ctx context.Context, owner string) (AutocompleteSession, bool, error) {
+	raw, err := s.kv.Do(ctx, s.kv.B().Get().Key(autocompleteSessionKey(owner)).Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		return AutocompleteSession{}, false, nil
+	}
+	if err != nil {
+		return AutocompleteSession{}, false, err
+	}
+	token, started, _ := strings.Cut(raw, " ")
+	millis, err := strconv.ParseInt(started, 10, 64)
+	if err != nil || token == "" {
+		return AutocompleteSession{}, false, fmt.Errorf("invalid autocomplete session %q", raw)
+	}
+	return AutocompleteSession{Token: token, StartedAt: time.UnixMilli(millis)}, true, nil
+}
+
+func (s valkeyAutocompleteSessions) Put(ctx context.Context, owner string, session AutocompleteSession) error {
+	value := session.Token + " " + strconv.FormatInt(session.StartedAt.UnixMilli(), 10)
+	return s.kv.Do(ctx, s.kv.B().Set().Key(autocompleteSessionKey(owner)).Value(value).
+		Ex(autocompleteSessionRecordTTL).Build()).Error()
+}
+
+func (s valkeyAutocompleteSessions) Delete(ctx context.Context, owner string) (bool, error) {
+	n, err := s.kv.Do(ctx, s.kv.B().Del().Key(autocompleteSessionKey(owner)).Build()).AsInt64()
+	return n > 0, err
+}
+
+// AutocompleteSessions issues the session tokens that group autocomplete requests and the
+// details call ending them for Google billing. Each signed-in login session has at most
+// one open session; clients echo its token and cannot choose their own. Without a
+// signed-in session, no token is sent.
+type AutocompleteSessions struct {
+	Store   AutocompleteSessionStore
+	Metrics *utils.AutocompleteSessionMetrics
+	// Owner returns the key sessions of a request are kept under, or "" when it has none.
+	Owner func(ctx context.Context, h http.Header) string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewAutocompleteSessions returns sessions kept in kv and owned by login sessions.
+func NewAutocompleteSessions(kv valkey.Client, metrics *utils.AutocompleteSessionMetrics) *AutocompleteSessions {
+	return &AutocompleteSessions{
+		Store:   ValkeyAutocompleteSessionStore(kv),
+		Metrics: metrics,
+		Owner: func(ctx context.Context, h http.Header) string {
+			if _, err := getUserIDFromSession(ctx, h, kv); err != nil {
+				return ""
+			}
+			return hashSessionToken(sessionToken(h))
+		},
+	}
+}
+
+// Continue returns the session token for an autocomplete request that echoed token: the
+// open session's while token names it and it is live, otherwise a new session's.
+func (a *AutocompleteSessions) Continue(ctx context.Context, h http.Header, token string) string {
+	if a == nil {
+		return ""
+	}
+	owner := a.Owner(ctx, h)
+	if owner == "" {
+		return ""
+	}
+	now := a.now()
+	open, ok, err := a.Store.Get(ctx, owner)
+	if err != nil {
+		slog.Warn("Failed to read autocomplete session", slog.Any("error", err))
+		return ""
+	}
+	if ok && open.Token == token && sessionLive(open, now) {
+		return open.Token
+	}
+	if ok {
+		a.Metrics.Observe(utils.AutocompleteSessionAbandoned)
+	}
+
+	next := AutocompleteSession{Token: uuid.NewString(), StartedAt: now}
+	if err := a.Store.Put(ctx, owner, next); err != nil {
+		slog.Warn("Failed to start autocomplete session", slog.Any("error", err))
+		return ""
+	}
+	a.Metrics.Observe(utils.AutocompleteSessionStarted)
+	return next.Token
+}
+
+// Complete claims the session token names for the details call that ends it and returns
+// its token, or "" when token does not name a live session of the caller. The caller
+// reports how that call went with Ended.
+func (a *AutocompleteSessions) Complete(ctx context.Context, h http.Header, token string) string {
+	if a == nil || token == "" {
+		return ""
+	}
+	owner := a.Owner(ctx, h)
+	if owner == "" {
+		return ""
+	}
+	open, ok, err := a.Store.Get(ctx, owner)
+	if err != nil {
+		slog.Warn("Failed to read autocomplete session", slog.Any("error", err))
+		return ""
+	}
+	if !ok || open.Token != token {
+		return ""
+	}
+	deleted, err := a.Store.Delete(ctx, owner)
+	if err != nil || !deleted {
+		return ""
+	}
+	if !sessionLive(open, a.now()) {
+		a.Metrics.Observe(utils.AutocompleteSessionAbandoned)
+		return ""
+	}
+	return open.Token
+}
+
+// Ended records the outcome of the details call a token from Complete was sent with: the
+// session is completed only if Google answered it.
+func (a *AutocompleteSessions) Ended(token string, err error) {
+	if a == nil || token == "" {
+		return
+	}
+	if err != nil {
+		a.Metrics.Observe(utils.AutocompleteSessionAbandoned)
+		return
+	}
+	a.Metrics.Observe(utils.AutocompleteSessionCompleted)
+}
+
+func sessionLive(s AutocompleteSession, now time.Time) bool {
+	return now.Sub(s.StartedAt) < autocompleteSessionTTL
+}
+
+func (a *AutocompleteSessions) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
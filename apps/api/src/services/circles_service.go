@@ -0,0 +1,410 @@
+package services
+
+import (
+	circlesv1 "api/src/generated/circles/v1"
+	"api/src/generated/circles/v1/v1connect"
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
+	"gorm.io/gorm"
+)
+
+const (
+	circleOwnerFilter = "id = ? AND owner_id = ?"
+	maxCircleNameLen  = 50
+)
+
+type CirclesService struct {
+	v1connect.UnimplementedCirclesServiceHandler
+	DB     *gorm.DB
+	Valkey valkey.Client
+}
+
+func NewCirclesService(db *gorm.DB, kv valkey.Client) *CirclesService {
+	return &CirclesService{DB: db, Valkey: kv}
+}
+
+func (s *CirclesService) CreateCircle(
+	ctx context.Context,
+	req *connect.Request[circlesv1.CreateCircleRequest],
+) (*connect.Response[circlesv1.CreateCircleResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := validateCircleName(req.Msg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs := uniqueStrings(req.Msg.MemberIds)
+	if err := assertAllFriends(ctx, s.DB, userID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	circle := models.Circle{OwnerID: userID, Name: name}
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Circle{}).Where("owner_id = ? AND name = ?", userID, name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return connect.NewError(connect.CodeAlreadyExists, errors.New("a circle with this name already exists"))
+		}
+		if err := tx.Create(&circle).Error; err != nil {
+			return err
+		}
+		return addCircleMembers(tx, circle.ID, memberIDs)
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+
+	loaded, err := loadOwnedCircle(ctx, s.DB, circle.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&circlesv1.CreateCircleResponse{Circle: loaded.ToProto()}), nil
+}
+
+func (s *CirclesService) UpdateCircle(
+	ctx context.Context,
+	req *connect.Request[circlesv1.UpdateCircleRequest],
+) (*connect.Response[circlesv1.UpdateCircleResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
+	}
+	name, err := validateCircleName(req.Msg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	circle, err := loadOwnedCircle(ctx, s.DB, req.Msg.Id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.DB.WithContext(ctx).Model(&models.Circle{}).
+		Where("owner_id = ? AND name = ? AND id != ?", userID, name, circle.ID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New("a circle with this name already exists"))
+	}
+
+	if err := s.DB.WithContext(ctx).Model(circle).Update("name", name).Error; err != nil {
+		return nil, err
+	}
+	circle.Name = name
+
+	return connect.NewResponse(&circlesv1.UpdateCircleResponse{Circle: circle.ToProto()}), nil
+}
+
+// DeleteCircle removes a circle, its memberships and every grant made to it.
+// Content shared only with the deleted circle becomes visible to nobody but its
+// author until the author picks a new audience.
+func (s *CirclesService) DeleteCircle(
+	ctx context.Context,
+	req *connect.Request[circlesv1.DeleteCircleRequest],
+) (*connect.Response[circlesv1.DeleteCircleResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
+	}
+
+	// Child rows go first: their foreign keys to circles do not cascade.
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&models.Circle{}).Select("id").Where(circleOwnerFilter, req.Msg.Id, userID)
+		if err := tx.Where("circle_id IN (?)", owned).Delete(&models.CircleMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_circles WHERE circle_id IN (?)", owned).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM wishlist_item_circles WHERE circle_id IN (?)", owned).Error; err != nil {
+			return err
+		}
+		result := tx.Where(circleOwnerFilter, req.Msg.Id, userID).Delete(&models.Circle{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return connect.NewError(connect.CodeNotFound, errors.New(errCircleNotFound))
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+
+	return connect.NewResponse(&circlesv1.DeleteCircleResponse{Success: true}), nil
+}
+
+func (s *CirclesService) ListCircles(
+	ctx context.Context,
+	req *connect.Request[circlesv1.ListCirclesRequest],
+) (*connect.Response[circlesv1.ListCirclesResponse], error) {
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	var circles []models.Circle
+	if err := s.DB.WithContext(ctx).Preload("Members").
+		Where("owner_id = ?", userID).
+		Order("name ASC").
+		Find(&circles).Error; err != nil {
+		return nil, err
+	}
+
+	protos := make([]*circlesv1.CircleProto, len(circles))
+	for i, c := range circles {
+		protos[i] = c.ToProto()
+	}
+
+	return connect.NewResponse(&circlesv1.ListCirclesResponse{Circles: protos}), nil
+}
+
+func (s *CirclesService) AddCircleMembers(
+	ctx context.Context,
+	req *connect.Request[circlesv1.AddCircleMembersRequest],
+) (*connect.Response[circlesv1.AddCircleMembersResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.CircleId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errCircleIDRequired))
+	}
+	memberIDs := uniqueStrings(req.Msg.UserIds)
+	if len(memberIDs) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errUserIDsRequired))
+	}
+
+	circle, err := loadOwnedCircle(ctx, s.DB, req.Msg.CircleId, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := assertAllFriends(ctx, s.DB, userID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	if err := addCircleMembers(s.DB.WithContext(ctx), circle.ID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	circle, err = loadOwnedCircle(ctx, s.DB, circle.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&circlesv1.AddCircleMembersResponse{Circle: circle.ToProto()}), nil
+}
+
+func (s *CirclesService) RemoveCircleMembers(
+	ctx context.Context,
+	req *connect.Request[circlesv1.RemoveCircleMembersRequest],
+) (*connect.Response[circlesv1.RemoveCircleMembersResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.CircleId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errCircleIDRequired))
+	}
+	memberIDs := uniqueStrings(req.Msg.UserIds)
+	if len(memberIDs) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errUserIDsRequired))
+	}
+
+	circle, err := loadOwnedCircle(ctx, s.DB, req.Msg.CircleId, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.WithContext(ctx).
+		Where("circle_id = ? AND member_id IN ?", circle.ID, memberIDs).
+		Delete(&models.CircleMember{}).Error; err != nil {
+		return nil, err
+	}
+
+	circle, err = loadOwnedCircle(ctx, s.DB, circle.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&circlesv1.RemoveCircleMembersResponse{Circle: circle.ToProto()}), nil
+}
+
+// validateCircleName trims the name and checks it is non-empty and reasonably short.
+func validateCircleName(raw string) (string, error) {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return "", connect.NewError(connect.CodeInvalidArgument, errors.New("name is required"))
+	}
+	if len([]rune(name)) > maxCircleNameLen {
+		return "", connect.NewError(connect.CodeInvalidArgument, errors.New("name must be at most 50 characters"))
+	}
+	return name, nil
+}
+
+// loadOwnedCircle fetches a circle with its members, scoped to the owner.
+func loadOwnedCircle(ctx context.Context, db *gorm.DB, circleID, ownerID string) (*models.Circle, error) {
+	var circle models.Circle
+	if err := db.WithContext(ctx).Preload("Members").First(&circle, circleOwnerFilter, circleID, ownerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New(errCircleNotFound))
+		}
+		return nil, err
+	}
+	return &circle, nil
+}
+
+// addCircleMembers inserts memberships, skipping users who are already members.
+func addCircleMembers(tx *gorm.DB, circleID string, memberIDs []string) error {
+	for _, memberID := range memberIDs {
+		member := models.CircleMember{CircleID: circleID, MemberID: memberID}
+		if err := tx.Where("circle_id = ? AND member_id = ?", circleID, memberID).
+			FirstOrCreate(&member).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assertAllFriends returns CodeFailedPrecondition unless every ID is a confirmed friend of ownerID.
+func assertAllFriends(ctx context.Context, db *gorm.DB, ownerID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	friendIDs, err := getFriendIDs(ctx, db, ownerID)
+	if err != nil {
+		return err
+	}
+	friends := make(map[string]struct{}, len(friendIDs))
+	for _, id := range friendIDs {
+		friends[id] = struct{}{}
+	}
+	for _, id := range userIDs {
+		if _, ok := friends[id]; !ok {
+			return connect.NewError(connect.CodeFailedPrecondition, errors.New("circle members must be your friends"))
+		}
+	}
+	return nil
+}
+
+// resolveAudience validates a visibility/circle_ids pair for content owned by ownerID
+// and returns the stored visibility plus the circles it is shared with. An unspecified
+// visibility falls back to the owner's default from their privacy settings.
+func resolveAudience(ctx context.Context, db *gorm.DB, ownerID string, visibility circlesv1.Visibility, ids []string) (string, []models.Circle, error) {
+	if visibility == circlesv1.Visibility_VISIBILITY_UNSPECIFIED {
+		settings, err := privacySettingsFor(ctx, db, ownerID)
+		if err != nil {
+			return "", nil, err
+		}
+		visibility = settings.DefaultVisibilityProto()
+	}
+	stored := models.VisibilityFromProto(visibility)
+	circleIDs := uniqueStrings(ids)
+	if stored != models.VisibilityCircles {
+		if len(circleIDs) > 0 {
+			return "", nil, connect.NewError(connect.CodeInvalidArgument, errors.New("circle_ids are only allowed with VISIBILITY_CIRCLES"))
+		}
+		return stored, []models.Circle{}, nil
+	}
+	if len(circleIDs) == 0 {
+		return "", nil, connect.NewError(connect.CodeInvalidArgument, errors.New("circle_ids are required with VISIBILITY_CIRCLES"))
+	}
+
+	var circles []models.Circle
+	if err := db.WithContext(ctx).Where("owner_id = ? AND id IN ?", ownerID, circleIDs).Find(&circles).Error; err != nil {
+		return "", nil, err
+	}
+	if len(circles) != len(circleIDs) {
+		return "", nil, connect.NewError(connect.CodeNotFound, errors.New(errCircleNotFound))
+	}
+	return stored, circles, nil
+}
+
+// visibilityCondition returns a SQL condition (with one viewer ID placeholder) that is true
+// for rows of table a friend may read: friends-visible rows, and circle-visible rows shared
+// with a circle the viewer belongs to. Private rows never match.
+func visibilityCondition(table, circlesJoinTable, joinColumn string) string {
+	return "(" + table + ".visibility = '" + models.VisibilityFriends + "' OR (" +
+		table + ".visibility = '" + models.VisibilityCircles + "' AND EXISTS (" +
+		"SELECT 1 FROM " + circlesJoinTable + " jc JOIN circle_members cm ON cm.circle_id = jc.circle_id " +
+		"WHERE jc." + joinColumn + " = " + table + ".id AND cm.member_id = ?)))"
+}
+
+// applyReviewVisibility restricts a reviews query to rows viewerID may read as a friend.
+func applyReviewVisibility(query *gorm.DB, viewerID string) *gorm.DB {
+	return query.Where(visibilityCondition("reviews", "review_circles", "review_id"), viewerID)
+}
+
+// applyOwnOrSharedReviews restricts a reviews query to viewerID's own reviews and the reviews
+// of friendIDs that are shared with viewerID.
+func applyOwnOrSharedReviews(query *gorm.DB, viewerID string, friendIDs []string) *gorm.DB {
+	return query.Where(
+		"reviews.user_id = ? OR (reviews.user_id IN ? AND "+visibilityCondition("reviews", "review_circles", "review_id")+")",
+		viewerID, friendIDs, viewerID,
+	)
+}
+
+// applyWishlistVisibility restricts a wishlist_items query to rows viewerID may read as a friend.
+func applyWishlistVisibility(query *gorm.DB, viewerID string) *gorm.DB {
+	return query.Where(visibilityCondition("wishlist_items", "wishlist_item_circles", "wishlist_item_id"), viewerID)
+}
+
+// applyOwnOrSharedWishlist is applyOwnOrSharedReviews for wishlist_items.
+func applyOwnOrSharedWishlist(query *gorm.DB, viewerID string, friendIDs []string) *gorm.DB {
+	return query.Where(
+		"wishlist_items.user_id = ? OR (wishlist_items.user_id IN ? AND "+visibilityCondition("wishlist_items", "wishlist_item_circles", "wishlist_item_id")+")",
+		viewerID, friendIDs, viewerID,
+	)
+}
+
+// removeMutualCircleMemberships drops a and b from each other's circles, used when a friendship ends.
+func removeMutualCircleMemberships(tx *gorm.DB, a, b string) error {
+	return tx.Where(
+		"(member_id = ? AND circle_id IN (SELECT id FROM circles WHERE owner_id = ?)) OR "+
+			"(member_id = ? AND circle_id IN (SELECT id FROM circles WHERE owner_id = ?))",
+		a, b, b, a,
+	).Delete(&models.CircleMember{}).Error
+}
+
+// uniqueStrings returns the non-empty values of in with duplicates removed, preserving order.
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v == "" {
+			continue
+		}
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
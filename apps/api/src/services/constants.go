@@ -2,18 +2,30 @@ package services
 
 import "api/src/internal/models"
 
-// missingRestaurantFields returns a map of field updates for a restaurant that is missing
-// city, country, or photo_reference but the caller has values. Used to backfill old records.
-func missingRestaurantFields(r *models.Restaurant, city, country, photoRef string) map[string]any {
+// missingRestaurantFields returns the column updates that fill in what r lacks from d: city,
+// country, photo_reference, business_status, coordinates or refreshed_at. Used to backfill
+// old records.
+func missingRestaurantFields(r, d *models.Restaurant) map[string]any {
 	updates := map[string]any{}
-	if r.City == "" && city != "" {
-		updates["city"] = city
+	if r.City == "" && d.City != "" {
+		updates["city"] = d.City
 	}
-	if r.Country == "" && country != "" {
-		updates["country"] = country
+	if r.Country == "" && d.Country != "" {
+		updates["country"] = d.Country
 	}
-	if r.PhotoReference == "" && photoRef != "" {
-		updates["photo_reference"] = photoRef
+	if r.PhotoReference == "" && d.PhotoReference != "" {
+		updates["photo_reference"] = d.PhotoReference
+	}
+	if r.BusinessStatus == "" && d.BusinessStatus != "" {
+		updates["business_status"] = d.BusinessStatus
+	}
+	if !r.HasLocation() && d.HasLocation() {
+		updates["latitude"] = *d.Latitude
+		updates["longitude"] = *d.Longitude
+		updates["geohash"] = d.Geohash
+	}
+	if r.RefreshedAt == nil && d.RefreshedAt != nil {
+		updates["refreshed_at"] = *d.RefreshedAt
 	}
 	return updates
 }
@@ -29,4 +41,51 @@ const (
 	errFriendUserIDRequired   = "friend_user_id is required"
 	errUsernameRequired       = "username is required"
 	errInvalidUsername        = "invalid username"
+	errCircleNotFound         = "circle not found"
+	errCircleIDRequired       = "circle_id is required"
+	errUserIDsRequired        = "user_ids is required"
+	errSharedListNotFound     = "shared list not found"
+
+	errInvalidDefaultVisibility   = "default_visibility must be private or friends"
+	errInvalidFriendRequestPolicy = "friend_request_policy must be specified"
+	errFriendRequestsNotAccepted  = "this user is not accepting friend requests"
+	errInvalidNotificationType    = "invalid notification type"
+	errPushNotConfigured          = "push notifications are not configured on this server"
+	errPushEndpointRequired       = "endpoint is required"
+	errAdminRequired              = "admin privileges required"
+	errTagNotFound                = "tag not found"
+	errInvalidTagSlug             = "slug must be lowercase letters and digits separated by single dashes"
+	errTagLabelRequired           = "label is required"
+	errTagCategoryRequired        = "category is required"
+	errTagExists                  = "a tag with this slug already exists"
+	errMergeSourcesRequired       = "source_slugs is required"
+	errMergeTargetIsSource        = "target_slug cannot also be a source"
+	errInvalidTagLocale           = "translations must be keyed by a language code other than en"
+	errUnknownTag                 = "unknown tag"
+	errPersonalTagLabelRequired   = "label must contain a letter or digit"
+	errPersonalTagExists          = "you already have a personal tag with this slug"
+	errPersonalTagIsGlobal        = "a global tag with this slug already exists"
+	errPersonalTagNotFound        = "personal tag not found"
+	errScoresRequired             = "rating_from_scores requires at least one score"
+	errInvalidScoreDimension      = "unknown score dimension"
+	errDishNameRequired           = "dish name is required"
+	errDishQueryRequired          = "query is required"
+	errInvalidCoordinates         = "latitude and longitude must be given together, within ±90 and ±180"
+	errInvalidBounds              = "bounds must have south <= north and valid coordinates"
+	errLocationRequired           = "latitude and longitude are required without bounds"
+	errInvalidGooglePlacesID      = "google_places_id must be a place resource name (places/...)"
+	errSearchLocationConflict     = "location_bias and location_restriction cannot both be set"
+	errSearchLocationEmpty        = "location_bias and location_restriction need a circle or a rectangle"
+	errSearchRestrictionCircle    = "location_restriction must be a rectangle; use location_bias for a circle"
+	errSearchInvalidCircle        = "circle needs a valid center and a radius above 0 and at most 50000 meters"
+	errSearchInvalidRectangle     = "rectangle needs valid low and high corners with low.lat <= high.lat"
+	errSearchInvalidMinRating     = "min_rating must be between 0 and 5"
+	errSearchInvalidPriceLevel    = "price_levels must only hold specified price levels"
+	errSearchInvalidMaxResults    = "max_result_count must be between 0 and 20"
+	errSearchInvalidRank          = "unknown rank_preference"
+	errSearchStrictWithoutType    = "strict_type_filtering requires included_type"
+	errSearchDistanceNoLocation   = "rank_preference DISTANCE requires location_bias or location_restriction"
+	errSearchUnsupportedOptions   = "ev_options, routing_parameters and search_along_route_parameters are not supported"
+	errUnknownGooglePlace         = "google_places_id does not name a known place"
+	errPlaceLookupsNotConfigured  = "restaurant lookups are not configured"
 )
@@ -0,0 +1,252 @@
+package services
+
+import (
+	v1 "api/src/generated/reviews/v1"
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"connectrpc.com/connect"
+	"gorm.io/gorm"
+)
+
+const (
+	maxDishesPerReview = 30
+	maxDishNameLength  = 100
+	// SearchDishes summarises at most this many of the newest matching reviews.
+	searchDishesReviewLimit = 500
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ContainsPattern returns a LIKE pattern, for use with ESCAPE '\', matching values that
+// contain s literally.
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+func (s *ReviewsService) ListRestaurantDishes(
+	ctx context.Context,
+	req *connect.Request[v1.ListRestaurantDishesRequest],
+) (*connect.Response[v1.ListRestaurantDishesResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.GooglePlacesId == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errGooglePlacesIDRequired))
+	}
+
+	friendIDs, err := getFriendIDs(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	var reviews []models.Review
+	if err := applyOwnOrSharedReviews(s.DB.WithContext(ctx), userID, friendIDs).
+		Preload("User").
+		Preload("Dishes.Dish").
+		Where("reviews.google_places_id = ?", req.Msg.GooglePlacesId).
+		Order("reviews.created_at DESC").
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.ListRestaurantDishesResponse{Dishes: SummarizeDishes(reviews, nil)}), nil
+}
+
+func (s *ReviewsService) SearchDishes(
+	ctx context.Context,
+	req *connect.Request[v1.SearchDishesRequest],
+) (*connect.Response[v1.SearchDishesResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	query := models.NormalizeDishName(req.Msg.Query)
+	if query == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errDishQueryRequired))
+	}
+	limit := int(req.Msg.Limit)
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	friendIDs, err := getFriendIDs(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	var reviews []models.Review
+	if err := applyOwnOrSharedReviews(s.DB.WithContext(ctx), userID, friendIDs).
+		Preload("User").
+		Preload("Restaurant").
+		Preload("Dishes.Dish").
+		Where("EXISTS (SELECT 1 FROM review_dishes rd JOIN dishes d ON d.id = rd.dish_id "+
+			"WHERE rd.review_id = reviews.id AND d.normalized_name LIKE ? ESCAPE '\\')", ContainsPattern(query)).
+		Order("reviews.created_at DESC").
+		Limit(searchDishesReviewLimit).
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	// Dishes belong to one restaurant, so each summary maps to a place.
+	restaurants := map[string]models.Restaurant{}
+	for _, r := range reviews {
+		for _, d := range r.Dishes {
+			restaurants[d.DishID] = r.Restaurant
+		}
+	}
+	summaries := SummarizeDishes(reviews, func(d models.Dish) bool {
+		return strings.Contains(d.NormalizedName, query)
+	})
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	results := make([]*v1.DishSearchResult, len(summaries))
+	for i, summary := range summaries {
+		restaurant := restaurants[summary.DishId]
+		results[i] = &v1.DishSearchResult{
+			Dish:           summary,
+			GooglePlacesId: restaurant.GoogleID,
+			RestaurantName: restaurant.Name,
+			RestaurantCity: restaurant.City,
+		}
+	}
+	return connect.NewResponse(&v1.SearchDishesResponse{Results: results}), nil
+}
+
+// SummarizeDishes aggregates the dishes on reviews (with User and Dishes.Dish preloaded,
+// newest first), keeping only those match accepts when it is set. The most ordered dishes
+// come first, then the best rated.
+func SummarizeDishes(reviews []models.Review, match func(models.Dish) bool) []*v1.DishSummary {
+	type tally struct {
+		summary             *v1.DishSummary
+		ratingSum, priceSum float64
+		ratings, prices     int
+		seenAuthors         map[string]bool
+	}
+	byDish := map[string]*tally{}
+	var order []string
+	for _, r := range reviews {
+		for _, d := range r.Dishes {
+			if match != nil && !match(d.Dish) {
+				continue
+			}
+			t := byDish[d.DishID]
+			if t == nil {
+				t = &tally{summary: &v1.DishSummary{DishId: d.DishID, Name: d.Dish.Name}, seenAuthors: map[string]bool{}}
+				byDish[d.DishID] = t
+				order = append(order, d.DishID)
+			}
+			t.summary.OrderCount++
+			if d.Rating > 0 {
+				t.ratingSum += d.Rating
+				t.ratings++
+			}
+			if d.Price > 0 {
+				t.priceSum += float64(d.Price)
+				t.prices++
+			}
+			if d.WouldOrderAgain != nil && *d.WouldOrderAgain {
+				t.summary.WouldOrderAgainCount++
+			}
+			if !t.seenAuthors[r.UserID] {
+				t.seenAuthors[r.UserID] = true
+				t.summary.OrderedBy = append(t.summary.OrderedBy, r.User.Name)
+			}
+		}
+	}
+
+	summaries := make([]*v1.DishSummary, len(order))
+	for i, id := range order {
+		t := byDish[id]
+		if t.ratings > 0 {
+			t.summary.AverageRating = t.ratingSum / float64(t.ratings)
+		}
+		if t.prices > 0 {
+			t.summary.AveragePrice = int32(math.Round(t.priceSum / float64(t.prices)))
+		}
+		summaries[i] = t.summary
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.OrderCount != b.OrderCount {
+			return a.OrderCount > b.OrderCount
+		}
+		if a.AverageRating != b.AverageRating {
+			return a.AverageRating > b.AverageRating
+		}
+		return a.Name < b.Name
+	})
+	return summaries
+}
+
+// validateDishes checks the dishes of a review: named, rated 1–5 or not at all, no negative
+// price, and no dish twice.
+func validateDishes(dishes []*v1.ReviewDishInput) error {
+	if len(dishes) > maxDishesPerReview {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("a review can list at most %d dishes", maxDishesPerReview))
+	}
+	seen := make(map[string]bool, len(dishes))
+	for _, d := range dishes {
+		name := models.CleanDishName(d.Name)
+		if name == "" {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New(errDishNameRequired))
+		}
+		if len([]rune(name)) > maxDishNameLength {
+			return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("dish names are limited to %d characters", maxDishNameLength))
+		}
+		if d.Rating != 0 && (d.Rating < 1 || d.Rating > 5) {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New("dish rating must be between 1 and 5"))
+		}
+		if d.Price < 0 {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New("dish price cannot be negative"))
+		}
+		key := models.NormalizeDishName(name)
+		if seen[key] {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New("each dish can only be listed once"))
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// saveReviewDishes replaces the dishes of a review, adding new dishes to the restaurant as
+// needed, and returns the stored entries with Dish set.
+func saveReviewDishes(tx *gorm.DB, reviewID, restaurantID string, inputs []*v1.ReviewDishInput) ([]models.ReviewDish, error) {
+	if err := tx.Where("review_id = ?", reviewID).Delete(&models.ReviewDish{}).Error; err != nil {
+		return nil, err
+	}
+	dishes := make([]models.ReviewDish, len(inputs))
+	for i, in := range inputs {
+		name := models.CleanDishName(in.Name)
+		var dish models.Dish
+		if err := tx.Where(models.Dish{RestaurantID: restaurantID, NormalizedName: models.NormalizeDishName(name)}).
+			Attrs(models.Dish{Name: name}).
+			FirstOrCreate(&dish).Error; err != nil {
+			return nil, err
+		}
+		dishes[i] = models.ReviewDish{
+			ReviewID:        reviewID,
+			DishID:          dish.ID,
+			Dish:            dish,
+			Position:        int32(i),
+			Rating:          in.Rating,
+			Price:           in.Price,
+			WouldOrderAgain: in.WouldOrderAgain,
+		}
+	}
+	if len(dishes) == 0 {
+		return dishes, nil
+	}
+	if err := tx.Omit("Dish").Create(&dishes).Error; err != nil {
+		return nil, err
+	}
+	return dishes, nil
+}
@@ -0,0 +1,491 @@
+package services
+
+import (
+	"api/src/internal/email"
+	"api/src/internal/jobs"
+	"api/src/internal/models"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Keys of EmailOutbox.Params.
+const (
+	emailParamActorID     = "actor_id"
+	emailParamSubjectID   = "subject_id"
+	emailParamSubjectName = "subject_name"
+	emailParamSince       = "since"
+	emailParamUntil       = "until"
+)
+
+const (
+	emailBatchSize       = 20
+	emailMaxAttempts     = 8
+	emailSendLease       = 10 * time.Minute
+	emailUnsubscribePath = "/email/unsubscribe"
+)
+
+// EmailConfig holds the settings needed to render and address emails.
+type EmailConfig struct {
+	From string
+	// Base URL of the web app, used for call-to-action links.
+	AppURL string
+	// Public base URL of this API, used for unsubscribe links.
+	APIURL            string
+	UnsubscribeSecret []byte
+}
+
+// EmailConfigFromEnv reads EMAIL_FROM, APP_BASE_URL, API_BASE_URL and
+// EMAIL_UNSUBSCRIBE_SECRET. Without a secret a random one is generated, which
+// invalidates unsubscribe links on restart; that is only acceptable in development.
+func EmailConfigFromEnv() EmailConfig {
+	cfg := EmailConfig{
+		From:              os.Getenv("EMAIL_FROM"),
+		AppURL:            strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"),
+		APIURL:            strings.TrimRight(os.Getenv("API_BASE_URL"), "/"),
+		UnsubscribeSecret: []byte(os.Getenv("EMAIL_UNSUBSCRIBE_SECRET")),
+	}
+	if cfg.From == "" {
+		cfg.From = "Resto Rate <no-reply@localhost>"
+	}
+	if cfg.AppURL == "" {
+		cfg.AppURL = "http://localhost:5173"
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = "http://localhost:3001"
+	}
+	if len(cfg.UnsubscribeSecret) == 0 {
+		slog.Warn("EMAIL_UNSUBSCRIBE_SECRET is not set; unsubscribe links will stop working after a restart")
+		cfg.UnsubscribeSecret = make([]byte, 32)
+		if _, err := rand.Read(cfg.UnsubscribeSecret); err != nil {
+			panic(err)
+		}
+	}
+	return cfg
+}
+
+// EmailDispatcher delivers queued emails and schedules weekly digests, as jobs registered
+// by RegisterJobs. Any number of replicas can run it at once: rows are leased with
+// FOR UPDATE SKIP LOCKED and digests are de-duplicated per user and week.
+type EmailDispatcher struct {
+	DB        *gorm.DB
+	Transport email.Transport
+	Config    EmailConfig
+}
+
+func NewEmailDispatcher(db *gorm.DB, transport email.Transport, cfg EmailConfig) *EmailDispatcher {
+	return &EmailDispatcher{DB: db, Transport: transport, Config: cfg}
+}
+
+var (
+	// emailDeliveryJob drains the outbox. It is enqueued whenever an email is queued and
+	// also runs every minute to pick up retries.
+	emailDeliveryJob = jobs.Kind[struct{}]{Name: "email.deliver"}
+	weeklyDigestJob  = jobs.Kind[struct{}]{Name: "email.weekly_digests"}
+)
+
+// RegisterJobs registers email delivery and the weekly digest on r.
+func (d *EmailDispatcher) RegisterJobs(r *jobs.Runner) error {
+	jobs.Handle(r, emailDeliveryJob, func(ctx context.Context, _ struct{}) error {
+		for {
+			n, err := d.DeliverDue(ctx, time.Now())
+			if err != nil || n < emailBatchSize {
+				return err
+			}
+		}
+	})
+	jobs.Handle(r, weeklyDigestJob, func(ctx context.Context, _ struct{}) error {
+		return d.EnqueueWeeklyDigests(ctx, time.Now())
+	})
+	if err := jobs.Every(r, emailDeliveryJob.Name, "@every 1m", emailDeliveryJob, struct{}{}); err != nil {
+		return err
+	}
+	// Digests are de-duplicated per week, so running hourly just catches up after downtime.
+	return jobs.Every(r, weeklyDigestJob.Name, "@hourly", weeklyDigestJob, struct{}{})
+}
+
+// DeliverDue sends up to one batch of emails whose next attempt is due and returns how
+// many were processed. Rows are claimed first, in a transaction of their own, and sent
+// after it commits: no row lock is held during SMTP I/O, and a failed commit cannot send
+// an email twice. A claim is a lease; if the replica holding it dies before recording the
+// outcome, the row becomes due again when the lease runs out.
+func (d *EmailDispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	rows, err := d.claimDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range rows {
+		row := &rows[i]
+		d.attempt(ctx, row, now)
+		// The email may have gone out, so record that even if ctx was cancelled meanwhile.
+		if err := d.record(context.WithoutCancel(ctx), row); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
+
+// claimDue leases up to one batch of due rows, counting the attempt, and returns them
+// as claimed. Rows whose earlier lease ran out are due again.
+func (d *EmailDispatcher) claimDue(ctx context.Context, now time.Time) ([]models.EmailOutbox, error) {
+	var rows []models.EmailOutbox
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{models.EmailStatusPending, models.EmailStatusSending}, now).
+			Order("next_attempt_at").Limit(emailBatchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]string, len(rows))
+		for i := range rows {
+			ids[i] = rows[i].ID
+			rows[i].Status = models.EmailStatusSending
+			rows[i].Attempts++
+		}
+		return tx.Model(&models.EmailOutbox{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":          models.EmailStatusSending,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(emailSendLease),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// attempt tries to send a claimed row once and records the outcome on it.
+func (d *EmailDispatcher) attempt(ctx context.Context, row *models.EmailOutbox, now time.Time) {
+	msg, skipReason, err := d.compose(ctx, d.DB, row)
+	switch {
+	case errors.Is(err, errPermanentEmailFailure):
+		row.Status = models.EmailStatusFailed
+		row.LastError = err.Error()
+		return
+	case err == nil && skipReason != "":
+		row.Status = models.EmailStatusSkipped
+		row.LastError = skipReason
+		return
+	case err == nil:
+		err = d.Transport.Send(ctx, msg)
+	}
+
+	if err == nil {
+		row.Status = models.EmailStatusSent
+		row.LastError = ""
+		row.SentAt = &now
+		return
+	}
+	row.LastError = err.Error()
+	if row.Attempts >= emailMaxAttempts {
+		row.Status = models.EmailStatusFailed
+		slog.Warn("Giving up on email", slog.String("id", row.ID), slog.String("template", row.Template), slog.Any("error", err))
+		return
+	}
+	row.Status = models.EmailStatusPending
+	row.NextAttemptAt = now.Add(EmailRetryDelay(int(row.Attempts)))
+}
+
+// record stores the outcome of an attempt on a claimed row. It changes nothing if the
+// lease ran out and another replica claimed the row since.
+func (d *EmailDispatcher) record(ctx context.Context, row *models.EmailOutbox) error {
+	return d.DB.WithContext(ctx).Model(&models.EmailOutbox{}).
+		Where("id = ? AND status = ? AND attempts = ?", row.ID, models.EmailStatusSending, row.Attempts).
+		Updates(map[string]any{
+			"status":          row.Status,
+			"last_error":      row.LastError,
+			"sent_at":         row.SentAt,
+			"next_attempt_at": row.NextAttemptAt,
+		}).Error
+}
+
+var errPermanentEmailFailure = errors.New("email can never be delivered")
+
+// compose builds the message for row. It returns a non-empty skip reason when the
+// email should no longer be sent.
+func (d *EmailDispatcher) compose(ctx context.Context, db *gorm.DB, row *models.EmailOutbox) (email.Message, string, error) {
+	var user models.User
+	if err := db.WithContext(ctx).First(&user, "id = ?", row.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return email.Message{}, "recipient no longer exists", nil
+		}
+		return email.Message{}, "", err
+	}
+	if user.Email == nil || *user.Email == "" {
+		return email.Message{}, "", fmt.Errorf("%w: recipient has no email address", errPermanentEmailFailure)
+	}
+
+	prefs, err := loadNotificationPreferences(ctx, db, user.ID)
+	if err != nil {
+		return email.Message{}, "", err
+	}
+	if pref, ok := prefs[row.Template]; ok && !pref.Email {
+		return email.Message{}, "recipient unsubscribed", nil
+	}
+
+	params := email.Params{
+		Locale:            user.DefaultLanguage,
+		RecipientName:     user.Name,
+		PlaceName:         row.Params[emailParamSubjectName],
+		UnsubscribeURL:    d.unsubscribeURL(user.ID, row.Template),
+		UnsubscribeAllURL: d.unsubscribeURL(user.ID, email.UnsubscribeAll),
+	}
+
+	switch row.Template {
+	case email.TemplateFriendRequestReceived, email.TemplateFriendRequestAccepted, email.TemplateFriendReviewedWishlistPlace:
+		params.ActionURL = notificationActionURL(d.Config.AppURL, row.Template, row.Params[emailParamSubjectID])
+	case email.TemplateWeeklyDigest:
+		params.ActionURL = d.Config.AppURL
+		digest, err := d.buildDigest(ctx, db, user.ID, row.Params)
+		if err != nil {
+			return email.Message{}, "", err
+		}
+		if digest.Empty() {
+			return email.Message{}, "nothing to report", nil
+		}
+		params.Digest = digest
+	default:
+		return email.Message{}, "", fmt.Errorf("%w: unknown template %q", errPermanentEmailFailure, row.Template)
+	}
+
+	if actorID := row.Params[emailParamActorID]; actorID != "" {
+		var actor models.User
+		if err := db.WithContext(ctx).First(&actor, "id = ?", actorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return email.Message{}, "actor no longer exists", nil
+			}
+			return email.Message{}, "", err
+		}
+		params.ActorName = actor.Name
+	}
+
+	rendered, err := email.Render(row.Template, params)
+	if err != nil {
+		return email.Message{}, "", fmt.Errorf("%w: %v", errPermanentEmailFailure, err)
+	}
+	return email.Message{
+		From:    d.Config.From,
+		To:      *user.Email,
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+		Headers: map[string]string{
+			// RFC 8058 one-click unsubscribe.
+			"List-Unsubscribe":      "<" + params.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, "", nil
+}
+
+// notificationActionURL returns the web app page a notification of the given type links to.
+func notificationActionURL(appURL, notificationType, subjectID string) string {
+	switch notificationType {
+	case models.NotificationTypeFriendRequestReceived, models.NotificationTypeFriendRequestAccepted:
+		return appURL + "/friends"
+	case models.NotificationTypeFriendReviewedWishlistPlace:
+		return appURL + "/restaurants/" + url.PathEscape(subjectID)
+	default:
+		return appURL
+	}
+}
+
+func (d *EmailDispatcher) unsubscribeURL(userID, scope string) string {
+	token := email.SignUnsubscribeToken(d.Config.UnsubscribeSecret, userID, scope)
+	return d.Config.APIURL + emailUnsubscribePath + "?token=" + url.QueryEscape(token)
+}
+
+// EmailRetryDelay returns how long to wait before retrying after the given number of
+// failed attempts: one minute, doubling each time, capped at six hours.
+func EmailRetryDelay(attempts int) time.Duration {
+	const maxDelay = 6 * time.Hour
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 10 {
+		return maxDelay
+	}
+	delay := time.Minute << (attempts - 1)
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// WeeklyDigestPeriod returns the previous ISO week (Monday 00:00 UTC to Monday 00:00 UTC)
+// relative to now, which is the period a digest sent at now covers.
+func WeeklyDigestPeriod(now time.Time) (since, until time.Time) {
+	now = now.UTC()
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	until = time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	return until.AddDate(0, 0, -7), until
+}
+
+// EnqueueWeeklyDigests queues last week's digest for every user who has an email address
+// and hasn't turned digests off. It is idempotent: each user gets at most one digest per week.
+func (d *EmailDispatcher) EnqueueWeeklyDigests(ctx context.Context, now time.Time) error {
+	since, until := WeeklyDigestPeriod(now)
+	year, week := since.ISOWeek()
+
+	var userIDs []string
+	if err := d.DB.WithContext(ctx).Model(&models.User{}).
+		Where("email IS NOT NULL AND email <> ''").
+		Where("NOT EXISTS (SELECT 1 FROM notification_preferences np WHERE np.user_id = users.id AND np.type = ? AND np.email = false)",
+			models.NotificationTypeWeeklyDigest).
+		Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		emailParamSince: strconv.FormatInt(since.Unix(), 10),
+		emailParamUntil: strconv.FormatInt(until.Unix(), 10),
+	}
+	for _, userID := range userIDs {
+		key := fmt.Sprintf("%s:%s:%d-W%02d", models.NotificationTypeWeeklyDigest, userID, year, week)
+		if err := enqueueEmail(d.DB.WithContext(ctx), userID, models.NotificationTypeWeeklyDigest, params, &key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDigest collects the friend reviews the user can see from the digest period,
+// splitting out those for places on the user's wishlist.
+func (d *EmailDispatcher) buildDigest(ctx context.Context, db *gorm.DB, userID string, params map[string]string) (*email.Digest, error) {
+	sinceUnix, err := strconv.ParseInt(params[emailParamSince], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid digest period", errPermanentEmailFailure)
+	}
+	untilUnix, err := strconv.ParseInt(params[emailParamUntil], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid digest period", errPermanentEmailFailure)
+	}
+	digest := &email.Digest{Since: time.Unix(sinceUnix, 0).UTC()}
+
+	friendIDs, err := getFriendIDs(ctx, db, userID)
+	if err != nil || len(friendIDs) == 0 {
+		return digest, err
+	}
+
+	var reviews []models.Review
+	query := db.WithContext(ctx).Preload("User").Preload("Restaurant").
+		Where("reviews.user_id IN ? AND reviews.created_at >= ? AND reviews.created_at < ?",
+			friendIDs, time.Unix(sinceUnix, 0), time.Unix(untilUnix, 0))
+	if err := applyReviewVisibility(query, userID).Order("reviews.created_at DESC").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	var wishlisted []string
+	if err := db.WithContext(ctx).Model(&models.WishlistItem{}).Where("user_id = ?", userID).
+		Pluck("restaurant_id", &wishlisted).Error; err != nil {
+		return nil, err
+	}
+	onWishlist := make(map[string]bool, len(wishlisted))
+	for _, id := range wishlisted {
+		onWishlist[id] = true
+	}
+
+	for _, r := range reviews {
+		entry := email.DigestEntry{ActorName: r.User.Name, PlaceName: r.Restaurant.Name, Rating: r.Rating}
+		if onWishlist[r.RestaurantID] {
+			digest.WishlistHits = append(digest.WishlistHits, entry)
+		} else {
+			digest.Reviews = append(digest.Reviews, entry)
+		}
+	}
+	return digest, nil
+}
+
+// enqueueEmail queues an email for userID and makes sure a delivery job is pending.
+// With a dedupeKey, nothing is queued if an email with the same key already exists.
+func enqueueEmail(tx *gorm.DB, userID, templateName string, params map[string]string, dedupeKey *string) error {
+	row := models.EmailOutbox{
+		UserID:        userID,
+		Template:      templateName,
+		Params:        params,
+		DedupeKey:     dedupeKey,
+		Status:        models.EmailStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if dedupeKey == nil {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+	} else {
+		res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "dedupe_key"}}, DoNothing: true}).Create(&row)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+	}
+	return emailDeliveryJob.Enqueue(tx, struct{}{}, jobs.Dedupe(emailDeliveryJob.Name))
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 48px auto;">
+{{if .Done}}<p>You have been unsubscribed.</p>
+{{else}}<form method="post"><p>Stop receiving these emails from Resto Rate?</p><button type="submit">Unsubscribe</button></form>
+{{end}}</body></html>
+`))
+
+// UnsubscribeHandler serves the link in every email footer and the List-Unsubscribe
+// header. GET shows a confirmation form so link scanners can't unsubscribe anyone;
+// POST (including RFC 8058 one-click) applies it.
+func (d *EmailDispatcher) UnsubscribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, scope, err := email.ParseUnsubscribeToken(d.Config.UnsubscribeSecret, r.URL.Query().Get("token"))
+		if err != nil {
+			http.Error(w, "invalid or expired link", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = unsubscribePage.Execute(w, struct{ Done bool }{false})
+		case http.MethodPost:
+			if err := disableEmailNotifications(r.Context(), d.DB, userID, scope); err != nil {
+				slog.Error("Failed to unsubscribe", slog.String("user_id", userID), slog.Any("error", err))
+				http.Error(w, "failed to unsubscribe", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = unsubscribePage.Execute(w, struct{ Done bool }{true})
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// disableEmailNotifications turns off email for one notification type, or for all of
+// them when scope is email.UnsubscribeAll. In-app settings are kept.
+func disableEmailNotifications(ctx context.Context, db *gorm.DB, userID, scope string) error {
+	prefs, err := loadNotificationPreferences(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+	var rows []models.NotificationPreference
+	for _, t := range models.NotificationTypes {
+		if scope != email.UnsubscribeAll && scope != t {
+			continue
+		}
+		p := prefs[t]
+		p.Email = false
+		rows = append(rows, p)
+	}
+	return saveNotificationPreferences(ctx, db, rows)
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	exportsv1 "api/src/generated/exports/v1"
+	"api/src/generated/exports/v1/v1connect"
+	reviewsv1 "api/src/generated/reviews/v1"
+	tagsv1 "api/src/generated/tags/v1"
+	wishlistv1 "api/src/generated/wishlist/v1"
+	"api/src/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
+	"gorm.io/gorm"
+)
+
+// Values of the status and layer feature properties.
+const (
+	PlaceStatusReviewed   = "reviewed"
+	PlaceStatusWishlisted = "wishlisted"
+	OwnLayer              = "mine"
+)
+
+type ExportService struct {
+	v1connect.UnimplementedExportServiceHandler
+	DB     *gorm.DB
+	Valkey valkey.Client
+}
+
+func NewExportService(db *gorm.DB, kv valkey.Client) *ExportService {
+	return &ExportService{DB: db, Valkey: kv}
+}
+
+// FeatureCollection is a GeoJSON FeatureCollection of places.
+type FeatureCollection struct {
+	Type     string         `json:"type"`
+	Features []PlaceFeature `json:"features"`
+}
+
+// PlaceFeature is one review or wishlist item as a GeoJSON Point feature.
+type PlaceFeature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Geometry   PointGeometry   `json:"geometry"`
+	Properties PlaceProperties `json:"properties"`
+}
+
+// PointGeometry holds [longitude, latitude], in that order.
+type PointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type PlaceProperties struct {
+	Status string `json:"status"`
+	// OwnLayer for the caller's entries, else the friend's user ID.
+	Layer          string   `json:"layer"`
+	OwnerName      string   `json:"owner_name"`
+	GooglePlacesID string   `json:"google_places_id"`
+	Name           string   `json:"name"`
+	Address        string   `json:"address"`
+	City           string   `json:"city"`
+	Country        string   `json:"country"`
+	Rating         *float64 `json:"rating,omitempty"`
+	Tags           []string `json:"tags"`
+	PersonalTags   []string `json:"personal_tags"`
+	CreatedAt      string   `json:"created_at"`
+}
+
+func (s *ExportService) ExportPlaces(
+	ctx context.Context,
+	req *connect.Request[exportsv1.ExportPlacesRequest],
+) (*connect.Response[exportsv1.ExportPlacesResponse], error) {
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, skipped, err := s.exportPlaces(ctx, userID, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&exportsv1.ExportPlacesResponse{
+		Geojson:      string(data),
+		FeatureCount: int32(len(collection.Features)),
+		SkippedCount: int32(skipped),
+	}), nil
+}
+
+// GeoJSONHandler serves GET /export/places.geojson for map viewers. It takes the
+// ExportPlaces request as query parameters (see ExportPlacesRequestFromQuery) and the
+// session cookie.
+func (s *ExportService) GeoJSONHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.DB == nil {
+			http.Error(w, errDatabaseNotInitialized, http.StatusInternalServerError)
+			return
+		}
+		msg, err := ExportPlacesRequestFromQuery(r.URL.Query())
+		if err != nil {
+			writeConnectErrorHTTP(w, err)
+			return
+		}
+		userID, err := getUserIDFromSession(r.Context(), r.Header, s.Valkey)
+		if err != nil {
+			writeConnectErrorHTTP(w, err)
+			return
+		}
+
+		collection, _, err := s.exportPlaces(r.Context(), userID, msg)
+		if err != nil {
+			slog.Error("Failed to export places", slog.String("user_id", userID), slog.Any("error", err))
+			writeConnectErrorHTTP(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Header().Set("Cache-Control", "private, no-store")
+		_ = json.NewEncoder(w).Encode(collection)
+	})
+}
+
+// ExportPlacesRequestFromQuery reads an ExportPlaces request from query parameters named
+// after the ListReviews and ListWishlist fields: google_places_id, city, country, tag_slugs
+// (repeated) and tag_filter_mode (and/or) filter both, min_rating, max_rating and
+// comment_search only reviews. friend_ids (repeated), all_friends, skip_reviews and
+// skip_wishlist match the request fields.
+func ExportPlacesRequestFromQuery(q url.Values) (*exportsv1.ExportPlacesRequest, error) {
+	msg := &exportsv1.ExportPlacesRequest{
+		ReviewFilter: &reviewsv1.ListReviewsRequest{
+			GooglePlacesId: q.Get("google_places_id"),
+			TagSlugs:       q["tag_slugs"],
+			CommentSearch:  q.Get("comment_search"),
+			City:           q.Get("city"),
+			Country:        q.Get("country"),
+		},
+		WishlistFilter: &wishlistv1.ListWishlistRequest{
+			GooglePlacesId: q.Get("google_places_id"),
+			TagSlugs:       q["tag_slugs"],
+			City:           q.Get("city"),
+			Country:        q.Get("country"),
+		},
+		FriendIds: q["friend_ids"],
+	}
+
+	switch strings.ToLower(q.Get("tag_filter_mode")) {
+	case "", "or":
+	case "and":
+		msg.ReviewFilter.TagFilterMode = reviewsv1.TagFilterMode_TAG_FILTER_MODE_AND
+		msg.WishlistFilter.TagFilterMode = wishlistv1.WishlistTagFilterMode_WISHLIST_TAG_FILTER_MODE_AND
+	default:
+		return nil, invalidQueryParam("tag_filter_mode")
+	}
+
+	for name, dst := range map[string]*float64{"min_rating": &msg.ReviewFilter.MinRating, "max_rating": &msg.ReviewFilter.MaxRating} {
+		if v := q.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalidQueryParam(name)
+			}
+			*dst = f
+		}
+	}
+	for name, dst := range map[string]*bool{"all_friends": &msg.AllFriends, "skip_reviews": &msg.SkipReviews, "skip_wishlist": &msg.SkipWishlist} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, invalidQueryParam(name)
+			}
+			*dst = b
+		}
+	}
+	return msg, nil
+}
+
+func invalidQueryParam(name string) error {
+	return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid %s", name))
+}
+
+// exportPlaces builds the FeatureCollection for userID and returns how many entries were
+// left out for lack of coordinates.
+func (s *ExportService) exportPlaces(ctx context.Context, userID string, msg *exportsv1.ExportPlacesRequest) (*FeatureCollection, int, error) {
+	reviewFilter := msg.ReviewFilter
+	if reviewFilter == nil {
+		reviewFilter = &reviewsv1.ListReviewsRequest{}
+	}
+	wishlistFilter := msg.WishlistFilter
+	if wishlistFilter == nil {
+		wishlistFilter = &wishlistv1.ListWishlistRequest{}
+	}
+	if err := validateListReviewsRequest(reviewFilter); err != nil {
+		return nil, 0, err
+	}
+
+	friendIDs, err := s.exportFriendIDs(ctx, userID, msg)
+	if err != nil {
+		return nil, 0, err
+	}
+	names, err := userNames(ctx, s.DB, append([]string{userID}, friendIDs...))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	collection := &FeatureCollection{Type: "FeatureCollection", Features: []PlaceFeature{}}
+	skipped := 0
+	add := func(feature PlaceFeature, ok bool) {
+		if !ok {
+			skipped++
+			return
+		}
+		feature.Properties.OwnerName = names[feature.Properties.Layer]
+		if feature.Properties.Layer == userID {
+			feature.Properties.Layer = OwnLayer
+		}
+		collection.Features = append(collection.Features, feature)
+	}
+
+	if !msg.SkipReviews {
+		var reviews []models.Review
+		query := applyOwnOrSharedReviews(preloadPersonalTags(s.DB.WithContext(ctx), userID), userID, friendIDs)
+		if err := applyListReviewsFilters(query, reviewFilter, userID).
+			Order("reviews.created_at DESC").
+			Find(&reviews).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range reviews {
+			add(ReviewFeature(&reviews[i]))
+		}
+	}
+
+	if !msg.SkipWishlist {
+		var items []models.WishlistItem
+		query := applyOwnOrSharedWishlist(preloadPersonalTags(s.DB.WithContext(ctx), userID), userID, friendIDs).
+			Preload("Restaurant").
+			Preload("Tags")
+		if err := applyListWishlistFilters(query, wishlistFilter, userID).
+			Order("wishlist_items.created_at DESC").
+			Find(&items).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range items {
+			add(WishlistFeature(&items[i]))
+		}
+	}
+	return collection, skipped, nil
+}
+
+// exportFriendIDs returns the friends whose layers msg asks for, checking the friendships.
+func (s *ExportService) exportFriendIDs(ctx context.Context, userID string, msg *exportsv1.ExportPlacesRequest) ([]string, error) {
+	if msg.AllFriends {
+		return getFriendIDs(ctx, s.DB, userID)
+	}
+	var ids []string
+	for _, id := range uniqueStrings(msg.FriendIds) {
+		if id == userID {
+			continue
+		}
+		if err := assertFriendship(ctx, s.DB, userID, id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// userNames maps user IDs to display names.
+func userNames(ctx context.Context, db *gorm.DB, ids []string) (map[string]string, error) {
+	var users []models.User
+	if err := db.WithContext(ctx).Select("id, name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+	return names, nil
+}
+
+// ReviewFeature returns a review (with Restaurant and tags loaded) as a feature whose Layer
+// is the author's ID. It reports false when the restaurant has no coordinates.
+func ReviewFeature(r *models.Review) (PlaceFeature, bool) {
+	rating := r.Rating
+	p := r.ToProto()
+	return placeFeature(r.ID, &r.Restaurant, PlaceProperties{
+		Status:       PlaceStatusReviewed,
+		Layer:        r.UserID,
+		Rating:       &rating,
+		Tags:         p.Tags,
+		PersonalTags: personalTagSlugs(p.PersonalTags),
+		CreatedAt:    r.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// WishlistFeature is ReviewFeature for wishlist items.
+func WishlistFeature(item *models.WishlistItem) (PlaceFeature, bool) {
+	p := item.ToProto()
+	return placeFeature(item.ID, &item.Restaurant, PlaceProperties{
+		Status:       PlaceStatusWishlisted,
+		Layer:        item.UserID,
+		Tags:         p.Tags,
+		PersonalTags: personalTagSlugs(p.PersonalTags),
+		CreatedAt:    item.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func placeFeature(id string, restaurant *models.Restaurant, props PlaceProperties) (PlaceFeature, bool) {
+	if !restaurant.HasLocation() {
+		return PlaceFeature{}, false
+	}
+	props.GooglePlacesID = restaurant.GoogleID
+	props.Name = restaurant.Name
+	props.Address = restaurant.Address
+	props.City = restaurant.City
+	props.Country = restaurant.Country
+	return PlaceFeature{
+		Type:       "Feature",
+		ID:         id,
+		Geometry:   PointGeometry{Type: "Point", Coordinates: [2]float64{*restaurant.Longitude, *restaurant.Latitude}},
+		Properties: props,
+	}, true
+}
+
+func personalTagSlugs(tags []*tagsv1.PersonalTagProto) []string {
+	slugs := make([]string, len(tags))
+	for i, t := range tags {
+		slugs[i] = t.Slug
+	}
+	return slugs
+}
+
+// writeConnectErrorHTTP reports err from a plain HTTP handler with the status matching its
+// Connect code.
+func writeConnectErrorHTTP(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch connect.CodeOf(err) {
+	case connect.CodeInvalidArgument:
+		status = http.StatusBadRequest
+	case connect.CodeUnauthenticated:
+		status = http.StatusUnauthorized
+	case connect.CodePermissionDenied:
+		status = http.StatusForbidden
+	case connect.CodeNotFound:
+		status = http.StatusNotFound
+	}
+	message := http.StatusText(status)
+	var connectErr *connect.Error
+	if status != http.StatusInternalServerError && errors.As(err, &connectErr) {
+		message = connectErr.Message()
+	}
+	http.Error(w, message, status)
+}
@@ -50,6 +50,14 @@ func (s *FriendshipService) SendFriendRequest(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("cannot send friend request to yourself"))
 	}
 
+	privacy, err := loadPrivacySettings(ctx, s.DB, senderID, receiver.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFriendRequestAllowed(ctx, s.DB, senderID, privacy[receiver.ID], req.Msg.GetReceiverEmail() != ""); err != nil {
+		return nil, err
+	}
+
 	var sender models.User
 	if err := s.DB.WithContext(ctx).First(&sender, "id = ?", senderID).Error; err != nil {
 		return nil, err
@@ -71,7 +79,8 @@ func (s *FriendshipService) SendFriendRequest(
 			if err := s.DB.WithContext(ctx).Save(&existing).Error; err != nil {
 				return nil, err
 			}
-			return connect.NewResponse(&v1.SendFriendRequestResponse{Request: existing.ToProto()}), nil
+			notifyFriendRequestReceived(ctx, s.DB, &existing)
+			return connect.NewResponse(&v1.SendFriendRequestResponse{Request: existing.ToProto(senderID, privacy)}), nil
 		}
 		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New("friend request already exists"))
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -88,8 +97,9 @@ func (s *FriendshipService) SendFriendRequest(
 	if err := s.DB.WithContext(ctx).Create(&fr).Error; err != nil {
 		return nil, err
 	}
+	notifyFriendRequestReceived(ctx, s.DB, &fr)
 
-	return connect.NewResponse(&v1.SendFriendRequestResponse{Request: fr.ToProto()}), nil
+	return connect.NewResponse(&v1.SendFriendRequestResponse{Request: fr.ToProto(senderID, privacy)}), nil
 }
 
 func (s *FriendshipService) AcceptFriendRequest(
@@ -118,8 +128,19 @@ func (s *FriendshipService) AcceptFriendRequest(
 	if err := s.DB.WithContext(ctx).Save(&fr).Error; err != nil {
 		return nil, err
 	}
+	notify(ctx, s.DB, notificationEvent{
+		UserID:    fr.SenderID,
+		Type:      models.NotificationTypeFriendRequestAccepted,
+		ActorID:   fr.ReceiverID,
+		SubjectID: fr.ID,
+	})
 
-	return connect.NewResponse(&v1.AcceptFriendRequestResponse{Request: fr.ToProto()}), nil
+	privacy, err := loadPrivacySettings(ctx, s.DB, fr.SenderID, fr.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.AcceptFriendRequestResponse{Request: fr.ToProto(userID, privacy)}), nil
 }
 
 func (s *FriendshipService) DeclineFriendRequest(
@@ -165,15 +186,22 @@ func (s *FriendshipService) RemoveFriend(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errFriendUserIDRequired))
 	}
 
-	result := s.DB.WithContext(ctx).Where(
-		"((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND status = ?",
-		userID, req.Msg.FriendUserId, req.Msg.FriendUserId, userID, models.FriendRequestStatusAccepted,
-	).Delete(&models.FriendRequest{})
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return nil, connect.NewError(connect.CodeNotFound, errors.New("friendship not found"))
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where(
+			"((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND status = ?",
+			userID, req.Msg.FriendUserId, req.Msg.FriendUserId, userID, models.FriendRequestStatusAccepted,
+		).Delete(&models.FriendRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return connect.NewError(connect.CodeNotFound, errors.New("friendship not found"))
+		}
+		// Former friends must not keep access through circles if they become friends again later.
+		return removeMutualCircleMemberships(tx, userID, req.Msg.FriendUserId)
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
 
 	return connect.NewResponse(&v1.RemoveFriendResponse{Success: true}), nil
@@ -195,24 +223,35 @@ func (s *FriendshipService) ListFriends(
 		return nil, err
 	}
 
+	friendIDs := make([]string, len(friendRequests))
+	for i, fr := range friendRequests {
+		friendIDs[i] = fr.SenderID
+		if fr.SenderID == userID {
+			friendIDs[i] = fr.ReceiverID
+		}
+	}
+	privacy, err := loadPrivacySettings(ctx, s.DB, friendIDs...)
+	if err != nil {
+		return nil, err
+	}
+
 	friends := make([]*v1.FriendProto, len(friendRequests))
 	for i, fr := range friendRequests {
+		friend := fr.Sender
 		if fr.SenderID == userID {
-			friends[i] = &v1.FriendProto{
-				UserId:       fr.ReceiverID,
-				Name:         fr.Receiver.Name,
-				Email:        derefStr(fr.Receiver.Email),
-				Username:     derefStr(fr.Receiver.Username),
-				FriendsSince: fr.UpdatedAt.Unix(),
-			}
-		} else {
-			friends[i] = &v1.FriendProto{
-				UserId:       fr.SenderID,
-				Name:         fr.Sender.Name,
-				Email:        derefStr(fr.Sender.Email),
-				Username:     derefStr(fr.Sender.Username),
-				FriendsSince: fr.UpdatedAt.Unix(),
-			}
+			friend = fr.Receiver
+		}
+		settings := privacy[friend.ID]
+		email := ""
+		if settings.EmailVisibleTo(true) {
+			email = derefStr(friend.Email)
+		}
+		friends[i] = &v1.FriendProto{
+			UserId:       friend.ID,
+			Name:         friend.Name,
+			Email:        email,
+			Username:     derefStr(friend.Username),
+			FriendsSince: fr.UpdatedAt.Unix(),
 		}
 	}
 
@@ -235,9 +274,10 @@ func (s *FriendshipService) ListPendingRequests(
 		return nil, err
 	}
 
+	// Pending requests never reveal the sender's email, so no settings need loading.
 	protos := make([]*v1.FriendRequestProto, len(friendRequests))
 	for i, fr := range friendRequests {
-		protos[i] = fr.ToProto()
+		protos[i] = fr.ToProto(userID, nil)
 	}
 
 	return connect.NewResponse(&v1.ListPendingRequestsResponse{Requests: protos}), nil
@@ -256,7 +296,8 @@ func (s *FriendshipService) FindUserByHandle(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidUsername))
 	}
 
-	if _, err := getUserIDFromSession(ctx, req.Header(), s.Valkey); err != nil {
+	viewerID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
 		return nil, err
 	}
 
@@ -268,6 +309,22 @@ func (s *FriendshipService) FindUserByHandle(
 		return nil, err
 	}
 
+	if user.ID != viewerID {
+		settings, err := privacySettingsFor(ctx, s.DB, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !settings.DiscoverableByUsername {
+			isFriend, err := areFriends(ctx, s.DB, viewerID, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !isFriend {
+				return nil, connect.NewError(connect.CodeNotFound, errors.New(errUserNotFound))
+			}
+		}
+	}
+
 	return connect.NewResponse(&v1.FindUserByHandleResponse{
 		Id:       user.ID,
 		Username: derefStr(user.Username),
@@ -320,6 +377,59 @@ func lookupReceiverByUsername(ctx context.Context, db *gorm.DB, rawUsername stri
 	return receiver, nil
 }
 
+func notifyFriendRequestReceived(ctx context.Context, db *gorm.DB, fr *models.FriendRequest) {
+	notify(ctx, db, notificationEvent{
+		UserID:    fr.ReceiverID,
+		Type:      models.NotificationTypeFriendRequestReceived,
+		ActorID:   fr.SenderID,
+		SubjectID: fr.ID,
+	})
+}
+
+// checkFriendRequestAllowed enforces the receiver's privacy settings for a new friend request.
+// Users hidden from the lookup method are reported as not found so their existence isn't leaked.
+func checkFriendRequestAllowed(ctx context.Context, db *gorm.DB, senderID string, receiver models.PrivacySettings, byEmail bool) error {
+	if (byEmail && !receiver.DiscoverableByEmail) || (!byEmail && !receiver.DiscoverableByUsername) {
+		return connect.NewError(connect.CodeNotFound, errors.New(errUserNotFound))
+	}
+
+	switch receiver.FriendRequestPolicy {
+	case models.FriendRequestPolicyNobody:
+		return connect.NewError(connect.CodePermissionDenied, errors.New(errFriendRequestsNotAccepted))
+	case models.FriendRequestPolicyFriendsOfFriends:
+		mutual, err := haveMutualFriend(ctx, db, senderID, receiver.UserID)
+		if err != nil {
+			return err
+		}
+		if !mutual {
+			return connect.NewError(connect.CodePermissionDenied, errors.New(errFriendRequestsNotAccepted))
+		}
+	}
+	return nil
+}
+
+// haveMutualFriend reports whether a and b share at least one accepted friend.
+func haveMutualFriend(ctx context.Context, db *gorm.DB, a, b string) (bool, error) {
+	aFriends, err := getFriendIDs(ctx, db, a)
+	if err != nil {
+		return false, err
+	}
+	bFriends, err := getFriendIDs(ctx, db, b)
+	if err != nil {
+		return false, err
+	}
+	seen := make(map[string]struct{}, len(aFriends))
+	for _, id := range aFriends {
+		seen[id] = struct{}{}
+	}
+	for _, id := range bFriends {
+		if _, ok := seen[id]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // getFriendIDs returns the user IDs of all accepted friends for the given user.
 func getFriendIDs(ctx context.Context, db *gorm.DB, userID string) ([]string, error) {
 	var friendRequests []models.FriendRequest
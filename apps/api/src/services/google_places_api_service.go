@@ -4,7 +4,9 @@ import (
 	v1 "api/src/generated/google_maps/v1"
 	"api/src/generated/google_maps/v1/v1connect"
 	"api/src/internal/mappers"
+	"api/src/internal/models"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -13,20 +15,22 @@ import (
 	"cloud.google.com/go/maps/places/apiv1/placespb"
 	"connectrpc.com/connect"
 	"google.golang.org/api/option"
-	"google.golang.org/grpc/metadata"
 )
 
 type GooglePlacesAPIService struct {
 	v1connect.UnimplementedGoogleMapsServiceHandler
-	client *places.Client
+	places   PlacesProvider
+	sessions *AutocompleteSessions
 }
 
 func NewGooglePlacesAPIClient() (*places.Client, error) {
 	return places.NewClient(context.Background(), option.WithAPIKey(os.Getenv("GOOGLE_PLACES_API_KEY")))
 }
 
-func NewGooglePlacesAPIService(client *places.Client) *GooglePlacesAPIService {
-	return &GooglePlacesAPIService{client: client}
+// NewGooglePlacesAPIService returns the service; with nil sessions, no autocomplete session
+// tokens are issued.
+func NewGooglePlacesAPIService(provider PlacesProvider, sessions *AutocompleteSessions) *GooglePlacesAPIService {
+	return &GooglePlacesAPIService{places: provider, sessions: sessions}
 }
 
 func (s *GooglePlacesAPIService) AutocompletePlaces(
@@ -48,16 +52,18 @@ func (s *GooglePlacesAPIService) AutocompletePlaces(
 		IncludedRegionCodes:     req.Msg.IncludedRegionCodes,
 		LanguageCode:            req.Msg.LanguageCode,
 		IncludeQueryPredictions: true,
-		SessionToken:            req.Msg.SessionToken,
+		SessionToken:            s.sessions.Continue(ctx, req.Header(), req.Msg.SessionToken),
 	}
 
-	out, err := s.client.AutocompletePlaces(ctx, pbReq)
+	out, err := s.places.AutocompletePlaces(ctx, pbReq)
 	if err != nil {
 		slog.Debug("AutocompletePlaces failed", slog.Any("error", err))
 		return nil, fmt.Errorf("autocomplete places failed: %w", err)
 	}
 
-	return connect.NewResponse(mappers.AutocompletePlacesResponseToProto(out)), nil
+	resp := mappers.AutocompletePlacesResponseToProto(out)
+	resp.SessionToken = pbReq.SessionToken
+	return connect.NewResponse(resp), nil
 }
 
 func (s *GooglePlacesAPIService) GetPlace(
@@ -72,14 +78,11 @@ func (s *GooglePlacesAPIService) GetPlace(
 		Name:         req.Msg.Name,
 		LanguageCode: req.Msg.LanguageCode,
 		RegionCode:   req.Msg.RegionCode,
+		SessionToken: s.sessions.Complete(ctx, req.Header(), req.Msg.SessionToken),
 	}
 
-	if len(req.Msg.RequestedFields) > 0 {
-		mask := mappers.BuildFieldMask(req.Msg.RequestedFields)
-		ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{"X-Goog-FieldMask": mask}))
-	}
-
-	out, err := s.client.GetPlace(ctx, pbReq)
+	out, err := s.places.GetPlace(ctx, pbReq, req.Msg.RequestedFields)
+	s.sessions.Ended(pbReq.SessionToken, err)
 	if err != nil {
 		slog.Debug("GetPlace failed", slog.Any("error", err))
 		return nil, fmt.Errorf("get place failed: %w", err)
@@ -99,12 +102,11 @@ func (s *GooglePlacesAPIService) GetRestaurantDetails(
 		Name:         req.Msg.Name,
 		LanguageCode: req.Msg.LanguageCode,
 		RegionCode:   req.Msg.RegionCode,
+		SessionToken: s.sessions.Complete(ctx, req.Header(), req.Msg.SessionToken),
 	}
 
-	mask := mappers.BuildFieldMask(predefinedRestaurantDetails())
-	ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{"X-Goog-FieldMask": mask}))
-
-	out, err := s.client.GetPlace(ctx, pbReq)
+	out, err := s.places.GetPlace(ctx, pbReq, predefinedRestaurantDetails())
+	s.sessions.Ended(pbReq.SessionToken, err)
 	if err != nil {
 		slog.Debug("GetRestaurantDetails failed", slog.Any("error", err))
 		return nil, fmt.Errorf("get place failed: %w", err)
@@ -120,21 +122,27 @@ func (s *GooglePlacesAPIService) SearchText(
 		return nil, fmt.Errorf("text_query is required")
 	}
 
-	pbReq := &placespb.SearchTextRequest{
-		TextQuery:      req.Msg.TextQuery,
-		LanguageCode:   req.Msg.LanguageCode,
-		RegionCode:     req.Msg.RegionCode,
-		IncludedType:   req.Msg.IncludedType,
-		OpenNow:        req.Msg.OpenNow,
-		MaxResultCount: req.Msg.MaxResultCount,
+	if err := ValidateSearchTextRequest(req.Msg); err != nil {
+		return nil, err
 	}
 
-	if len(req.Msg.RequestedFields) > 0 {
-		mask := mappers.BuildFieldMask(req.Msg.RequestedFields)
-		ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{"X-Goog-FieldMask": mask}))
+	pbReq := &placespb.SearchTextRequest{
+		TextQuery:                        req.Msg.TextQuery,
+		LanguageCode:                     req.Msg.LanguageCode,
+		RegionCode:                       req.Msg.RegionCode,
+		RankPreference:                   mappers.RankPreferenceToSDK(req.Msg.RankPreference),
+		IncludedType:                     req.Msg.IncludedType,
+		OpenNow:                          req.Msg.OpenNow,
+		MinRating:                        req.Msg.MinRating,
+		MaxResultCount:                   req.Msg.MaxResultCount,
+		PriceLevels:                      mappers.PriceLevelsToSDK(req.Msg.PriceLevels),
+		StrictTypeFiltering:              req.Msg.StrictTypeFiltering,
+		LocationBias:                     mappers.LocationBiasToSDK(req.Msg.LocationBias),
+		LocationRestriction:              mappers.LocationRestrictionToSDK(req.Msg.LocationRestriction),
+		IncludePureServiceAreaBusinesses: req.Msg.IncludePureServiceAreaBusinesses,
 	}
 
-	out, err := s.client.SearchText(ctx, pbReq)
+	out, err := s.places.SearchText(ctx, pbReq, req.Msg.RequestedFields)
 	if err != nil {
 		slog.Debug("SearchText failed", slog.Any("error", err))
 		return nil, fmt.Errorf("search failed: %w", err)
@@ -142,6 +150,77 @@ func (s *GooglePlacesAPIService) SearchText(
 	return connect.NewResponse(mappers.SearchTextResponseToProto(out)), nil
 }
 
+// ValidateSearchTextRequest rejects what the Places API would, and options it cannot
+// express: EV filters take a charging rate and connectors, routes take coordinates and
+// polylines, and a text search is only restricted to a rectangle.
+func ValidateSearchTextRequest(req *v1.SearchTextRequest) error {
+	invalid := func(msg string) error {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(msg))
+	}
+	if req.EvOptions != nil || req.RoutingParameters != nil || req.SearchAlongRouteParameters != nil {
+		return invalid(errSearchUnsupportedOptions)
+	}
+	if req.LocationBias != nil && req.LocationRestriction != nil {
+		return invalid(errSearchLocationConflict)
+	}
+	if b := req.LocationBias; b != nil {
+		if err := validateSearchArea(b.GetCircle(), b.GetRectangle()); err != nil {
+			return err
+		}
+	}
+	if r := req.LocationRestriction; r != nil {
+		if r.GetCircle() != nil {
+			return invalid(errSearchRestrictionCircle)
+		}
+		if err := validateSearchArea(nil, r.GetRectangle()); err != nil {
+			return err
+		}
+	}
+	if req.MinRating < 0 || req.MinRating > 5 {
+		return invalid(errSearchInvalidMinRating)
+	}
+	if req.MaxResultCount < 0 || req.MaxResultCount > 20 {
+		return invalid(errSearchInvalidMaxResults)
+	}
+	for _, level := range req.PriceLevels {
+		if _, known := v1.PriceLevel_name[int32(level)]; !known || level == v1.PriceLevel_PRICE_LEVEL_UNSPECIFIED {
+			return invalid(errSearchInvalidPriceLevel)
+		}
+	}
+	if _, known := v1.RankPreference_name[int32(req.RankPreference)]; !known {
+		return invalid(errSearchInvalidRank)
+	}
+	// Distance from our server would mean nothing to the user.
+	if req.RankPreference == v1.RankPreference_RANK_PREFERENCE_DISTANCE && req.LocationBias == nil && req.LocationRestriction == nil {
+		return invalid(errSearchDistanceNoLocation)
+	}
+	if req.StrictTypeFiltering && req.IncludedType == "" {
+		return invalid(errSearchStrictWithoutType)
+	}
+	return nil
+}
+
+// validateSearchArea checks the circle or rectangle of a location bias or restriction.
+func validateSearchArea(circle *v1.Circle, rect *v1.Rectangle) error {
+	validPoint := func(p *v1.LatLng) bool {
+		return p != nil && models.ValidCoordinates(p.Lat, p.Lng)
+	}
+	switch {
+	case circle != nil:
+		if !validPoint(circle.Center) || circle.Radius <= 0 || circle.Radius > 50000 {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New(errSearchInvalidCircle))
+		}
+	case rect != nil:
+		// low.lng may exceed high.lng; the rectangle then crosses the antimeridian.
+		if !validPoint(rect.Low) || !validPoint(rect.High) || rect.Low.Lat > rect.High.Lat {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New(errSearchInvalidRectangle))
+		}
+	default:
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errSearchLocationEmpty))
+	}
+	return nil
+}
+
 func (s *GooglePlacesAPIService) SearchRestaurants(
 	ctx context.Context,
 	req *connect.Request[v1.SearchRestaurantsRequest],
@@ -159,11 +238,7 @@ func (s *GooglePlacesAPIService) SearchRestaurants(
 		MaxResultCount: 20,
 	}
 
-	ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{
-		"X-Goog-FieldMask": mappers.BuildFieldMask(predefinedRestaurantDetails()),
-	}))
-
-	out, err := s.client.SearchText(ctx, pbReq)
+	out, err := s.places.SearchText(ctx, pbReq, predefinedRestaurantDetails())
 	if err != nil {
 		slog.Debug("SearchRestaurants failed", slog.Any("error", err))
 		return nil, fmt.Errorf("search failed: %w", err)
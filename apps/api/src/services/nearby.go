@@ -0,0 +1,215 @@
+package services
+
+import (
+	v1 "api/src/generated/restaurants/v1"
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"connectrpc.com/connect"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNearbyRadius = 5000.0
+	maxNearbyRadius     = 50000.0
+)
+
+func (s *RestaurantsService) ListNearby(
+	ctx context.Context,
+	req *connect.Request[v1.ListNearbyRequest],
+) (*connect.Response[v1.ListNearbyResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	area, err := NearbyAreaFor(req.Msg)
+	if err != nil {
+		return nil, err
+	}
+	limit := int(req.Msg.Limit)
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	friendIDs, err := getFriendIDs(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	reviewed := applyOwnOrSharedReviews(s.DB.WithContext(ctx).Model(&models.Review{}), userID, friendIDs).
+		Select("reviews.restaurant_id")
+	wishlisted := applyOwnOrSharedWishlist(s.DB.WithContext(ctx).Model(&models.WishlistItem{}), userID, friendIDs).
+		Select("wishlist_items.restaurant_id")
+	var restaurants []models.Restaurant
+	if err := applyArea(s.DB.WithContext(ctx), area.Box).
+		Where("restaurants.id IN (?) OR restaurants.id IN (?)", reviewed, wishlisted).
+		Find(&restaurants).Error; err != nil {
+		return nil, err
+	}
+
+	places := area.Places(restaurants)
+	if len(places) > limit {
+		places = places[:limit]
+	}
+	if err := s.countNearbyActivity(ctx, userID, friendIDs, places); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&v1.ListNearbyResponse{Places: places}), nil
+}
+
+// countNearbyActivity fills in the review and wishlist figures of places from what userID
+// can see.
+func (s *RestaurantsService) countNearbyActivity(ctx context.Context, userID string, friendIDs []string, places []*v1.NearbyPlace) error {
+	if len(places) == 0 {
+		return nil
+	}
+	byID := make(map[string]*v1.NearbyPlace, len(places))
+	ids := make([]string, len(places))
+	for i, p := range places {
+		byID[p.Restaurant.Id] = p
+		ids[i] = p.Restaurant.Id
+	}
+
+	var reviews []struct {
+		RestaurantID string
+		UserID       string
+		Rating       float64
+	}
+	if err := applyOwnOrSharedReviews(s.DB.WithContext(ctx).Model(&models.Review{}), userID, friendIDs).
+		Select("reviews.restaurant_id, reviews.user_id, reviews.rating").
+		Where("reviews.restaurant_id IN ?", ids).
+		Scan(&reviews).Error; err != nil {
+		return err
+	}
+	for _, r := range reviews {
+		p := byID[r.RestaurantID]
+		p.AverageRating = (p.AverageRating*float64(p.ReviewCount) + r.Rating) / float64(p.ReviewCount+1)
+		p.ReviewCount++
+		p.ReviewedByMe = p.ReviewedByMe || r.UserID == userID
+	}
+
+	var items []struct {
+		RestaurantID string
+		UserID       string
+	}
+	if err := applyOwnOrSharedWishlist(s.DB.WithContext(ctx).Model(&models.WishlistItem{}), userID, friendIDs).
+		Select("wishlist_items.restaurant_id, wishlist_items.user_id").
+		Where("wishlist_items.restaurant_id IN ?", ids).
+		Scan(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		p := byID[item.RestaurantID]
+		p.WishlistCount++
+		p.OnMyWishlist = p.OnMyWishlist || item.UserID == userID
+	}
+	return nil
+}
+
+// NearbyArea is where ListNearby looks: a box, or a radius around the origin when Radius is
+// set. Distances are measured from the origin either way.
+type NearbyArea struct {
+	Box                  models.BoundingBox
+	OriginLat, OriginLng float64
+	Radius               float64
+}
+
+// NearbyAreaFor validates a ListNearby request and returns the area it asks for.
+func NearbyAreaFor(req *v1.ListNearbyRequest) (NearbyArea, error) {
+	hasOrigin := req.Latitude != nil || req.Longitude != nil
+	if hasOrigin {
+		if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+			return NearbyArea{}, err
+		}
+	}
+
+	if b := req.Bounds; b != nil {
+		area := NearbyArea{Box: models.BoundingBox{South: b.South, West: b.West, North: b.North, East: b.East}}
+		if !area.Box.Valid() {
+			return NearbyArea{}, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidBounds))
+		}
+		if hasOrigin {
+			area.OriginLat, area.OriginLng = *req.Latitude, *req.Longitude
+		} else {
+			area.OriginLat, area.OriginLng = area.Box.Center()
+		}
+		return area, nil
+	}
+
+	if !hasOrigin {
+		return NearbyArea{}, connect.NewError(connect.CodeInvalidArgument, errors.New(errLocationRequired))
+	}
+	radius := req.RadiusMeters
+	if radius <= 0 {
+		radius = defaultNearbyRadius
+	}
+	radius = min(radius, maxNearbyRadius)
+	return NearbyArea{
+		Box:       models.BoundingBoxAround(*req.Latitude, *req.Longitude, radius),
+		OriginLat: *req.Latitude,
+		OriginLng: *req.Longitude,
+		Radius:    radius,
+	}, nil
+}
+
+// Places returns the restaurants inside the area, nearest to the origin first.
+func (a NearbyArea) Places(restaurants []models.Restaurant) []*v1.NearbyPlace {
+	places := make([]*v1.NearbyPlace, 0, len(restaurants))
+	for i := range restaurants {
+		r := &restaurants[i]
+		if !r.HasLocation() || !a.Box.Contains(*r.Latitude, *r.Longitude) {
+			continue
+		}
+		distance := models.DistanceMeters(a.OriginLat, a.OriginLng, *r.Latitude, *r.Longitude)
+		if a.Radius > 0 && distance > a.Radius {
+			continue
+		}
+		places = append(places, &v1.NearbyPlace{Restaurant: r.ToProto(), DistanceMeters: distance})
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		if places[i].DistanceMeters != places[j].DistanceMeters {
+			return places[i].DistanceMeters < places[j].DistanceMeters
+		}
+		return places[i].Restaurant.Name < places[j].Restaurant.Name
+	})
+	return places
+}
+
+// applyArea restricts a restaurants query to those located in box. The geohash prefixes
+// narrow the search through idx_restaurants_geohash; the coordinates make it exact.
+func applyArea(query *gorm.DB, box models.BoundingBox) *gorm.DB {
+	var boxes []string
+	var args []any
+	for _, part := range box.Split() {
+		boxes = append(boxes, "(restaurants.latitude BETWEEN ? AND ? AND restaurants.longitude BETWEEN ? AND ?)")
+		args = append(args, part.South, part.North, part.West, part.East)
+	}
+	query = query.Where(strings.Join(boxes, " OR "), args...)
+
+	if cover := models.GeohashCover(box); cover != nil {
+		prefixes := make([]string, len(cover))
+		args = make([]any, len(cover))
+		for i, prefix := range cover {
+			prefixes[i] = "restaurants.geohash LIKE ?"
+			args[i] = prefix + "%"
+		}
+		query = query.Where(strings.Join(prefixes, " OR "), args...)
+	}
+	return query
+}
+
+// validateCoordinates accepts no coordinates, or a latitude and longitude on Earth.
+func validateCoordinates(lat, lng *float64) error {
+	if lat == nil && lng == nil {
+		return nil
+	}
+	if lat == nil || lng == nil || !models.ValidCoordinates(*lat, *lng) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidCoordinates))
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+package services
+
+import (
+	notificationsv1 "api/src/generated/notifications/v1"
+	"api/src/generated/notifications/v1/v1connect"
+	"api/src/internal/models"
+	"api/src/internal/webpush"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationsService struct {
+	v1connect.UnimplementedNotificationsServiceHandler
+	DB     *gorm.DB
+	Valkey valkey.Client
+	// Nil when Web Push is not configured.
+	VAPID *webpush.VAPIDKeys
+}
+
+func NewNotificationsService(db *gorm.DB, kv valkey.Client, vapid *webpush.VAPIDKeys) *NotificationsService {
+	return &NotificationsService{DB: db, Valkey: kv, VAPID: vapid}
+}
+
+func (s *NotificationsService) ListNotifications(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.ListNotificationsRequest],
+) (*connect.Response[notificationsv1.ListNotificationsResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	page := int(req.Msg.Page)
+	pageSize := int(req.Msg.PageSize)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := s.DB.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+	if req.Msg.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var notifications []models.Notification
+	if err := query.Session(&gorm.Session{}).Order("updated_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	unread, err := countUnreadNotifications(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	actors, err := loadNotificationActors(ctx, s.DB, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	protos := make([]*notificationsv1.NotificationProto, len(notifications))
+	for i, n := range notifications {
+		protos[i] = n.ToProto(actors)
+	}
+
+	return connect.NewResponse(&notificationsv1.ListNotificationsResponse{
+		Notifications: protos,
+		Total:         int32(total),
+		UnreadCount:   unread,
+		Page:          int32(page),
+		PageSize:      int32(pageSize),
+	}), nil
+}
+
+func (s *NotificationsService) GetUnreadCount(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.GetUnreadCountRequest],
+) (*connect.Response[notificationsv1.GetUnreadCountResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := countUnreadNotifications(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.GetUnreadCountResponse{UnreadCount: unread}), nil
+}
+
+func (s *NotificationsService) MarkRead(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.MarkReadRequest],
+) (*connect.Response[notificationsv1.MarkReadResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := uniqueStrings(req.Msg.Ids)
+	if len(ids) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("ids is required"))
+	}
+
+	if err := s.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		UpdateColumn("read_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+
+	unread, err := countUnreadNotifications(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.MarkReadResponse{UnreadCount: unread}), nil
+}
+
+func (s *NotificationsService) MarkAllRead(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.MarkAllReadRequest],
+) (*connect.Response[notificationsv1.MarkAllReadResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		UpdateColumn("read_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.MarkAllReadResponse{UnreadCount: 0}), nil
+}
+
+func (s *NotificationsService) GetNotificationPreferences(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.GetNotificationPreferencesRequest],
+) (*connect.Response[notificationsv1.GetNotificationPreferencesResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := loadNotificationPreferences(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.GetNotificationPreferencesResponse{Preferences: notificationPreferenceProtos(prefs)}), nil
+}
+
+func (s *NotificationsService) UpdateNotificationPreferences(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.UpdateNotificationPreferencesRequest],
+) (*connect.Response[notificationsv1.UpdateNotificationPreferencesResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Msg.Preferences) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("preferences is required"))
+	}
+	rows := make([]models.NotificationPreference, 0, len(req.Msg.Preferences))
+	for _, p := range req.Msg.Preferences {
+		notificationType := models.NotificationTypeFromProto(p.Type)
+		if notificationType == "" {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidNotificationType))
+		}
+		rows = append(rows, models.NotificationPreference{
+			UserID: userID,
+			Type:   notificationType,
+			// The digest has no in-app or push form.
+			InApp: p.InApp && notificationType != models.NotificationTypeWeeklyDigest,
+			Email: p.Email,
+			Push:  p.Push && notificationType != models.NotificationTypeWeeklyDigest,
+		})
+	}
+
+	if err := saveNotificationPreferences(ctx, s.DB, rows); err != nil {
+		return nil, err
+	}
+
+	prefs, err := loadNotificationPreferences(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.UpdateNotificationPreferencesResponse{Preferences: notificationPreferenceProtos(prefs)}), nil
+}
+
+func (s *NotificationsService) GetPushConfig(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.GetPushConfigRequest],
+) (*connect.Response[notificationsv1.GetPushConfigResponse], error) {
+	if s.VAPID == nil {
+		return connect.NewResponse(&notificationsv1.GetPushConfigResponse{}), nil
+	}
+	return connect.NewResponse(&notificationsv1.GetPushConfigResponse{
+		Enabled:        true,
+		VapidPublicKey: s.VAPID.PublicKey,
+	}), nil
+}
+
+func (s *NotificationsService) RegisterPushSubscription(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.RegisterPushSubscriptionRequest],
+) (*connect.Response[notificationsv1.RegisterPushSubscriptionResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	if s.VAPID == nil {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New(errPushNotConfigured))
+	}
+	if err := ValidatePushEndpoint(req.Msg.Endpoint); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if err := webpush.ValidateSubscriptionKeys(req.Msg.P256Dh, req.Msg.Auth); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+
+	sub, err := savePushSubscription(ctx, s.DB, models.PushSubscription{
+		UserID:      userID,
+		Endpoint:    req.Msg.Endpoint,
+		P256dh:      req.Msg.P256Dh,
+		Auth:        req.Msg.Auth,
+		UserAgent:   req.Msg.UserAgent,
+		SessionHash: hashSessionToken(sessionToken(req.Header())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.RegisterPushSubscriptionResponse{Id: sub.ID}), nil
+}
+
+func (s *NotificationsService) UnregisterPushSubscription(
+	ctx context.Context,
+	req *connect.Request[notificationsv1.UnregisterPushSubscriptionRequest],
+) (*connect.Response[notificationsv1.UnregisterPushSubscriptionResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Endpoint == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errPushEndpointRequired))
+	}
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+
+	// Idempotent: unregistering an unknown endpoint is not an error.
+	if err := s.DB.WithContext(ctx).Where("user_id = ? AND endpoint = ?", userID, req.Msg.Endpoint).
+		Delete(&models.PushSubscription{}).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&notificationsv1.UnregisterPushSubscriptionResponse{}), nil
+}
+
+func countUnreadNotifications(ctx context.Context, db *gorm.DB, userID string) (int32, error) {
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int32(count), nil
+}
+
+// loadNotificationActors returns the users referenced by the given notifications, keyed by ID.
+func loadNotificationActors(ctx context.Context, db *gorm.DB, notifications []models.Notification) (map[string]models.User, error) {
+	var ids []string
+	for _, n := range notifications {
+		ids = append(ids, n.ActorIDs...)
+	}
+	ids = uniqueStrings(ids)
+	out := make(map[string]models.User, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	var users []models.User
+	if err := db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		out[u.ID] = u
+	}
+	return out, nil
+}
+
+// loadNotificationPreferences returns a user's preference for every notification type,
+// filling in DefaultNotificationPreference for types they never changed.
+func loadNotificationPreferences(ctx context.Context, db *gorm.DB, userID string) (map[string]models.NotificationPreference, error) {
+	var rows []models.NotificationPreference
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]models.NotificationPreference, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		out[t] = models.DefaultNotificationPreference(userID, t)
+	}
+	for _, row := range rows {
+		out[row.Type] = row
+	}
+	return out, nil
+}
+
+// saveNotificationPreferences upserts preference rows, replacing every channel.
+func saveNotificationPreferences(ctx context.Context, db *gorm.DB, rows []models.NotificationPreference) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	// Select every column so a false Email or Push isn't dropped in favour of its DB default.
+	return db.WithContext(ctx).Select("user_id", "type", "in_app", "email", "push", "updated_at").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "push", "updated_at"}),
+		}).Create(&rows).Error
+}
+
+func notificationPreferenceProtos(prefs map[string]models.NotificationPreference) []*notificationsv1.NotificationPreferenceProto {
+	protos := make([]*notificationsv1.NotificationPreferenceProto, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		p := prefs[t]
+		protos = append(protos, p.ToProto())
+	}
+	return protos
+}
+
+// notificationEvent is something that happened which UserID should be told about.
+type notificationEvent struct {
+	UserID      string
+	Type        string
+	ActorID     string
+	SubjectID   string
+	SubjectName string
+}
+
+// notify records events in their recipients' inboxes and queues an email and push
+// messages for each new one. An event is merged into the recipient's unread notification
+// for the same type and subject if there is one, so repeats don't pile up or send more
+// email or pushes. Failures are logged, not returned: a notification must never fail the
+// write that produced it.
+func notify(ctx context.Context, db *gorm.DB, events ...notificationEvent) {
+	for _, e := range events {
+		if err := recordNotification(ctx, db, e); err != nil {
+			slog.Warn("Failed to record notification",
+				slog.String("type", e.Type), slog.String("user_id", e.UserID), slog.Any("error", err))
+		}
+	}
+}
+
+func recordNotification(ctx context.Context, db *gorm.DB, e notificationEvent) error {
+	var pref models.NotificationPreference
+	err := db.WithContext(ctx).Where("user_id = ? AND type = ?", e.UserID, e.Type).First(&pref).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		pref = models.DefaultNotificationPreference(e.UserID, e.Type)
+	case err != nil:
+		return err
+	}
+	if !pref.InApp && !pref.Email && !pref.Push {
+		return nil
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		isNew := true
+		if pref.InApp {
+			var err error
+			if isNew, err = upsertNotification(tx, e); err != nil {
+				return err
+			}
+		}
+		if !isNew {
+			return nil
+		}
+		params := map[string]string{
+			emailParamActorID:     e.ActorID,
+			emailParamSubjectID:   e.SubjectID,
+			emailParamSubjectName: e.SubjectName,
+		}
+		if pref.Email {
+			if err := enqueueEmail(tx, e.UserID, e.Type, params, nil); err != nil {
+				return err
+			}
+		}
+		if pref.Push {
+			return enqueuePush(tx, e.UserID, e.Type, params)
+		}
+		return nil
+	})
+}
+
+// upsertNotification coalesces e into the recipient's unread notification for the same
+// subject, or creates one. It reports whether a new notification was created.
+func upsertNotification(tx *gorm.DB, e notificationEvent) (bool, error) {
+	var existing models.Notification
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND type = ? AND subject_id = ? AND read_at IS NULL", e.UserID, e.Type, e.SubjectID).
+		Order("updated_at DESC").First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		n := models.Notification{
+			UserID:      e.UserID,
+			Type:        e.Type,
+			SubjectID:   e.SubjectID,
+			SubjectName: e.SubjectName,
+		}
+		n.AddActor(e.ActorID)
+		return true, tx.Create(&n).Error
+	}
+	if err != nil {
+		return false, err
+	}
+	existing.AddActor(e.ActorID)
+	if e.SubjectName != "" {
+		existing.SubjectName = e.SubjectName
+	}
+	// Save bumps updated_at, moving the notification back to the top of the inbox.
+	return false, tx.Save(&existing).Error
+}
+
+// wishlistReviewRecipients returns the friends of the review's author who can see the
+// review and have its restaurant on their wishlist.
+func wishlistReviewRecipients(ctx context.Context, db *gorm.DB, review *models.Review) ([]string, error) {
+	if review.Visibility == models.VisibilityPrivate {
+		return nil, nil
+	}
+	friendIDs, err := getFriendIDs(ctx, db, review.UserID)
+	if err != nil || len(friendIDs) == 0 {
+		return nil, err
+	}
+
+	query := db.WithContext(ctx).Model(&models.WishlistItem{}).
+		Where("restaurant_id = ? AND user_id IN ?", review.RestaurantID, friendIDs)
+	if review.Visibility == models.VisibilityCircles {
+		query = query.Where(
+			"user_id IN (SELECT cm.member_id FROM circle_members cm JOIN review_circles rc ON rc.circle_id = cm.circle_id WHERE rc.review_id = ?)",
+			review.ID,
+		)
+	}
+
+	var ids []string
+	if err := query.Distinct().Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,650 @@
+package services
+
+import (
+	"api/src/internal/mappers"
+	"api/src/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/geo/type/viewport"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/genproto/googleapis/type/localized_text"
+	"google.golang.org/genproto/googleapis/type/postaladdress"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultNominatimURL = "https://nominatim.openstreetmap.org"
+	defaultOverpassURL  = "https://overpass-api.de/api/interpreter"
+	// How far from a stored restaurant an OSM element may be to be linked to it.
+	osmMatchRadiusMeters = 100
+	// Nominatim returns at most 40 results.
+	nominatimMaxLimit = 40
+)
+
+// OSMConfig configures the OpenStreetMap places provider.
+type OSMConfig struct {
+	// Base URL of a Nominatim instance; searches go to /search.
+	NominatimURL string
+	// An Overpass API interpreter endpoint.
+	OverpassURL string
+	// Sent with every request; Nominatim's usage policy requires one that identifies the app.
+	UserAgent string
+	// Nominatim requests per second, for the whole process. Defaults to 1, which is also
+	// the most the public instance allows.
+	NominatimRate float64
+}
+
+// OSMConfigFromEnv reads NOMINATIM_URL, OVERPASS_URL, OSM_USER_AGENT and
+// NOMINATIM_REQUESTS_PER_SECOND, defaulting to the public instances.
+func OSMConfigFromEnv() OSMConfig {
+	cfg := OSMConfig{
+		NominatimURL: strings.TrimSuffix(os.Getenv("NOMINATIM_URL"), "/"),
+		OverpassURL:  os.Getenv("OVERPASS_URL"),
+		UserAgent:    os.Getenv("OSM_USER_AGENT"),
+	}
+	if v := os.Getenv("NOMINATIM_REQUESTS_PER_SECOND"); v != "" {
+		perSecond, err := strconv.ParseFloat(v, 64)
+		if err != nil || perSecond <= 0 {
+			slog.Warn("NOMINATIM_REQUESTS_PER_SECOND must be a positive number; using 1", slog.String("value", v))
+		} else {
+			cfg.NominatimRate = perSecond
+		}
+	}
+	if cfg.NominatimURL == "" {
+		cfg.NominatimURL = defaultNominatimURL
+		slog.Warn("NOMINATIM_URL is not set; the public instance allows one request per second and no autocompletion")
+	}
+	if cfg.OverpassURL == "" {
+		cfg.OverpassURL = defaultOverpassURL
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "resto-rate-api/1.0"
+	}
+	return cfg
+}
+
+// PlaceLinkStore keeps the OSM counterparts of Google places. NewPlaceLinkStore keeps them
+// in the database.
+type PlaceLinkStore interface {
+	// OSMName returns the OSM place linked to googleName, or "" when there is none.
+	OSMName(ctx context.Context, googleName string) (string, error)
+	// Restaurant returns the stored restaurant with googleName, or nil.
+	Restaurant(ctx context.Context, googleName string) (*models.Restaurant, error)
+	Link(ctx context.Context, googleName, osmName string) error
+}
+
+type dbPlaceLinks struct {
+	db *gorm.DB
+}
+
+func NewPlaceLinkStore(db *gorm.DB) PlaceLinkStore {
+	return dbPlaceLinks{db: db}
+}
+
+func (s dbPlaceLinks) OSMName(ctx context.Context, googleName string) (string, error) {
+	var link models.PlaceLink
+	if err := s.db.WithContext(ctx).Where("google_name = ?", googleName).Limit(1).Find(&link).Error; err != nil {
+		return "", err
+	}
+	return link.OSMName, nil
+}
+
+func (s dbPlaceLinks) Restaurant(ctx context.Context, googleName string) (*models.Restaurant, error) {
+	var restaurant models.Restaurant
+	if err := s.db.WithContext(ctx).Where("google_id = ?", googleName).Limit(1).Find(&restaurant).Error; err != nil {
+		return nil, err
+	}
+	if restaurant.ID == "" {
+		return nil, nil
+	}
+	return &restaurant, nil
+}
+
+func (s dbPlaceLinks) Link(ctx context.Context, googleName, osmName string) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.PlaceLink{GoogleName: googleName, OSMName: osmName}).Error
+}
+
+// OSMPlacesProvider is a PlacesProvider backed by OpenStreetMap: Nominatim for text search
+// and autocompletion, Overpass for place details. Its places are named
+// "places/osm-<type>-<id>"; Google place names resolve through Links, so restaurants
+// stored under them keep working. OSM has no ratings or photos, so those stay empty, and
+// field masks are ignored.
+type OSMPlacesProvider struct {
+	Config OSMConfig
+	Links  PlaceLinkStore
+	HTTP   *http.Client
+	// Paces Nominatim requests; shared with every provider using the same instance.
+	nominatimLimiter *rate.Limiter
+}
+
+func NewOSMPlacesProvider(cfg OSMConfig, links PlaceLinkStore) *OSMPlacesProvider {
+	if cfg.NominatimRate <= 0 || (cfg.NominatimURL == defaultNominatimURL && cfg.NominatimRate > 1) {
+		cfg.NominatimRate = 1
+	}
+	return &OSMPlacesProvider{
+		Config:           cfg,
+		Links:            links,
+		HTTP:             &http.Client{Timeout: 15 * time.Second},
+		nominatimLimiter: nominatimLimiter(cfg.NominatimURL, cfg.NominatimRate),
+	}
+}
+
+// nominatimLimiters holds a limiter per Nominatim base URL, so that the process as a whole
+// keeps to an instance's request rate.
+var nominatimLimiters sync.Map
+
+func nominatimLimiter(baseURL string, perSecond float64) *rate.Limiter {
+	limiter, _ := nominatimLimiters.LoadOrStore(baseURL, rate.NewLimiter(rate.Limit(perSecond), 1))
+	return limiter.(*rate.Limiter)
+}
+
+// OSMPlaceName returns the Places resource name of an OSM element.
+func OSMPlaceName(osmType string, id int64) string {
+	return fmt.Sprintf("places/osm-%s-%d", osmType, id)
+}
+
+// ParseOSMPlaceName returns the element an OSMPlaceName names.
+func ParseOSMPlaceName(name string) (osmType string, id int64, ok bool) {
+	rest, found := strings.CutPrefix(name, "places/osm-")
+	if !found {
+		return "", 0, false
+	}
+	osmType, num, _ := strings.Cut(rest, "-")
+	if osmType != "node" && osmType != "way" && osmType != "relation" {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return osmType, id, true
+}
+
+func (p *OSMPlacesProvider) SearchText(ctx context.Context, req *placespb.SearchTextRequest, _ []string) (*placespb.SearchTextResponse, error) {
+	limit := int(req.GetMaxResultCount())
+	if limit <= 0 || limit > 20 {
+		limit = 20
+	}
+	viewbox, bounded := nominatimViewbox(req)
+	elements, err := p.nominatim(ctx, req.TextQuery, req.LanguageCode, []string{req.RegionCode}, viewbox, bounded)
+	if err != nil {
+		return nil, err
+	}
+	resp := &placespb.SearchTextResponse{}
+	for _, e := range elements {
+		place := e.place(OSMPlaceName(e.Type, e.ID))
+		if req.IncludedType != "" && !slices.Contains(place.Types, req.IncludedType) {
+			continue
+		}
+		resp.Places = append(resp.Places, place)
+		if len(resp.Places) == limit {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// AutocompletePlaces needs a self-hosted Nominatim: the public instance's usage policy
+// forbids autocompletion, so it is refused with FailedPrecondition there.
+func (p *OSMPlacesProvider) AutocompletePlaces(ctx context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error) {
+	if p.Config.NominatimURL == defaultNominatimURL {
+		return nil, status.Error(codes.FailedPrecondition, "nominatim: autocompletion needs a self-hosted instance (NOMINATIM_URL)")
+	}
+	elements, err := p.nominatim(ctx, req.Input, req.LanguageCode, req.IncludedRegionCodes, "", false)
+	if err != nil {
+		return nil, err
+	}
+	resp := &placespb.AutocompletePlacesResponse{}
+	for _, e := range elements {
+		name := OSMPlaceName(e.Type, e.ID)
+		place := e.place(name)
+		if len(req.IncludedPrimaryTypes) > 0 && !slices.ContainsFunc(req.IncludedPrimaryTypes, func(t string) bool {
+			return slices.Contains(place.Types, t)
+		}) {
+			continue
+		}
+		main := formattableText(place.GetDisplayName().GetText(), req.Input)
+		secondary := formattableText(place.ShortFormattedAddress, "")
+		text := formattableText(strings.Join(nonEmpty(main.Text, secondary.Text), ", "), req.Input)
+		resp.Suggestions = append(resp.Suggestions, &placespb.AutocompletePlacesResponse_Suggestion{
+			Kind: &placespb.AutocompletePlacesResponse_Suggestion_PlacePrediction_{
+				PlacePrediction: &placespb.AutocompletePlacesResponse_Suggestion_PlacePrediction{
+					Place:   name,
+					PlaceId: place.Id,
+					Text:    text,
+					StructuredFormat: &placespb.AutocompletePlacesResponse_Suggestion_StructuredFormat{
+						MainText:      main,
+						SecondaryText: secondary,
+					},
+					Types: place.Types,
+				},
+			},
+		})
+		if len(resp.Suggestions) == 5 {
+			break
+		}
+	}
+	return resp, nil
+}
+
+func (p *OSMPlacesProvider) GetPlace(ctx context.Context, req *placespb.GetPlaceRequest, _ []string) (*placespb.Place, error) {
+	osmName := req.Name
+	if _, _, ok := ParseOSMPlaceName(osmName); !ok {
+		var err error
+		if osmName, err = p.linkedOSMName(ctx, req.Name); err != nil {
+			return nil, err
+		}
+	}
+	osmType, id, _ := ParseOSMPlaceName(osmName)
+	elements, err := p.overpass(ctx, fmt.Sprintf("%s(%d);out center tags;", osmType, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 {
+		return nil, status.Errorf(codes.NotFound, "%s not found in OpenStreetMap", osmName)
+	}
+	// The place keeps the name it was asked for, so a Google name stays the key of its row.
+	return elements[0].place(req.Name), nil
+}
+
+// linkedOSMName returns the OSM place linked to a Google place. The first time, it links
+// the named OSM element nearest the stored restaurant whose name matches.
+func (p *OSMPlacesProvider) linkedOSMName(ctx context.Context, googleName string) (string, error) {
+	notFound := status.Errorf(codes.NotFound, "%s has no OpenStreetMap counterpart", googleName)
+	if p.Links == nil {
+		return "", notFound
+	}
+	if osmName, err := p.Links.OSMName(ctx, googleName); err != nil || osmName != "" {
+		return osmName, err
+	}
+	restaurant, err := p.Links.Restaurant(ctx, googleName)
+	if err != nil {
+		return "", err
+	}
+	if restaurant == nil || !restaurant.HasLocation() {
+		return "", notFound
+	}
+	elements, err := p.overpass(ctx, fmt.Sprintf(`nwr(around:%d,%f,%f)["name"];out center tags;`,
+		osmMatchRadiusMeters, *restaurant.Latitude, *restaurant.Longitude))
+	if err != nil {
+		return "", err
+	}
+	match := matchOSMElement(restaurant, elements)
+	if match == nil {
+		return "", notFound
+	}
+	osmName := OSMPlaceName(match.Type, match.ID)
+	if err := p.Links.Link(ctx, googleName, osmName); err != nil {
+		return "", err
+	}
+	return osmName, nil
+}
+
+// matchOSMElement returns the element nearest restaurant whose name matches its name, or
+// nil.
+func matchOSMElement(restaurant *models.Restaurant, elements []osmElement) *osmElement {
+	want := normalizePlaceName(restaurant.Name)
+	var best *osmElement
+	bestDistance := math.Inf(1)
+	for i := range elements {
+		e := &elements[i]
+		got := normalizePlaceName(e.Tags["name"])
+		if want == "" || got == "" || (!strings.Contains(got, want) && !strings.Contains(want, got)) {
+			continue
+		}
+		if d := models.DistanceMeters(*restaurant.Latitude, *restaurant.Longitude, e.Lat, e.Lon); d < bestDistance {
+			best, bestDistance = e, d
+		}
+	}
+	return best
+}
+
+// normalizePlaceName lowercases name and keeps only its letters and digits, one space apart.
+func normalizePlaceName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// osmElement is a node, way or relation from either API.
+type osmElement struct {
+	Type     string
+	ID       int64
+	Lat, Lon float64
+	Tags     map[string]string
+	Address  osmAddress
+}
+
+type osmAddress struct {
+	HouseNumber, Road, City, Postcode, Country, CountryCode string
+}
+
+// osmPrimaryTypes maps OSM amenities to Google place types.
+var osmPrimaryTypes = map[string]string{
+	"restaurant": "restaurant",
+	"fast_food":  "fast_food_restaurant",
+	"food_court": "food_court",
+	"cafe":       "cafe",
+	"bar":        "bar",
+	"pub":        "pub",
+	"biergarten": "bar",
+	"ice_cream":  "ice_cream_shop",
+}
+
+// place maps the element to a Places API place named name.
+func (e osmElement) place(name string) *placespb.Place {
+	tags := e.Tags
+	amenity := tags["amenity"]
+	place := &placespb.Place{
+		Name:                     name,
+		Id:                       strings.TrimPrefix(name, "places/"),
+		DisplayName:              &localized_text.LocalizedText{Text: tags["name"]},
+		FormattedAddress:         e.Address.formatted(),
+		ShortFormattedAddress:    strings.Join(nonEmpty(e.Address.street(), e.Address.City), ", "),
+		Location:                 &latlng.LatLng{Latitude: e.Lat, Longitude: e.Lon},
+		Types:                    osmTypes(amenity, tags["cuisine"]),
+		PrimaryType:              osmPrimaryTypes[amenity],
+		WebsiteUri:               firstNonEmpty(tags["website"], tags["contact:website"]),
+		InternationalPhoneNumber: firstNonEmpty(tags["phone"], tags["contact:phone"]),
+		Takeout:                  osmBool(tags["takeaway"]),
+		Delivery:                 osmBool(tags["delivery"]),
+		OutdoorSeating:           osmBool(tags["outdoor_seating"]),
+		ServesVegetarianFood:     osmBool(tags["diet:vegetarian"]),
+		AllowsDogs:               osmBool(tags["dog"]),
+		Reservable:               osmBool(tags["reservation"]),
+		Restroom:                 osmBool(tags["toilets"]),
+	}
+	switch {
+	case amenity != "":
+		place.BusinessStatus = placespb.Place_OPERATIONAL
+	case tags["disused:amenity"] != "" || tags["was:amenity"] != "":
+		place.BusinessStatus = placespb.Place_CLOSED_PERMANENTLY
+	}
+	if a := e.Address; a.City != "" || a.CountryCode != "" {
+		place.PostalAddress = &postaladdress.PostalAddress{
+			RegionCode:   strings.ToUpper(a.CountryCode),
+			PostalCode:   a.Postcode,
+			Locality:     a.City,
+			AddressLines: nonEmpty(a.street()),
+		}
+	}
+	return place
+}
+
+// osmTypes returns the Google place types of an amenity, most specific first.
+func osmTypes(amenity, cuisine string) []string {
+	primary, ok := osmPrimaryTypes[amenity]
+	if !ok {
+		return nil
+	}
+	var types []string
+	if c, _, _ := strings.Cut(cuisine, ";"); c != "" && primary == "restaurant" {
+		types = append(types, strings.ToLower(strings.TrimSpace(c))+"_restaurant")
+	}
+	types = append(types, primary)
+	if primary == "fast_food_restaurant" || primary == "food_court" {
+		types = append(types, "restaurant")
+	}
+	return append(types, "food", "point_of_interest", "establishment")
+}
+
+func (a osmAddress) street() string {
+	return strings.TrimSpace(a.Road + " " + a.HouseNumber)
+}
+
+func (a osmAddress) formatted() string {
+	country := a.Country
+	if country == "" && a.CountryCode != "" {
+		country = mappers.CountryName(a.CountryCode)
+	}
+	return strings.Join(nonEmpty(a.street(), strings.TrimSpace(a.Postcode+" "+a.City), country), ", ")
+}
+
+// osmBool maps a yes/no tag; "only", "required" and the like count as yes.
+func osmBool(v string) *bool {
+	var b bool
+	switch v {
+	case "":
+		return nil
+	case "no":
+		b = false
+	default:
+		b = true
+	}
+	return &b
+}
+
+// formattableText returns text with its leading match of input, ignoring case, marked.
+func formattableText(text, input string) *placespb.AutocompletePlacesResponse_Suggestion_FormattableText {
+	ft := &placespb.AutocompletePlacesResponse_Suggestion_FormattableText{Text: text}
+	if input != "" && strings.HasPrefix(strings.ToLower(text), strings.ToLower(input)) {
+		ft.Matches = []*placespb.AutocompletePlacesResponse_Suggestion_StringRange{
+			{EndOffset: int32(utf8.RuneCountInString(input))},
+		}
+	}
+	return ft
+}
+
+func nonEmpty(values ...string) []string {
+	out := values[:0:0]
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type nominatimResult struct {
+	OSMType   string            `json:"osm_type"`
+	OSMID     int64             `json:"osm_id"`
+	Lat       string            `json:"lat"`
+	Lon       string            `json:"lon"`
+	Category  string            `json:"category"`
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Address   map[string]string `json:"address"`
+	ExtraTags map[string]string `json:"extratags"`
+}
+
+// nominatimViewbox returns the Nominatim viewbox of a text search's location restriction or
+// bias, and whether results must lie inside it.
+func nominatimViewbox(req *placespb.SearchTextRequest) (string, bool) {
+	box := func(v *viewport.Viewport) string {
+		return fmt.Sprintf("%g,%g,%g,%g", v.GetLow().GetLongitude(), v.GetLow().GetLatitude(), v.GetHigh().GetLongitude(), v.GetHigh().GetLatitude())
+	}
+	if rect := req.GetLocationRestriction().GetRectangle(); rect != nil {
+		return box(rect), true
+	}
+	if rect := req.GetLocationBias().GetRectangle(); rect != nil {
+		return box(rect), false
+	}
+	if c := req.GetLocationBias().GetCircle(); c != nil {
+		lat, lng := c.GetCenter().GetLatitude(), c.GetCenter().GetLongitude()
+		dLat := c.Radius / 111320
+		dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+		return fmt.Sprintf("%g,%g,%g,%g", lng-dLng, lat-dLat, lng+dLng, lat+dLat), false
+	}
+	return "", false
+}
+
+// nominatim runs a Nominatim search for query, preferring or, when bounded, limited to
+// results inside viewbox.
+func (p *OSMPlacesProvider) nominatim(ctx context.Context, query, language string, regions []string, viewbox string, bounded bool) ([]osmElement, error) {
+	params := url.Values{
+		"q":              {query},
+		"format":         {"jsonv2"},
+		"addressdetails": {"1"},
+		"extratags":      {"1"},
+		"limit":          {strconv.Itoa(nominatimMaxLimit)},
+	}
+	if language != "" {
+		params.Set("accept-language", language)
+	}
+	if codes := strings.ToLower(strings.Join(nonEmpty(regions...), ",")); codes != "" {
+		params.Set("countrycodes", codes)
+	}
+	if viewbox != "" {
+		params.Set("viewbox", viewbox)
+		if bounded {
+			params.Set("bounded", "1")
+		}
+	}
+	if err := p.waitForNominatim(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Config.NominatimURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var results []nominatimResult
+	if err := p.doJSON(req, "nominatim", &results); err != nil {
+		return nil, err
+	}
+
+	elements := make([]osmElement, 0, len(results))
+	for _, r := range results {
+		lat, errLat := strconv.ParseFloat(r.Lat, 64)
+		lon, errLon := strconv.ParseFloat(r.Lon, 64)
+		if errLat != nil || errLon != nil || r.OSMID == 0 {
+			continue
+		}
+		tags := map[string]string{"name": r.Name}
+		for k, v := range r.ExtraTags {
+			tags[k] = v
+		}
+		if r.Category == "amenity" {
+			tags["amenity"] = r.Type
+		}
+		a := r.Address
+		elements = append(elements, osmElement{
+			Type: r.OSMType, ID: r.OSMID, Lat: lat, Lon: lon, Tags: tags,
+			Address: osmAddress{
+				HouseNumber: a["house_number"],
+				Road:        firstNonEmpty(a["road"], a["pedestrian"], a["square"]),
+				City:        firstNonEmpty(a["city"], a["town"], a["village"], a["hamlet"]),
+				Postcode:    a["postcode"],
+				Country:     a["country"],
+				CountryCode: a["country_code"],
+			},
+		})
+	}
+	return elements, nil
+}
+
+// waitForNominatim blocks until the rate limit allows another Nominatim request. A request
+// that could not start before ctx's deadline fails with ResourceExhausted at once.
+func (p *OSMPlacesProvider) waitForNominatim(ctx context.Context) error {
+	limiter := p.nominatimLimiter
+	if limiter == nil {
+		limiter = nominatimLimiter(p.Config.NominatimURL, 1)
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Errorf(codes.ResourceExhausted, "nominatim: rate limited: %v", err)
+	}
+	return nil
+}
+
+type overpassResponse struct {
+	Elements []struct {
+		Type   string                      `json:"type"`
+		ID     int64                       `json:"id"`
+		Lat    float64                     `json:"lat"`
+		Lon    float64                     `json:"lon"`
+		Center *struct{ Lat, Lon float64 } `json:"center"`
+		Tags   map[string]string           `json:"tags"`
+	} `json:"elements"`
+}
+
+// overpass runs an Overpass QL query with JSON output.
+func (p *OSMPlacesProvider) overpass(ctx context.Context, query string) ([]osmElement, error) {
+	form := url.Values{"data": {"[out:json][timeout:25];" + query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Config.OverpassURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp overpassResponse
+	if err := p.doJSON(req, "overpass", &resp); err != nil {
+		return nil, err
+	}
+
+	elements := make([]osmElement, 0, len(resp.Elements))
+	for _, r := range resp.Elements {
+		e := osmElement{Type: r.Type, ID: r.ID, Lat: r.Lat, Lon: r.Lon, Tags: r.Tags}
+		if r.Center != nil {
+			e.Lat, e.Lon = r.Center.Lat, r.Center.Lon
+		}
+		if e.Tags == nil {
+			e.Tags = map[string]string{}
+		}
+		e.Address = osmAddress{
+			HouseNumber: e.Tags["addr:housenumber"],
+			Road:        firstNonEmpty(e.Tags["addr:street"], e.Tags["addr:place"]),
+			City:        e.Tags["addr:city"],
+			Postcode:    e.Tags["addr:postcode"],
+			CountryCode: e.Tags["addr:country"],
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
+
+// doJSON sends req and decodes its JSON response into dst. Failures come back as gRPC
+// statuses, like the Google client's.
+func (p *OSMPlacesProvider) doJSON(req *http.Request, api string, dst any) error {
+	req.Header.Set("User-Agent", p.Config.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return status.FromContextError(req.Context().Err()).Err()
+		}
+		return status.Errorf(codes.Unavailable, "%s: %v", api, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return status.Errorf(codes.ResourceExhausted, "%s: %s", api, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return status.Errorf(codes.Unavailable, "%s: %s", api, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return status.Errorf(codes.Internal, "%s: decode response: %v", api, err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	tagsv1 "api/src/generated/tags/v1"
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"strings"
+
+	"connectrpc.com/connect"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *TagsService) ListPersonalTags(
+	ctx context.Context,
+	req *connect.Request[tagsv1.ListPersonalTagsRequest],
+) (*connect.Response[tagsv1.ListPersonalTagsResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []models.PersonalTag
+	if err := s.DB.WithContext(ctx).Where("owner_id = ?", userID).Order("slug").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	protos := make([]*tagsv1.PersonalTagProto, len(tags))
+	for i := range tags {
+		protos[i] = tags[i].ToProto()
+	}
+	return connect.NewResponse(&tagsv1.ListPersonalTagsResponse{Tags: protos}), nil
+}
+
+func (s *TagsService) CreatePersonalTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.CreatePersonalTagRequest],
+) (*connect.Response[tagsv1.CreatePersonalTagResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+	label := strings.TrimSpace(req.Msg.Label)
+	slug := models.PersonalTagSlug(label)
+	if slug == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errPersonalTagLabelRequired))
+	}
+
+	// A personal tag shadowing a global one would make tag filters ambiguous.
+	var globals int64
+	if err := s.DB.WithContext(ctx).Model(&models.Tag{}).Where("slug = ?", slug).Count(&globals).Error; err != nil {
+		return nil, err
+	}
+	if globals > 0 {
+		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New(errPersonalTagIsGlobal))
+	}
+
+	tag := models.PersonalTag{OwnerID: userID, Slug: slug, Label: label}
+	res := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New(errPersonalTagExists))
+	}
+	return connect.NewResponse(&tagsv1.CreatePersonalTagResponse{Tag: tag.ToProto()}), nil
+}
+
+func (s *TagsService) DeletePersonalTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.DeletePersonalTagRequest],
+) (*connect.Response[tagsv1.DeletePersonalTagResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tag models.PersonalTag
+		if err := tx.First(&tag, "owner_id = ? AND slug = ?", userID, req.Msg.Slug).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return connect.NewError(connect.CodeNotFound, errors.New(errPersonalTagNotFound))
+			}
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_personal_tags WHERE personal_tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM wishlist_item_personal_tags WHERE personal_tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	return connect.NewResponse(&tagsv1.DeletePersonalTagResponse{}), nil
+}
+
+func (s *TagsService) ListPopularPersonalTags(
+	ctx context.Context,
+	req *connect.Request[tagsv1.ListPopularPersonalTagsRequest],
+) (*connect.Response[tagsv1.ListPopularPersonalTagsResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	limit := int(req.Msg.Limit)
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	// Slugs are unique per owner, so each personal tag row is one owner.
+	var rows []struct {
+		Slug       string
+		Label      string
+		OwnerCount int32
+		UseCount   int32
+	}
+	if err := s.DB.WithContext(ctx).Table("personal_tags pt").
+		Select("pt.slug, mode() WITHIN GROUP (ORDER BY pt.label) AS label, COUNT(*) AS owner_count, " +
+			"SUM((SELECT COUNT(*) FROM review_personal_tags rp WHERE rp.personal_tag_id = pt.id) + " +
+			"(SELECT COUNT(*) FROM wishlist_item_personal_tags wp WHERE wp.personal_tag_id = pt.id)) AS use_count").
+		Group("pt.slug").
+		Order("owner_count DESC, use_count DESC, pt.slug").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make([]*tagsv1.PopularPersonalTag, len(rows))
+	for i, r := range rows {
+		tags[i] = &tagsv1.PopularPersonalTag{Slug: r.Slug, Label: r.Label, OwnerCount: r.OwnerCount, UseCount: r.UseCount}
+	}
+	return connect.NewResponse(&tagsv1.ListPopularPersonalTagsResponse{Tags: tags}), nil
+}
+
+func (s *TagsService) PromotePersonalTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.PromotePersonalTagRequest],
+) (*connect.Response[tagsv1.PromotePersonalTagResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	slug := strings.TrimSpace(req.Msg.Slug)
+
+	var tag models.Tag
+	var reviews, wishlistItems int
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var personalIDs []string
+		if err := tx.Model(&models.PersonalTag{}).Where("slug = ?", slug).Pluck("id", &personalIDs).Error; err != nil {
+			return err
+		}
+		if len(personalIDs) == 0 {
+			return connect.NewError(connect.CodeNotFound, errors.New(errPersonalTagNotFound))
+		}
+
+		err := tx.First(&tag, "slug = ?", slug).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag = models.Tag{
+				Slug:     slug,
+				Label:    strings.TrimSpace(req.Msg.Label),
+				Category: strings.TrimSpace(req.Msg.Category),
+			}
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+			err = tx.Omit(clause.Associations).Create(&tag).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if reviews, err = moveTagRows(tx, "review_personal_tags", "personal_tag_id", "review_tags", "review_id", personalIDs, tag.ID); err != nil {
+			return err
+		}
+		if wishlistItems, err = moveTagRows(tx, "wishlist_item_personal_tags", "personal_tag_id", "wishlist_item_tags", "wishlist_item_id", personalIDs, tag.ID); err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", personalIDs).Delete(&models.PersonalTag{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := invalidateTagsCache(ctx, s.Valkey); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&tagsv1.PromotePersonalTagResponse{
+		Tag:                  tag.ToProto(),
+		ReviewsUpdated:       int32(reviews),
+		WishlistItemsUpdated: int32(wishlistItems),
+	}), nil
+}
+
+// resolvePersonalTags returns ownerID's personal tags for slugs, ignoring blanks and
+// duplicates. Slugs the owner has no personal tag for are rejected.
+func resolvePersonalTags(ctx context.Context, db *gorm.DB, ownerID string, slugs []string) ([]models.PersonalTag, error) {
+	unique := uniqueStrings(slugs)
+	tags := []models.PersonalTag{}
+	if len(unique) == 0 {
+		return tags, nil
+	}
+	if err := db.WithContext(ctx).Where("owner_id = ? AND slug IN ?", ownerID, unique).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(unique) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errPersonalTagNotFound))
+	}
+	return tags, nil
+}
+
+// sharesPersonalTags returns a SQL condition that is true when the user in ownerColumn
+// lets friends see their personal tags. Users without privacy settings don't.
+func sharesPersonalTags(ownerColumn string) string {
+	return "EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = " + ownerColumn + " AND ps.share_personal_tags)"
+}
+
+// preloadPersonalTags preloads the personal tags of reviews or wishlist items that viewerID
+// may see: their own, and a friend's only when the friend shares them.
+func preloadPersonalTags(query *gorm.DB, viewerID string) *gorm.DB {
+	return query.Preload("PersonalTags", "personal_tags.owner_id = ? OR "+sharesPersonalTags("personal_tags.owner_id"), viewerID)
+}
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"api/src/internal/cache"
+	"api/src/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+const (
+	placesAPIBaseURL  = "https://places.googleapis.com/v1/"
+	defaultPhotoWidth = 800
+	// Consecutive Google failures that open the photo circuit, and how long it stays open.
+	photoBreakerThreshold = 5
+	photoBreakerCooldown  = 30 * time.Second
+	// Client rate limiters idle this long are dropped; their buckets are full again by then.
+	clientLimiterIdle = 10 * time.Minute
+)
+
+// PlacePhotoSizes are the widths and heights, in pixels, clients may ask for. A short list
+// keeps the photo URI cache small.
+var PlacePhotoSizes = []int{100, 200, 400, 800, 1600}
+
+// placePhotoName matches Places photo resource names, places/{place}/photos/{photo}.
+var placePhotoName = regexp.MustCompile(`^places/[A-Za-z0-9_-]+/photos/[A-Za-z0-9_-]+$`)
+
+type PlacePhotoConfig struct {
+	// Places API root the media requests go to.
+	BaseURL string
+	// How long a resolved photo URI is cached, here and by browsers.
+	CacheTTL time.Duration
+	// Deadline of one Google call.
+	Timeout time.Duration
+	// Requests per minute one client may make; 0 disables the limit.
+	RateLimit int
+	// Identify clients by the last X-Forwarded-For address rather than the connection's.
+	// Only safe behind a proxy that appends it.
+	TrustForwardedFor bool
+}
+
+// PlacePhotoConfigFromEnv reads PLACE_PHOTO_CACHE_TTL (default 1h), PLACE_PHOTO_TIMEOUT
+// (default 5s), PLACE_PHOTO_RATE_LIMIT (per client per minute, default 120) and
+// PLACE_PHOTO_TRUST_FORWARDED_FOR.
+func PlacePhotoConfigFromEnv() (PlacePhotoConfig, error) {
+	cfg := PlacePhotoConfig{BaseURL: placesAPIBaseURL, CacheTTL: time.Hour, Timeout: 5 * time.Second, RateLimit: 120}
+	if v := os.Getenv("PLACE_PHOTO_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			return cfg, fmt.Errorf("PLACE_PHOTO_CACHE_TTL must be a positive duration, got %q", v)
+		}
+		cfg.CacheTTL = ttl
+	}
+	if v := os.Getenv("PLACE_PHOTO_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return cfg, fmt.Errorf("PLACE_PHOTO_TIMEOUT must be a positive duration, got %q", v)
+		}
+		cfg.Timeout = timeout
+	}
+	if v := os.Getenv("PLACE_PHOTO_RATE_LIMIT"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return cfg, fmt.Errorf("PLACE_PHOTO_RATE_LIMIT must be a non-negative integer, got %q", v)
+		}
+		cfg.RateLimit = limit
+	}
+	if v := os.Getenv("PLACE_PHOTO_TRUST_FORWARDED_FOR"); v != "" {
+		trust, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("PLACE_PHOTO_TRUST_FORWARDED_FOR must be a boolean, got %q", v)
+		}
+		cfg.TrustForwardedFor = trust
+	}
+	return cfg, nil
+}
+
+// PhotoURIStore caches resolved photo URIs. ValkeyPhotoURIStore keeps them in Valkey.
+type PhotoURIStore interface {
+	Get(ctx context.Context, key string) (string, bool)
+	Set(ctx context.Context, key, uri string, ttl time.Duration)
+}
+
+type valkeyPhotoURIs struct {
+	kv valkey.Client
+}
+
+func ValkeyPhotoURIStore(kv valkey.Client) PhotoURIStore {
+	return valkeyPhotoURIs{kv: kv}
+}
+
+func (s valkeyPhotoURIs) Get(ctx context.Context, key string) (string, bool) {
+	uri, err := s.kv.Do(ctx, s.kv.B().Get().Key(key).Build()).ToString()
+	if err != nil {
+		if !valkey.IsValkeyNil(err) {
+			slog.Debug("Photo URI cache get error", slog.String("key", key), slog.Any("error", err))
+		}
+		return "", false
+	}
+	return uri, uri != ""
+}
+
+func (s valkeyPhotoURIs) Set(ctx context.Context, key, uri string, ttl time.Duration) {
+	if err := s.kv.Do(ctx, s.kv.B().Set().Key(key).Value(uri).Ex(ttl).Build()).Error(); err != nil {
+		slog.Debug("Photo URI cache set error", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// PlacePhotoService redirects browsers to Google-hosted place photos. Google resolves a
+// photo name to a short-lived URI; those are cached, and the Places API is shielded by a
+// per-client rate limit, a timeout and a circuit breaker.
+type PlacePhotoService struct {
+	Config PlacePhotoConfig
+	URIs   PhotoURIStore
+	HTTP   *http.Client
+	// Authorize adds credentials to a Places API request.
+	Authorize func(*http.Request) error
+	// Nil when the rate limit is disabled.
+	Limiter *ClientRateLimiter
+	Breaker *CircuitBreaker
+	Metrics *utils.PlacePhotoMetrics
+
+	sf singleflight.Group
+}
+
+func NewPlacePhotoService(cfg PlacePhotoConfig, uris PhotoURIStore, authorize func(*http.Request) error, metrics *utils.PlacePhotoMetrics) *PlacePhotoService {
+	s := &PlacePhotoService{
+		Config:    cfg,
+		URIs:      uris,
+		HTTP:      &http.Client{Timeout: cfg.Timeout},
+		Authorize: authorize,
+		Breaker:   &CircuitBreaker{Threshold: photoBreakerThreshold, Cooldown: photoBreakerCooldown},
+		Metrics:   metrics,
+	}
+	if cfg.RateLimit > 0 {
+		s.Limiter = NewClientRateLimiter(cfg.RateLimit)
+	}
+	return s
+}
+
+// Handler serves GET /place-photo?name=places/…/photos/…[&width=…][&height=…] with a
+// redirect to the photo. width and height must be in PlacePhotoSizes; without either the
+// photo is 800 pixels wide.
+func (s *PlacePhotoService) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Limiter != nil && !s.Limiter.Allow(s.clientKey(r)) {
+			s.Metrics.Observe(utils.PlacePhotoRateLimited)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(60/float64(s.Config.RateLimit)))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		q := r.URL.Query()
+		name := q.Get("name")
+		width, height, err := placePhotoSize(q)
+		if err == nil && !placePhotoName.MatchString(name) {
+			err = errors.New("name must be a photo resource name (places/…/photos/…)")
+		}
+		if err != nil {
+			s.Metrics.Observe(utils.PlacePhotoInvalid)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key := cache.HashKey(name, strconv.Itoa(width), strconv.Itoa(height))
+		uri, result, err := s.photoURI(r.Context(), "place_photo:"+key, name, width, height)
+		if err != nil {
+			s.Metrics.Observe(result)
+			w.Header().Set("Cache-Control", "no-store")
+			var upstream *photoUpstreamError
+			switch {
+			case errors.Is(err, errPhotoCircuitOpen):
+				w.Header().Set("Retry-After", strconv.Itoa(int(s.Breaker.Cooldown.Seconds())))
+				http.Error(w, "photos are temporarily unavailable", http.StatusServiceUnavailable)
+			case errors.As(err, &upstream) && upstream.clientError():
+				http.Error(w, "photo not found", http.StatusNotFound)
+			default:
+				http.Error(w, "upstream request failed", http.StatusBadGateway)
+			}
+			return
+		}
+
+		// The validator follows the photo URI, so a client holding an expired URI gets the
+		// new one once the cached URI is replaced.
+		etag := `"` + cache.HashKey(uri)[:20] + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.Config.CacheTTL.Seconds())))
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			s.Metrics.Observe(utils.PlacePhotoNotModified)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.Metrics.Observe(result)
+		http.Redirect(w, r, uri, http.StatusFound)
+	})
+}
+
+// placePhotoSize reads the width and height parameters.
+func placePhotoSize(q url.Values) (width, height int, err error) {
+	for _, p := range []struct {
+		param string
+		dst   *int
+	}{{"width", &width}, {"height", &height}} {
+		v := q.Get(p.param)
+		if v == "" {
+			continue
+		}
+		n, convErr := strconv.Atoi(v)
+		if convErr != nil || !slices.Contains(PlacePhotoSizes, n) {
+			return 0, 0, fmt.Errorf("%s must be one of %s", p.param, strings.Trim(fmt.Sprint(PlacePhotoSizes), "[]"))
+		}
+		*p.dst = n
+	}
+	if width == 0 && height == 0 {
+		width = defaultPhotoWidth
+	}
+	return width, height, nil
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// clientKey identifies the client of r for rate limiting.
+func (s *PlacePhotoService) clientKey(r *http.Request) string {
+	if s.Config.TrustForwardedFor {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+var errPhotoCircuitOpen = errors.New("place photo circuit is open")
+
+// photoUpstreamError is a non-200 answer from the Places API.
+type photoUpstreamError struct {
+	status int
+	reason string
+}
+
+func (e *photoUpstreamError) Error() string {
+	return fmt.Sprintf("places media: HTTP %d %s", e.status, e.reason)
+}
+
+// clientError reports whether the photo name was at fault rather than Google.
+func (e *photoUpstreamError) clientError() bool {
+	return e.status >= 400 && e.status < 500 && e.status != http.StatusTooManyRequests
+}
+
+// photoURI returns the cached URI of a photo or resolves it, with the metrics result that
+// describes how.
+func (s *PlacePhotoService) photoURI(ctx context.Context, key, name string, width, height int) (string, string, error) {
+	if uri, ok := s.URIs.Get(ctx, key); ok {
+		return uri, utils.PlacePhotoHit, nil
+	}
+	v, err, _ := s.sf.Do(key, func() (any, error) {
+		if !s.Breaker.Allow() {
+			return nil, errPhotoCircuitOpen
+		}
+		// Finish the call even if this client leaves; others may be waiting on it.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.Config.Timeout)
+		defer cancel()
+		uri, err := s.resolve(ctx, name, width, height)
+		var upstream *photoUpstreamError
+		s.Breaker.Record(err != nil && !(errors.As(err, &upstream) && upstream.clientError()))
+		if err != nil {
+			slog.Warn("place-photo: upstream request failed", slog.String("name", name), slog.Any("error", err))
+			return nil, err
+		}
+		s.URIs.Set(ctx, key, uri, s.Config.CacheTTL)
+		return uri, nil
+	})
+	switch {
+	case errors.Is(err, errPhotoCircuitOpen):
+		return "", utils.PlacePhotoCircuitOpen, err
+	case err != nil:
+		return "", utils.PlacePhotoUpstreamError, err
+	}
+	return v.(string), utils.PlacePhotoMiss, nil
+}
+
+// resolve asks the Places API for the URI of a photo.
+func (s *PlacePhotoService) resolve(ctx context.Context, name string, width, height int) (string, error) {
+	params := url.Values{"skipHttpRedirect": {"true"}}
+	if width > 0 {
+		params.Set("maxWidthPx", strconv.Itoa(width))
+	}
+	if height > 0 {
+		params.Set("maxHeightPx", strconv.Itoa(height))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Config.BaseURL+name+"/media?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(req); err != nil {
+			return "", fmt.Errorf("authorize: %w", err)
+		}
+	}
+
+	start := time.Now()
+	resp, err := s.HTTP.Do(req)
+	s.Metrics.ObserveUpstream(time.Since(start))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	// Error bodies can echo the request, key included, so only Google's status is kept.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var googleErr struct {
+			Error struct {
+				Status string `json:"status"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &googleErr)
+		return "", &photoUpstreamError{status: resp.StatusCode, reason: googleErr.Error.Status}
+	}
+	// skipHttpRedirect=true returns {"name":"...","photoUri":"https://..."}.
+	var photo struct {
+		PhotoURI string `json:"photoUri"`
+	}
+	if err := json.Unmarshal(body, &photo); err != nil || !strings.HasPrefix(photo.PhotoURI, "https://") {
+		return "", errors.New("places media: response has no photoUri")
+	}
+	return photo.PhotoURI, nil
+}
+
+// CircuitBreaker stops calls to a failing dependency: after Threshold consecutive failures
+// it rejects calls for Cooldown, then lets a single call through to see if it recovered.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// Allow reports whether a call may be made; each allowed call must be followed by Record.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.Threshold {
+		return true
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Record records the outcome of an allowed call.
+func (b *CircuitBreaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.Threshold {
+		b.openUntil = b.now().Add(b.Cooldown)
+	}
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// ClientRateLimiter gives each client a token bucket refilled at a per-minute rate and
+// holding half a minute's worth, so a page of photos loads at once.
+type ClientRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewClientRateLimiter(perMinute int) *ClientRateLimiter {
+	return &ClientRateLimiter{
+		limit:   rate.Limit(float64(perMinute) / 60),
+		burst:   max(1, perMinute/2),
+		clients: map[string]*clientLimiter{},
+	}
+}
+
+// Allow takes a token from client's bucket, reporting whether there was one.
+func (l *ClientRateLimiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > clientLimiterIdle {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientLimiterIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"api/src/internal/mappers"
+	"api/src/internal/models"
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"connectrpc.com/connect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// placeDetailsFields is the field mask of a lookup: what a new restaurant row stores.
+var placeDetailsFields = []string{"id", "display_name", "formatted_address", "postal_address", "photos", "business_status", "location"}
+
+// PlaceResolver looks restaurants up in Google Places so that the shared restaurants table
+// holds canonical details rather than whatever a client sent. Give it a
+// CachedPlacesProvider to avoid a lookup per request.
+type PlaceResolver struct {
+	Places PlacesProvider
+}
+
+func NewPlaceResolver(provider PlacesProvider) *PlaceResolver {
+	return &PlaceResolver{Places: provider}
+}
+
+// RestaurantHints are the restaurant details a client sends along with a Google Places ID.
+type RestaurantHints struct {
+	Name, Address, City, Country, PhotoReference string
+	Latitude, Longitude                          *float64
+}
+
+// Lookup returns the place with the resource name name.
+func (r *PlaceResolver) Lookup(ctx context.Context, name string) (*placespb.Place, error) {
+	place, err := r.Places.GetPlace(ctx, &placespb.GetPlaceRequest{Name: name}, placeDetailsFields)
+	if err == nil && place == nil {
+		err = errors.New("empty place")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return place, nil
+}
+
+// Resolve returns the restaurant details to store for googleID. Restaurants already looked
+// up once are returned as they are, whatever fields Google left out; keeping them current
+// is the RestaurantRefresher's job. Others, including those stored from hints, come from
+// Details.
+func (r *PlaceResolver) Resolve(ctx context.Context, db *gorm.DB, googleID string, hints RestaurantHints) (models.Restaurant, error) {
+	var existing models.Restaurant
+	if err := db.WithContext(ctx).Where("google_id = ?", googleID).Limit(1).Find(&existing).Error; err != nil {
+		return models.Restaurant{}, err
+	}
+	if existing.ID != "" && existing.RefreshedAt != nil {
+		return existing, nil
+	}
+	if r == nil || r.Places == nil {
+		slog.Error("No places provider to look restaurants up with", slog.String("google_id", googleID))
+		return models.Restaurant{}, connect.NewError(connect.CodeInternal, errors.New(errPlaceLookupsNotConfigured))
+	}
+	return r.Details(ctx, googleID, hints)
+}
+
+// Details looks googleID up and returns the restaurant details to store for it. Hints are
+// used only while Google Places cannot answer; a place it does not know is rejected, so
+// clients cannot store made-up restaurants under made-up IDs.
+func (r *PlaceResolver) Details(ctx context.Context, googleID string, hints RestaurantHints) (models.Restaurant, error) {
+	place, err := r.Lookup(ctx, googleID)
+	switch status.Code(err) {
+	case codes.OK:
+		restaurant := RestaurantFromPlace(googleID, place)
+		now := time.Now()
+		restaurant.RefreshedAt = &now
+		return restaurant, nil
+	case codes.NotFound, codes.InvalidArgument:
+		return models.Restaurant{}, connect.NewError(connect.CodeInvalidArgument, errors.New(errUnknownGooglePlace))
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		slog.Warn("Place lookup failed; using client-sent details",
+			slog.String("google_id", googleID), slog.Any("error", err))
+		return RestaurantFromHints(googleID, hints), nil
+	default:
+		return models.Restaurant{}, connect.NewError(connect.CodeInternal, err)
+	}
+}
+
+// RestaurantFromPlace returns the restaurant row for a place from Google Places.
+func RestaurantFromPlace(googleID string, place *placespb.Place) models.Restaurant {
+	city, country := mappers.PlaceCityCountry(place)
+	restaurant := models.Restaurant{
+		GoogleID:       googleID,
+		Name:           place.GetDisplayName().GetText(),
+		Address:        place.GetFormattedAddress(),
+		City:           city,
+		Country:        country,
+		BusinessStatus: businessStatusOf(place),
+	}
+	if photos := place.GetPhotos(); len(photos) > 0 {
+		restaurant.PhotoReference = photos[0].GetName()
+	}
+	if loc := place.GetLocation(); loc != nil {
+		restaurant.SetLocation(loc.GetLatitude(), loc.GetLongitude())
+	}
+	return restaurant
+}
+
+// RestaurantFromHints returns the restaurant row for client-sent details.
+func RestaurantFromHints(googleID string, hints RestaurantHints) models.Restaurant {
+	restaurant := models.Restaurant{
+		GoogleID:       googleID,
+		Name:           hints.Name,
+		Address:        hints.Address,
+		City:           hints.City,
+		Country:        hints.Country,
+		PhotoReference: hints.PhotoReference,
+	}
+	if hints.Latitude != nil && hints.Longitude != nil {
+		restaurant.SetLocation(*hints.Latitude, *hints.Longitude)
+	}
+	return restaurant
+}
+
+// saveRestaurant returns the restaurant row for details.GoogleID, creating it from details
+// on first sight and filling in the fields an existing row lacks.
+func saveRestaurant(tx *gorm.DB, details models.Restaurant) (models.Restaurant, error) {
+	var restaurant models.Restaurant
+	if err := tx.Where(models.Restaurant{GoogleID: details.GoogleID}).
+		Attrs(details).
+		FirstOrCreate(&restaurant).Error; err != nil {
+		return restaurant, err
+	}
+	// Backfill fields of restaurants created before they were tracked, or from hints.
+	if updates := missingRestaurantFields(&restaurant, &details); len(updates) > 0 {
+		if err := tx.Model(&restaurant).Updates(updates).Error; err != nil {
+			return restaurant, err
+		}
+		if err := tx.First(&restaurant, "id = ?", restaurant.ID).Error; err != nil {
+			return restaurant, err
+		}
+	}
+	return restaurant, nil
+}
+
+// googlePlacesIDPattern matches place resource names: "places/" and a place ID.
+var googlePlacesIDPattern = regexp.MustCompile(`^places/[A-Za-z0-9_-]+$`)
+
+// validateGooglePlacesID requires a place resource name ("places/…").
+func validateGooglePlacesID(id string) error {
+	if id == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errGooglePlacesIDRequired))
+	}
+	if !googlePlacesIDPattern.MatchString(id) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidGooglePlacesID))
+	}
+	return nil
+}
+
+// businessStatusOf returns the stored business status of place, "" when Google doesn't say.
+func businessStatusOf(place *placespb.Place) string {
+	if s := place.GetBusinessStatus(); s != placespb.Place_BUSINESS_STATUS_UNSPECIFIED {
+		return s.String()
+	}
+	return ""
+}
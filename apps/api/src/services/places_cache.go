@@ -0,0 +1,162 @@
+package services
+
+import (
+	"api/src/internal/cache"
+	"api/src/internal/utils"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"github.com/valkey-io/valkey-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// How long Google Places responses are cached. Place details change rarely; search results
+// shift with ratings and opening hours. Requests with a session token are never answered
+// from the cache, since Google bills the session as a whole when it ends with a details
+// call.
+const (
+	placesDetailsCacheTTL      = 24 * time.Hour
+	placesSearchCacheTTL       = 10 * time.Minute
+	placesAutocompleteCacheTTL = 5 * time.Minute
+)
+
+// PlacesCacheStore stores responses for one RPC; its TTL is that RPC's and its keys carry
+// its prefix. cache.ProtoCache implements it.
+type PlacesCacheStore interface {
+	BuildKey(operation string, parts ...string) string
+	CachedFetch(ctx context.Context, key string, dst proto.Message, fetchFn func() (proto.Message, error)) (proto.Message, error)
+	Set(ctx context.Context, key string, msg proto.Message)
+}
+
+// PlacesCacheStores holds the store of each cached RPC.
+type PlacesCacheStores struct {
+	Details      PlacesCacheStore
+	Search       PlacesCacheStore
+	Autocomplete PlacesCacheStore
+}
+
+// ValkeyPlacesCacheStores returns stores in kv with the per-RPC TTLs.
+func ValkeyPlacesCacheStores(kv valkey.Client) PlacesCacheStores {
+	return PlacesCacheStores{
+		Details:      cache.NewProtoCache(kv, placesDetailsCacheTTL, "places:details:"),
+		Search:       cache.NewProtoCache(kv, placesSearchCacheTTL, "places:search:"),
+		Autocomplete: cache.NewProtoCache(kv, placesAutocompleteCacheTTL, "places:autocomplete:"),
+	}
+}
+
+// CachedPlacesProvider is a PlacesProvider that caches another one's responses. Keys cover
+// the whole request, language and region included, and the field mask; errors are not
+// cached.
+type CachedPlacesProvider struct {
+	upstream PlacesProvider
+	stores   PlacesCacheStores
+	metrics  *utils.PlacesCacheMetrics
+}
+
+func NewCachedPlacesProvider(upstream PlacesProvider, stores PlacesCacheStores, metrics *utils.PlacesCacheMetrics) *CachedPlacesProvider {
+	return &CachedPlacesProvider{upstream: upstream, stores: stores, metrics: metrics}
+}
+
+func (p *CachedPlacesProvider) SearchText(ctx context.Context, req *placespb.SearchTextRequest, fields []string) (*placespb.SearchTextResponse, error) {
+	resp := &placespb.SearchTextResponse{}
+	err := p.cached(ctx, "SearchText", p.stores.Search, req, fields, resp, func() (proto.Message, error) {
+		return p.upstream.SearchText(ctx, req, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (p *CachedPlacesProvider) GetPlace(ctx context.Context, req *placespb.GetPlaceRequest, fields []string) (*placespb.Place, error) {
+	// The session token only groups billing; the place is the same without it.
+	keyed := proto.Clone(req).(*placespb.GetPlaceRequest)
+	keyed.SessionToken = ""
+	if req.SessionToken != "" {
+		// A details call ending an autocomplete session must reach Google for the session
+		// to be billed as one; its answer still serves later calls.
+		p.metrics.Observe("GetPlace", utils.PlacesCacheBypass)
+		place, err := p.upstream.GetPlace(ctx, req, fields)
+		if err != nil {
+			return nil, err
+		}
+		if hash, err := PlacesCacheKey(keyed, fields); err == nil {
+			p.stores.Details.Set(ctx, p.stores.Details.BuildKey("GetPlace", hash), place)
+		}
+		return place, nil
+	}
+	resp := &placespb.Place{}
+	err := p.cached(ctx, "GetPlace", p.stores.Details, keyed, fields, resp, func() (proto.Message, error) {
+		return p.upstream.GetPlace(ctx, req, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (p *CachedPlacesProvider) AutocompletePlaces(ctx context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error) {
+	if req.SessionToken != "" {
+		p.metrics.Observe("AutocompletePlaces", utils.PlacesCacheBypass)
+		return p.upstream.AutocompletePlaces(ctx, req)
+	}
+	resp := &placespb.AutocompletePlacesResponse{}
+	err := p.cached(ctx, "AutocompletePlaces", p.stores.Autocomplete, req, nil, resp, func() (proto.Message, error) {
+		return p.upstream.AutocompletePlaces(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// cached fills dst from store under the key of method, req and fields, or on a miss from
+// fetch; concurrent misses on one key share a single fetch.
+func (p *CachedPlacesProvider) cached(ctx context.Context, method string, store PlacesCacheStore, req proto.Message, fields []string, dst proto.Message, fetch func() (proto.Message, error)) error {
+	hash, err := PlacesCacheKey(req, fields)
+	if err != nil {
+		p.metrics.Observe(method, utils.PlacesCacheBypass)
+		msg, err := fetch()
+		if err != nil {
+			return err
+		}
+		proto.Merge(dst, msg)
+		return nil
+	}
+	fetched := false
+	msg, err := store.CachedFetch(ctx, store.BuildKey(method, hash), dst, func() (proto.Message, error) {
+		fetched = true
+		return fetch()
+	})
+	switch {
+	case fetched:
+		p.metrics.Observe(method, utils.PlacesCacheMiss)
+	case msg == dst:
+		p.metrics.Observe(method, utils.PlacesCacheHit)
+	default:
+		p.metrics.Observe(method, utils.PlacesCacheShared)
+	}
+	if err != nil {
+		return err
+	}
+	// Callers get their own copy; a fetched message is shared with concurrent waiters.
+	if msg != dst {
+		proto.Merge(dst, msg)
+	}
+	return nil
+}
+
+// PlacesCacheKey returns the cache key of a request with a field mask. The mask's order
+// does not matter.
+func PlacesCacheKey(req proto.Message, fields []string) (string, error) {
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	mask := slices.Clone(fields)
+	slices.Sort(mask)
+	return cache.HashKey(strings.Join(mask, ","), string(raw)), nil
+}
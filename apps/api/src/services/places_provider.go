@@ -0,0 +1,51 @@
+package services
+
+import (
+	"api/src/internal/mappers"
+	"context"
+
+	places "cloud.google.com/go/maps/places/apiv1"
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"google.golang.org/grpc/metadata"
+)
+
+// PlacesProvider is the part of the Google Places API the services use. Field masks are
+// passed explicitly rather than through request metadata so that fakes can check them;
+// NewGooglePlacesProvider wraps the API client and placestest replays recorded responses.
+type PlacesProvider interface {
+	// SearchText runs a text search, filling in only fields of each place (every field the
+	// caller may see when fields is empty).
+	SearchText(ctx context.Context, req *placespb.SearchTextRequest, fields []string) (*placespb.SearchTextResponse, error)
+	// GetPlace returns one place, filling in only fields.
+	GetPlace(ctx context.Context, req *placespb.GetPlaceRequest, fields []string) (*placespb.Place, error)
+	AutocompletePlaces(ctx context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error)
+}
+
+type googlePlacesProvider struct {
+	client *places.Client
+}
+
+// NewGooglePlacesProvider returns a PlacesProvider that calls the Google Places API.
+func NewGooglePlacesProvider(client *places.Client) PlacesProvider {
+	return googlePlacesProvider{client: client}
+}
+
+func (p googlePlacesProvider) SearchText(ctx context.Context, req *placespb.SearchTextRequest, fields []string) (*placespb.SearchTextResponse, error) {
+	return p.client.SearchText(withFieldMask(ctx, fields), req)
+}
+
+func (p googlePlacesProvider) GetPlace(ctx context.Context, req *placespb.GetPlaceRequest, fields []string) (*placespb.Place, error) {
+	return p.client.GetPlace(withFieldMask(ctx, fields), req)
+}
+
+func (p googlePlacesProvider) AutocompletePlaces(ctx context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error) {
+	return p.client.AutocompletePlaces(ctx, req)
+}
+
+// withFieldMask sets the X-Goog-FieldMask header the Places API requires on ctx.
+func withFieldMask(ctx context.Context, fields []string) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{"X-Goog-FieldMask": mappers.BuildFieldMask(fields)}))
+}
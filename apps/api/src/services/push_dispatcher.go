@@ -0,0 +1,296 @@
+package services
+
+import (
+	"api/src/internal/email"
+	"api/src/internal/jobs"
+	"api/src/internal/models"
+	"api/src/internal/webpush"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	pushBatchSize   = 50
+	pushMaxAttempts = 5
+	// How long push services should hold a message for an offline device.
+	pushTTL = 24 * time.Hour
+	// Oldest subscriptions beyond this are dropped when a user registers another device.
+	maxPushSubscriptionsPerUser = 20
+	maxPushUserAgentLength      = 255
+)
+
+// PushPayload is the JSON the service worker receives in its push event.
+type PushPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	// Notifications with the same tag replace each other on the device.
+	Tag string `json:"tag"`
+}
+
+// PushDispatcher delivers queued push messages as jobs registered by RegisterJobs. Like
+// EmailDispatcher it is safe to run on every replica: rows are claimed with
+// FOR UPDATE SKIP LOCKED.
+type PushDispatcher struct {
+	DB     *gorm.DB
+	Client *webpush.Client
+	// Base URL of the web app, opened when a notification is clicked.
+	AppURL string
+}
+
+func NewPushDispatcher(db *gorm.DB, client *webpush.Client, appURL string) *PushDispatcher {
+	return &PushDispatcher{DB: db, Client: client, AppURL: appURL}
+}
+
+// pushDeliveryJob drains the push outbox. It is enqueued whenever a message is queued
+// and also runs every minute to pick up retries.
+var pushDeliveryJob = jobs.Kind[struct{}]{Name: "push.deliver"}
+
+// RegisterJobs registers push delivery on r.
+func (d *PushDispatcher) RegisterJobs(r *jobs.Runner) error {
+	jobs.Handle(r, pushDeliveryJob, func(ctx context.Context, _ struct{}) error {
+		for {
+			n, err := d.DeliverDue(ctx, time.Now())
+			if err != nil || n < pushBatchSize {
+				return err
+			}
+		}
+	})
+	return jobs.Every(r, pushDeliveryJob.Name, "@every 1m", pushDeliveryJob, struct{}{})
+}
+
+// DeliverDue sends up to one batch of pending push messages whose next attempt is due
+// and returns how many were processed.
+func (d *PushDispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	processed := 0
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []models.PushOutbox
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.PushStatusPending, now).
+			Order("next_attempt_at").Limit(pushBatchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			row := &rows[i]
+			if err := d.attempt(ctx, tx, row, now); err != nil {
+				return err
+			}
+			if err := tx.Save(row).Error; err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+// attempt tries to send row once and records the outcome on it. Only database errors
+// are returned; delivery failures are recorded on the row.
+func (d *PushDispatcher) attempt(ctx context.Context, tx *gorm.DB, row *models.PushOutbox, now time.Time) error {
+	row.Attempts++
+
+	var sub models.PushSubscription
+	if err := tx.WithContext(ctx).First(&sub, "id = ?", row.SubscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			row.Status = models.PushStatusSkipped
+			row.LastError = "subscription removed"
+			return nil
+		}
+		return err
+	}
+
+	payload, skipReason, err := d.compose(ctx, tx, row)
+	if err != nil {
+		return err
+	}
+	if skipReason != "" {
+		row.Status = models.PushStatusSkipped
+		row.LastError = skipReason
+		return nil
+	}
+
+	err = d.Client.Send(ctx, webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth},
+		payload, webpush.Options{TTL: pushTTL, Urgency: "normal"})
+	var statusErr *webpush.StatusError
+	switch {
+	case err == nil:
+		row.Status = models.PushStatusSent
+		row.LastError = ""
+		row.SentAt = &now
+		return tx.Model(&sub).UpdateColumn("last_used_at", now).Error
+	case errors.Is(err, webpush.ErrSubscriptionGone):
+		row.Status = models.PushStatusSkipped
+		row.LastError = err.Error()
+		return tx.Delete(&sub).Error
+	case errors.As(err, &statusErr) && !statusErr.Temporary():
+		row.Status = models.PushStatusFailed
+		row.LastError = err.Error()
+		return nil
+	}
+
+	row.LastError = err.Error()
+	if row.Attempts >= pushMaxAttempts {
+		row.Status = models.PushStatusFailed
+		slog.Warn("Giving up on push message", slog.String("id", row.ID), slog.String("template", row.Template), slog.Any("error", err))
+		return nil
+	}
+	// Same backoff as email; pushes give up sooner.
+	row.NextAttemptAt = now.Add(EmailRetryDelay(int(row.Attempts)))
+	return nil
+}
+
+// compose renders row in the recipient's language. It returns a non-empty skip reason
+// when the message should no longer be sent.
+func (d *PushDispatcher) compose(ctx context.Context, tx *gorm.DB, row *models.PushOutbox) ([]byte, string, error) {
+	var user models.User
+	if err := tx.WithContext(ctx).First(&user, "id = ?", row.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "recipient no longer exists", nil
+		}
+		return nil, "", err
+	}
+	prefs, err := loadNotificationPreferences(ctx, tx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if pref, ok := prefs[row.Template]; !ok || !pref.Push {
+		return nil, "recipient turned push off", nil
+	}
+
+	vars := map[string]string{"place": row.Params[emailParamSubjectName]}
+	if actorID := row.Params[emailParamActorID]; actorID != "" {
+		var actor models.User
+		if err := tx.WithContext(ctx).First(&actor, "id = ?", actorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, "actor no longer exists", nil
+			}
+			return nil, "", err
+		}
+		vars["actor"] = actor.Name
+	}
+
+	// Push copy reuses the email catalogs: the subject is the title, the body line the body.
+	locale := email.NormalizeLocale(user.DefaultLanguage)
+	payload, err := json.Marshal(PushPayload{
+		Title: email.Translate(locale, row.Template+"_subject", vars),
+		Body:  email.Translate(locale, row.Template+"_body", vars),
+		URL:   notificationActionURL(d.AppURL, row.Template, row.Params[emailParamSubjectID]),
+		Tag:   row.Template + ":" + row.Params[emailParamSubjectID],
+	})
+	return payload, "", err
+}
+
+// enqueuePush queues a push message for each of userID's subscriptions and makes sure a
+// delivery job is pending.
+func enqueuePush(tx *gorm.DB, userID, templateName string, params map[string]string) error {
+	var subscriptionIDs []string
+	if err := tx.Model(&models.PushSubscription{}).Where("user_id = ?", userID).
+		Pluck("id", &subscriptionIDs).Error; err != nil {
+		return err
+	}
+	if len(subscriptionIDs) == 0 {
+		return nil
+	}
+	rows := make([]models.PushOutbox, len(subscriptionIDs))
+	for i, id := range subscriptionIDs {
+		rows[i] = models.PushOutbox{
+			UserID:         userID,
+			SubscriptionID: id,
+			Template:       templateName,
+			Params:         params,
+			Status:         models.PushStatusPending,
+			NextAttemptAt:  time.Now(),
+		}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return err
+	}
+	return pushDeliveryJob.Enqueue(tx, struct{}{}, jobs.Dedupe(pushDeliveryJob.Name))
+}
+
+// savePushSubscription stores sub, taking over any existing row for the same endpoint
+// (the browser may have been used by another account, or re-subscribed after login),
+// and drops the user's oldest subscriptions beyond maxPushSubscriptionsPerUser.
+func savePushSubscription(ctx context.Context, db *gorm.DB, sub models.PushSubscription) (*models.PushSubscription, error) {
+	if len(sub.UserAgent) > maxPushUserAgentLength {
+		sub.UserAgent = sub.UserAgent[:maxPushUserAgentLength]
+	}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.PushSubscription
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("endpoint = ?", sub.Endpoint).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&sub).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			existing.UserID = sub.UserID
+			existing.P256dh = sub.P256dh
+			existing.Auth = sub.Auth
+			existing.UserAgent = sub.UserAgent
+			existing.SessionHash = sub.SessionHash
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			sub = existing
+		}
+
+		var stale []string
+		if err := tx.Model(&models.PushSubscription{}).Where("user_id = ?", sub.UserID).
+			Order("updated_at DESC").Offset(maxPushSubscriptionsPerUser).Pluck("id", &stale).Error; err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", stale).Delete(&models.PushSubscription{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// hashSessionToken returns the hex SHA-256 of a session token, used to link push
+// subscriptions to the session that registered them without storing the token.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidatePushEndpoint rejects endpoints the server must never POST to: anything but
+// https, and hosts that are loopback, private or link-local addresses. Push service
+// endpoints are always public https URLs. Host names are only checked once resolved,
+// by the webpush client when it connects.
+func ValidatePushEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return errors.New(errPushEndpointRequired)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("endpoint must be an https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("endpoint host %q is not allowed", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !webpush.PublicAddress(addr) {
+		return fmt.Errorf("endpoint host %q is not allowed", host)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"api/src/internal/jobs"
+	"api/src/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var refreshRestaurantsJob = jobs.Kind[struct{}]{Name: "restaurants.refresh", MaxAttempts: 3, Timeout: 15 * time.Minute}
+
+// restaurantRefreshFields is the field mask of a refresh: only what a restaurant row stores.
+var restaurantRefreshFields = []string{"id", "display_name", "formatted_address", "photos", "business_status", "location"}
+
+type RestaurantRefreshConfig struct {
+	// Google Places requests per UTC day; 0 disables refreshing.
+	DailyBudget int
+	// Restaurants are refreshed again once their last refresh is this old.
+	MaxAge time.Duration
+}
+
+// RestaurantRefreshConfigFromEnv reads PLACES_REFRESH_DAILY_BUDGET (default 200) and
+// PLACES_REFRESH_MAX_AGE (a Go duration, default 720h).
+func RestaurantRefreshConfigFromEnv() (RestaurantRefreshConfig, error) {
+	cfg := RestaurantRefreshConfig{DailyBudget: 200, MaxAge: 30 * 24 * time.Hour}
+	if v := os.Getenv("PLACES_REFRESH_DAILY_BUDGET"); v != "" {
+		budget, err := strconv.Atoi(v)
+		if err != nil || budget < 0 {
+			return cfg, fmt.Errorf("PLACES_REFRESH_DAILY_BUDGET must be a non-negative integer, got %q", v)
+		}
+		cfg.DailyBudget = budget
+	}
+	if v := os.Getenv("PLACES_REFRESH_MAX_AGE"); v != "" {
+		age, err := time.ParseDuration(v)
+		if err != nil || age <= 0 {
+			return cfg, fmt.Errorf("PLACES_REFRESH_MAX_AGE must be a positive duration, got %q", v)
+		}
+		cfg.MaxAge = age
+	}
+	return cfg, nil
+}
+
+// RestaurantRefreshStore keeps the restaurant rows a RestaurantRefresher works through.
+// NewRestaurantRefreshStore keeps them in the database.
+type RestaurantRefreshStore interface {
+	// Requests returns how many Google Places requests refreshes made on the UTC day of now.
+	Requests(ctx context.Context, now time.Time) (int, error)
+	// CountRequest records one refresh request made at now.
+	CountRequest(ctx context.Context, now time.Time) error
+	// Due returns up to limit Google restaurants last refreshed before before, never
+	// refreshed first.
+	Due(ctx context.Context, before time.Time, limit int) ([]models.Restaurant, error)
+	// Save applies updates to restaurant and records it as refreshed at now.
+	Save(ctx context.Context, restaurant *models.Restaurant, updates map[string]any, now time.Time) error
+}
+
+type dbRestaurantRefreshes struct {
+	db *gorm.DB
+}
+
+func NewRestaurantRefreshStore(db *gorm.DB) RestaurantRefreshStore {
+	return dbRestaurantRefreshes{db: db}
+}
+
+func (s dbRestaurantRefreshes) Requests(ctx context.Context, now time.Time) (int, error) {
+	var usage models.RestaurantRefreshUsage
+	err := s.db.WithContext(ctx).Where("day = ?", refreshDay(now)).Limit(1).Find(&usage).Error
+	return usage.Requests, err
+}
+
+func (s dbRestaurantRefreshes) CountRequest(ctx context.Context, now time.Time) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]any{"requests": gorm.Expr("restaurant_refresh_usages.requests + 1")}),
+	}).Create(&models.RestaurantRefreshUsage{Day: refreshDay(now), Requests: 1}).Error
+}
+
+// refreshDay returns the UTC day of now, which the refresh budget is counted per.
+func refreshDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+func (s dbRestaurantRefreshes) Due(ctx context.Context, before time.Time, limit int) ([]models.Restaurant, error) {
+	var due []models.Restaurant
+	err := s.db.WithContext(ctx).
+		Where("google_id LIKE 'places/%' AND (refreshed_at IS NULL OR refreshed_at < ?)", before).
+		Order("refreshed_at NULLS FIRST, created_at").
+		Limit(limit).
+		Find(&due).Error
+	return due, err
+}
+
+func (s dbRestaurantRefreshes) Save(ctx context.Context, restaurant *models.Restaurant, updates map[string]any, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Addresses are unique; two rows for one place must not fail the whole refresh.
+		if address, ok := updates["address"]; ok {
+			var taken int64
+			if err := tx.Model(&models.Restaurant{}).Where("address = ? AND id <> ?", address, restaurant.ID).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				slog.Warn("Refreshed address belongs to another restaurant",
+					slog.String("restaurant_id", restaurant.ID), slog.Any("address", address))
+				delete(updates, "address")
+			}
+		}
+		updates["refreshed_at"] = now
+		return tx.Model(restaurant).Updates(updates).Error
+	})
+}
+
+// RestaurantRefresher keeps restaurant rows in line with Google Places: names, addresses
+// and photos go stale, and places close. It runs hourly as a job registered by
+// RegisterJobs and spreads Config.DailyBudget requests over the day.
+type RestaurantRefresher struct {
+	Store  RestaurantRefreshStore
+	Places PlacesProvider
+	Config RestaurantRefreshConfig
+}
+
+func NewRestaurantRefresher(db *gorm.DB, provider PlacesProvider, cfg RestaurantRefreshConfig) *RestaurantRefresher {
+	return &RestaurantRefresher{Store: NewRestaurantRefreshStore(db), Places: provider, Config: cfg}
+}
+
+// RegisterJobs registers the hourly refresh on r.
+func (rr *RestaurantRefresher) RegisterJobs(r *jobs.Runner) error {
+	jobs.Handle(r, refreshRestaurantsJob, func(ctx context.Context, _ struct{}) error {
+		n, err := rr.RefreshDue(ctx, time.Now())
+		if n > 0 {
+			slog.Info("Refreshed restaurants from Google Places", slog.Int("count", n))
+		}
+		return err
+	})
+	return jobs.Every(r, refreshRestaurantsJob.Name, "@hourly", refreshRestaurantsJob, struct{}{})
+}
+
+// RefreshAllowance returns how many refreshes may run at now, given that used of the daily
+// budget have run since midnight UTC. The budget accrues evenly over the hours of the day,
+// so a missed run is made up later but the whole budget is never spent at once.
+func RefreshAllowance(budget, used int, now time.Time) int {
+	accrued := int(math.Ceil(float64(budget) * float64(now.UTC().Hour()+1) / 24))
+	return max(0, min(budget, accrued)-used)
+}
+
+// RefreshDue refreshes the restaurants whose last refresh is older than Config.MaxAge (never
+// refreshed first), as far as the budget allows, and returns how many were refreshed.
+// Every request counts against the budget, including failed ones. A restaurant that fails
+// to refresh is recorded as refreshed all the same, so that it does not hold up the others
+// until it is due again. Running out of Google quota ends the run early, without an error:
+// the next hourly run carries on.
+func (rr *RestaurantRefresher) RefreshDue(ctx context.Context, now time.Time) (int, error) {
+	if rr.Config.DailyBudget <= 0 {
+		return 0, nil
+	}
+	used, err := rr.Store.Requests(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	allowance := RefreshAllowance(rr.Config.DailyBudget, used, now)
+	if allowance == 0 {
+		return 0, nil
+	}
+
+	due, err := rr.Store.Due(ctx, now.Add(-rr.Config.MaxAge), allowance)
+	if err != nil {
+		return 0, err
+	}
+	refreshed := 0
+	for i := range due {
+		restaurant := &due[i]
+		if err := rr.Store.CountRequest(ctx, now); err != nil {
+			return refreshed, err
+		}
+		updates, err := rr.FetchUpdates(ctx, restaurant)
+		if err != nil {
+			if ctx.Err() != nil {
+				return refreshed, err
+			}
+			if status.Code(err) == codes.ResourceExhausted {
+				slog.Warn("Google Places quota ran out; restaurant refresh stopped until the next run", slog.Any("error", err))
+				return refreshed, nil
+			}
+			slog.Warn("Failed to refresh restaurant",
+				slog.String("restaurant_id", restaurant.ID), slog.Any("error", err))
+			if err := rr.Store.Save(ctx, restaurant, map[string]any{}, now); err != nil {
+				return refreshed, err
+			}
+			continue
+		}
+		if err := rr.Store.Save(ctx, restaurant, updates, now); err != nil {
+			return refreshed, err
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Refresh re-fetches one restaurant and saves what changed, recording the refresh even when
+// nothing did. The request counts against the daily budget.
+func (rr *RestaurantRefresher) Refresh(ctx context.Context, restaurant *models.Restaurant, now time.Time) error {
+	if err := rr.Store.CountRequest(ctx, now); err != nil {
+		return err
+	}
+	updates, err := rr.FetchUpdates(ctx, restaurant)
+	if err != nil {
+		return err
+	}
+	return rr.Store.Save(ctx, restaurant, updates, now)
+}
+
+// FetchUpdates fetches restaurant from Google Places and returns the column updates that
+// bring it up to date. A place Google no longer knows yields no updates.
+func (rr *RestaurantRefresher) FetchUpdates(ctx context.Context, restaurant *models.Restaurant) (map[string]any, error) {
+	place, err := rr.Places.GetPlace(ctx, &placespb.GetPlaceRequest{Name: restaurant.GoogleID}, restaurantRefreshFields)
+	if status.Code(err) == codes.NotFound {
+		slog.Warn("Restaurant is no longer in Google Places",
+			slog.String("restaurant_id", restaurant.ID), slog.String("google_id", restaurant.GoogleID))
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", restaurant.GoogleID, err)
+	}
+
+	updates := map[string]any{}
+	if name := place.GetDisplayName().GetText(); name != "" && name != restaurant.Name {
+		updates["name"] = name
+	}
+	if address := place.GetFormattedAddress(); address != "" && address != restaurant.Address {
+		updates["address"] = address
+	}
+	if photos := place.GetPhotos(); len(photos) > 0 && photos[0].GetName() != restaurant.PhotoReference {
+		updates["photo_reference"] = photos[0].GetName()
+	}
+	if s := businessStatusOf(place); s != "" && s != restaurant.BusinessStatus {
+		updates["business_status"] = s
+	}
+	if loc := place.GetLocation(); loc != nil && !restaurant.HasLocation() {
+		updates["latitude"] = loc.GetLatitude()
+		updates["longitude"] = loc.GetLongitude()
+		updates["geohash"] = models.Geohash(loc.GetLatitude(), loc.GetLongitude(), models.GeohashPrecision)
+	}
+	return updates, nil
+}
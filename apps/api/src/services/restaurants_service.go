@@ -12,15 +12,17 @@ import (
 	"gorm.io/gorm"
 
 	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
 )
 
 type RestaurantsService struct {
 	v1connect.UnimplementedRestaurantsServiceHandler
-	DB *gorm.DB
+	DB     *gorm.DB
+	Valkey valkey.Client
 }
 
-func NewRestaurantsService(db *gorm.DB) *RestaurantsService {
-	return &RestaurantsService{DB: db}
+func NewRestaurantsService(db *gorm.DB, kv valkey.Client) *RestaurantsService {
+	return &RestaurantsService{DB: db, Valkey: kv}
 }
 
 func (s *RestaurantsService) CreateRestaurant(
@@ -7,9 +7,8 @@ import (
 	"api/src/internal/models"
 	"context"
 	"errors"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"connectrpc.com/connect"
@@ -23,10 +22,11 @@ type ReviewsService struct {
 	v1connect.UnimplementedReviewsServiceHandler
 	DB     *gorm.DB
 	Valkey valkey.Client
+	Places *PlaceResolver
 }
 
-func NewReviewsService(db *gorm.DB, kv valkey.Client) *ReviewsService {
-	return &ReviewsService{DB: db, Valkey: kv}
+func NewReviewsService(db *gorm.DB, kv valkey.Client, places *PlaceResolver) *ReviewsService {
+	return &ReviewsService{DB: db, Valkey: kv, Places: places}
 }
 
 func getUserIDFromSession(ctx context.Context, h http.Header, kv valkey.Client) (string, error) {
@@ -54,10 +54,28 @@ func (s *ReviewsService) CreateReview(
 		return nil, err
 	}
 
-	if req.Msg.GooglePlacesId == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errGooglePlacesIDRequired))
+	if err := validateGooglePlacesID(req.Msg.GooglePlacesId); err != nil {
+		return nil, err
+	}
+	if err := validateScores(req.Msg.Scores); err != nil {
+		return nil, err
+	}
+	if err := validateDishes(req.Msg.Dishes); err != nil {
+		return nil, err
+	}
+	if err := validateCoordinates(req.Msg.Latitude, req.Msg.Longitude); err != nil {
+		return nil, err
+	}
+	scored := models.Review{}
+	scored.SetScores(req.Msg.Scores)
+	rating := req.Msg.Rating
+	if req.Msg.RatingFromScores {
+		var ok bool
+		if rating, ok = scored.RatingFromScores(); !ok {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errScoresRequired))
+		}
 	}
-	if req.Msg.Rating < 1 || req.Msg.Rating > 5 {
+	if rating < 1 || rating > 5 {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("rating must be between 1 and 5"))
 	}
 	if req.Msg.PricePaidPerPerson < 0 {
@@ -67,41 +85,60 @@ func (s *ReviewsService) CreateReview(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid would_visit_again value"))
 	}
 
+	visibility, circles, err := resolveAudience(ctx, s.DB, userID, req.Msg.Visibility, req.Msg.CircleIds)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := resolveTags(ctx, s.DB, req.Msg.Tags)
+	if err != nil {
+		return nil, err
+	}
+	personalTags, err := resolvePersonalTags(ctx, s.DB, userID, req.Msg.PersonalTags)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.Places.Resolve(ctx, s.DB, req.Msg.GooglePlacesId, RestaurantHints{
+		Name:           req.Msg.RestaurantName,
+		Address:        req.Msg.RestaurantAddress,
+		City:           req.Msg.City,
+		Country:        req.Msg.Country,
+		PhotoReference: req.Msg.PhotoReference,
+		Latitude:       req.Msg.Latitude,
+		Longitude:      req.Msg.Longitude,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	var restaurant models.Restaurant
 	var review models.Review
 
 	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Find or create restaurant by Google Places ID
-		result := tx.Where(models.Restaurant{GoogleID: req.Msg.GooglePlacesId}).
-			Attrs(models.Restaurant{
-				Name:           req.Msg.RestaurantName,
-				Address:        req.Msg.RestaurantAddress,
-				City:           req.Msg.City,
-				Country:        req.Msg.Country,
-				PhotoReference: req.Msg.PhotoReference,
-			}).
-			FirstOrCreate(&restaurant)
-		if result.Error != nil {
-			return result.Error
-		}
-
-		// Backfill city/country/photo_reference on existing restaurants that were created before these fields were tracked.
-		if updates := missingRestaurantFields(&restaurant, req.Msg.City, req.Msg.Country, req.Msg.PhotoReference); len(updates) > 0 {
-			if err := tx.Model(&restaurant).Updates(updates).Error; err != nil {
-				return err
-			}
-			if v, ok := updates["city"].(string); ok {
-				restaurant.City = v
-			}
-			if v, ok := updates["country"].(string); ok {
-				restaurant.Country = v
-			}
-			if v, ok := updates["photo_reference"].(string); ok {
-				restaurant.PhotoReference = v
-			}
+		restaurant, err = saveRestaurant(tx, details)
+		if err != nil {
+			return err
 		}
 
 		// Remove from wishlist if present (review supersedes wishlist)
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_circles WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND restaurant_id = ?)",
+			userID, restaurant.ID,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND restaurant_id = ?)",
+			userID, restaurant.ID,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_personal_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND restaurant_id = ?)",
+			userID, restaurant.ID,
+		).Error; err != nil {
+			return err
+		}
 		if err := tx.Where("user_id = ? AND restaurant_id = ?", userID, restaurant.ID).
 			Delete(&models.WishlistItem{}).Error; err != nil {
 			return err
@@ -120,17 +157,30 @@ func (s *ReviewsService) CreateReview(
 			UserID:             userID,
 			GooglePlacesID:     req.Msg.GooglePlacesId,
 			Comment:            req.Msg.Comment,
-			Rating:             req.Msg.Rating,
-			Tags:               req.Msg.Tags,
+			Rating:             rating,
+			Tags:               tags,
+			PersonalTags:       personalTags,
 			PricePaidPerPerson: req.Msg.PricePaidPerPerson,
 			WouldVisitAgain:    int32(req.Msg.WouldVisitAgain),
 			DishHighlights:     req.Msg.DishHighlights,
+			Visibility:         visibility,
+			Circles:            circles,
 		}
 		if req.Msg.VisitedAt != 0 {
 			t := time.Unix(req.Msg.VisitedAt, 0)
 			review.VisitedAt = &t
 		}
-		return tx.Create(&review).Error
+		review.SetScores(req.Msg.Scores)
+		// Circles and tags already exist; only the join table references need inserting.
+		if err := tx.Omit("Circles.*", "Tags.*", "PersonalTags.*").Create(&review).Error; err != nil {
+			return err
+		}
+		dishes, err := saveReviewDishes(tx, review.ID, restaurant.ID, req.Msg.Dishes)
+		if err != nil {
+			return err
+		}
+		review.Dishes = dishes
+		return nil
 	})
 	if txErr != nil {
 		return nil, txErr
@@ -146,6 +196,9 @@ func (s *ReviewsService) CreateReview(
 	}
 	review.Restaurant = restaurant
 	review.User = currentUser
+
+	s.notifyWishlistWatchers(ctx, &review)
+
 	return connect.NewResponse(&v1.CreateReviewResponse{
 		Review:     review.ToProto(),
 		Restaurant: restaurant.ToProto(),
@@ -156,9 +209,9 @@ func (s *ReviewsService) ListReviews(
 	ctx context.Context,
 	req *connect.Request[v1.ListReviewsRequest],
 ) (*connect.Response[v1.ListReviewsResponse], error) {
-	// Validate rating range first (cheap, no DB or auth needed)
-	if req.Msg.MinRating > 0 && req.Msg.MaxRating > 0 && req.Msg.MinRating > req.Msg.MaxRating {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("min_rating must not exceed max_rating"))
+	// Validate filters first (cheap, no DB or auth needed)
+	if err := validateListReviewsRequest(req.Msg); err != nil {
+		return nil, err
 	}
 
 	if s.DB == nil {
@@ -179,47 +232,19 @@ func (s *ReviewsService) ListReviews(
 		targetUserID = req.Msg.TargetUserId
 	}
 
-	needsRestaurantJoin := req.Msg.City != "" || req.Msg.Country != ""
-
-	query := s.DB.WithContext(ctx).Preload("User").Where("reviews.user_id = ?", targetUserID)
+	query := preloadPersonalTags(s.DB.WithContext(ctx).Preload("User"), callerID).Where("reviews.user_id = ?", targetUserID)
 
-	if needsRestaurantJoin {
-		query = query.Joins("JOIN restaurants ON restaurants.id = reviews.restaurant_id").
-			Preload("Restaurant")
+	// Friends only see what the author shared with them; the author sees everything plus its audience.
+	if targetUserID != callerID {
+		query = applyReviewVisibility(query, callerID)
 	} else {
-		query = query.Preload("Restaurant")
-	}
-
-	if req.Msg.GooglePlacesId != "" {
-		query = query.Where("reviews.google_places_id = ?", req.Msg.GooglePlacesId)
-	}
-
-	// Tag filter
-	query = applyTagFilter(query, req.Msg.TagSlugs, req.Msg.TagFilterMode)
-
-	// Rating range
-	if req.Msg.MinRating > 0 {
-		query = query.Where("reviews.rating >= ?", req.Msg.MinRating)
-	}
-	if req.Msg.MaxRating > 0 {
-		query = query.Where("reviews.rating <= ?", req.Msg.MaxRating)
+		query = query.Preload("Circles")
 	}
 
-	// Comment keyword search (case-insensitive)
-	if req.Msg.CommentSearch != "" {
-		query = query.Where("reviews.comment ILIKE ?", "%"+req.Msg.CommentSearch+"%")
-	}
-
-	// City / country filter (requires restaurant join above)
-	if req.Msg.City != "" {
-		query = query.Where("restaurants.city ILIKE ?", "%"+req.Msg.City+"%")
-	}
-	if req.Msg.Country != "" {
-		query = query.Where("restaurants.country ILIKE ?", "%"+req.Msg.Country+"%")
-	}
+	query = applyListReviewsFilters(query, req.Msg, callerID)
 
 	// Sort order
-	query = applyReviewSort(query, req.Msg.SortBy)
+	query = applyReviewSort(query, req.Msg.SortBy, req.Msg.SortDimension)
 
 	var reviews []models.Review
 	if err := query.Find(&reviews).Error; err != nil {
@@ -247,28 +272,54 @@ func (s *ReviewsService) UpdateReview(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
 	}
 
-	if req.Msg.Rating < 1 || req.Msg.Rating > 5 {
+	if !req.Msg.RatingFromScores && (req.Msg.Rating < 1 || req.Msg.Rating > 5) {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("rating must be between 1 and 5"))
 	}
+	if err := validateScores(req.Msg.Scores); err != nil {
+		return nil, err
+	}
+	if err := validateDishes(req.Msg.Dishes.GetDishes()); err != nil {
+		return nil, err
+	}
 	if req.Msg.PricePaidPerPerson != nil && *req.Msg.PricePaidPerPerson < 0 {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("price_paid_per_person cannot be negative"))
 	}
 	if req.Msg.WouldVisitAgain != nil && (int32(*req.Msg.WouldVisitAgain) < 0 || int32(*req.Msg.WouldVisitAgain) > 3) {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid would_visit_again value"))
 	}
+	if req.Msg.Visibility == nil && len(req.Msg.CircleIds) > 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("circle_ids require visibility to be set"))
+	}
 
 	var review models.Review
-	if err := s.DB.WithContext(ctx).Preload("Restaurant").Preload("User").First(&review, reviewOwnerFilter, req.Msg.Id, userID).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Preload("Restaurant").Preload("User").Preload("Circles").Preload("Tags").Preload("PersonalTags").Preload("Dishes.Dish").First(&review, reviewOwnerFilter, req.Msg.Id, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, connect.NewError(connect.CodeNotFound, errors.New(errReviewNotFound))
 		}
 		return nil, err
 	}
 
+	var circles []models.Circle
+	if req.Msg.Visibility != nil {
+		review.Visibility, circles, err = resolveAudience(ctx, s.DB, userID, *req.Msg.Visibility, req.Msg.CircleIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tags, err := resolveTags(ctx, s.DB, req.Msg.Tags)
+	if err != nil {
+		return nil, err
+	}
+	var personalTags []models.PersonalTag
+	if req.Msg.PersonalTags != nil {
+		if personalTags, err = resolvePersonalTags(ctx, s.DB, userID, req.Msg.PersonalTags.Slugs); err != nil {
+			return nil, err
+		}
+	}
+
 	// Always-present fields (same contract as before this feature)
 	review.Comment = req.Msg.Comment
 	review.Rating = req.Msg.Rating
-	review.Tags = req.Msg.Tags
 
 	// Optional fields: only update when explicitly provided by the client.
 	if req.Msg.VisitedAt != nil {
@@ -288,9 +339,49 @@ func (s *ReviewsService) UpdateReview(
 	if req.Msg.DishHighlights != nil {
 		review.DishHighlights = *req.Msg.DishHighlights
 	}
+	if req.Msg.Scores != nil {
+		review.SetScores(req.Msg.Scores)
+	}
+	if req.Msg.RatingFromScores {
+		rating, ok := review.RatingFromScores()
+		if !ok {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errScoresRequired))
+		}
+		review.Rating = rating
+	}
 
-	if err := s.DB.WithContext(ctx).Save(&review).Error; err != nil {
-		return nil, err
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Circles", "Tags", "PersonalTags", "Dishes").Save(&review).Error; err != nil {
+			return err
+		}
+		if req.Msg.Dishes != nil {
+			dishes, err := saveReviewDishes(tx, review.ID, review.RestaurantID, req.Msg.Dishes.Dishes)
+			if err != nil {
+				return err
+			}
+			review.Dishes = dishes
+		}
+		if err := tx.Model(&review).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		review.Tags = tags
+		if req.Msg.PersonalTags != nil {
+			if err := tx.Model(&review).Association("PersonalTags").Replace(personalTags); err != nil {
+				return err
+			}
+			review.PersonalTags = personalTags
+		}
+		if req.Msg.Visibility == nil {
+			return nil
+		}
+		if err := tx.Model(&review).Association("Circles").Replace(circles); err != nil {
+			return err
+		}
+		review.Circles = circles
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
 
 	return connect.NewResponse(&v1.UpdateReviewResponse{Review: review.ToProto()}), nil
@@ -310,7 +401,7 @@ func (s *ReviewsService) GetReview(
 	}
 
 	var review models.Review
-	if err := s.DB.WithContext(ctx).Preload("Restaurant").Preload("User").First(&review, reviewOwnerFilter, req.Msg.Id, userID).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Preload("Restaurant").Preload("User").Preload("Circles").Preload("Tags").Preload("PersonalTags").Preload("Dishes.Dish").First(&review, reviewOwnerFilter, req.Msg.Id, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, connect.NewError(connect.CodeNotFound, errors.New(errReviewNotFound))
 		}
@@ -333,12 +424,32 @@ func (s *ReviewsService) DeleteReview(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
 	}
 
-	result := s.DB.WithContext(ctx).Where(reviewOwnerFilter, req.Msg.Id, userID).Delete(&models.Review{})
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return nil, connect.NewError(connect.CodeNotFound, errors.New(errReviewNotFound))
+	// Child rows go first: their foreign keys to reviews do not cascade.
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&models.Review{}).Select("id").Where(reviewOwnerFilter, req.Msg.Id, userID)
+		if err := tx.Exec("DELETE FROM review_circles WHERE review_id IN (?)", owned).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_tags WHERE review_id IN (?)", owned).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM review_personal_tags WHERE review_id IN (?)", owned).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id IN (?)", owned).Delete(&models.ReviewDish{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where(reviewOwnerFilter, req.Msg.Id, userID).Delete(&models.Review{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return connect.NewError(connect.CodeNotFound, errors.New(errReviewNotFound))
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
 
 	return connect.NewResponse(&v1.DeleteReviewResponse{Success: true}), nil
@@ -362,14 +473,15 @@ func (s *ReviewsService) ListRestaurantReviews(
 		return nil, err
 	}
 
-	visibleUserIDs := append(friendIDs, userID)
-
+	// The caller's own review is always visible; friends' reviews only when shared with the caller.
 	var reviews []models.Review
-	if err := s.DB.WithContext(ctx).
+	if err := applyOwnOrSharedReviews(preloadPersonalTags(s.DB.WithContext(ctx), userID), userID, friendIDs).
 		Preload("Restaurant").
 		Preload("User").
-		Where("google_places_id = ? AND user_id IN ?", req.Msg.GooglePlacesId, visibleUserIDs).
-		Order("created_at DESC").
+		Preload("Tags").
+		Preload("Dishes.Dish").
+		Where("reviews.google_places_id = ?", req.Msg.GooglePlacesId).
+		Order("reviews.created_at DESC").
 		Find(&reviews).Error; err != nil {
 		return nil, err
 	}
@@ -389,6 +501,7 @@ func (s *ReviewsService) ListRestaurantReviews(
 	resp := &v1.ListRestaurantReviewsResponse{
 		Reviews:       protos,
 		AverageRating: avgRating,
+		ScoreAverages: ScoreAverages(reviews),
 	}
 
 	// Populate restaurant metadata: prefer from reviews, fall back to a DB lookup.
@@ -432,40 +545,204 @@ func assertFriendship(ctx context.Context, db *gorm.DB, callerID, targetID strin
 	return nil
 }
 
-// applyTagFilter adds WHERE clauses for tag filtering based on mode (AND/OR).
-func applyTagFilter(query *gorm.DB, slugs []string, mode v1.TagFilterMode) *gorm.DB {
-	if len(slugs) == 0 {
-		return query
+// notifyWishlistWatchers tells friends who can see the review that a place on their
+// wishlist was reviewed. Reviews of the same place are coalesced per recipient.
+func (s *ReviewsService) notifyWishlistWatchers(ctx context.Context, review *models.Review) {
+	recipients, err := wishlistReviewRecipients(ctx, s.DB, review)
+	if err != nil {
+		slog.Warn("Failed to find wishlist notification recipients", slog.String("review_id", review.ID), slog.Any("error", err))
+		return
+	}
+	events := make([]notificationEvent, len(recipients))
+	for i, userID := range recipients {
+		events[i] = notificationEvent{
+			UserID:      userID,
+			Type:        models.NotificationTypeFriendReviewedWishlistPlace,
+			ActorID:     review.UserID,
+			SubjectID:   review.GooglePlacesID,
+			SubjectName: review.Restaurant.Name,
+		}
+	}
+	notify(ctx, s.DB, events...)
+}
+
+// validateListReviewsRequest checks the rating and sub-score filters of a ListReviews request.
+func validateListReviewsRequest(msg *v1.ListReviewsRequest) error {
+	if msg.MinRating > 0 && msg.MaxRating > 0 && msg.MinRating > msg.MaxRating {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("min_rating must not exceed max_rating"))
 	}
-	if mode == v1.TagFilterMode_TAG_FILTER_MODE_AND {
-		for _, slug := range slugs {
-			query = query.Where("reviews.tags LIKE ?", fmt.Sprintf(`%%"%s"%%`, slug))
+	for _, f := range msg.ScoreFilters {
+		if models.ScoreColumn(f.Dimension) == "" {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidScoreDimension))
+		}
+		if f.Min > 0 && f.Max > 0 && f.Min > f.Max {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New("score filter min must not exceed max"))
 		}
+	}
+	if msg.SortDimension != v1.ScoreDimension_SCORE_DIMENSION_UNSPECIFIED && models.ScoreColumn(msg.SortDimension) == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidScoreDimension))
+	}
+	return nil
+}
+
+// applyListReviewsFilters applies the filters of a ListReviews request (all but
+// target_user_id and the sort order) for viewerID.
+func applyListReviewsFilters(query *gorm.DB, msg *v1.ListReviewsRequest, viewerID string) *gorm.DB {
+	if msg.GooglePlacesId != "" {
+		query = query.Where("reviews.google_places_id = ?", msg.GooglePlacesId)
+	}
+
+	query = applyReviewFilters(query, reviewFilter{
+		TagSlugs:      msg.TagSlugs,
+		TagFilterMode: msg.TagFilterMode,
+		MinRating:     msg.MinRating,
+		MaxRating:     msg.MaxRating,
+		City:          msg.City,
+		Country:       msg.Country,
+		ViewerID:      viewerID,
+		Scores:        msg.ScoreFilters,
+	})
+
+	// Comment keyword search (case-insensitive)
+	if msg.CommentSearch != "" {
+		query = query.Where("reviews.comment ILIKE ?", "%"+msg.CommentSearch+"%")
+	}
+	return query
+}
+
+// reviewFilter holds the ListReviews filters that shared lists can also save.
+type reviewFilter struct {
+	TagSlugs      []string
+	TagFilterMode v1.TagFilterMode
+	MinRating     float64
+	MaxRating     float64
+	City          string
+	Country       string
+	// Personal tags in TagSlugs only match when this user may see them.
+	ViewerID string
+	Scores   []*v1.ScoreFilter
+}
+
+// applyReviewFilters adds tag, rating and location filters to a reviews query and preloads
+// the restaurant, tags and dishes, joining the restaurant when a city or country filter needs its columns.
+func applyReviewFilters(query *gorm.DB, f reviewFilter) *gorm.DB {
+	if f.City != "" || f.Country != "" {
+		query = query.Joins("JOIN restaurants ON restaurants.id = reviews.restaurant_id")
+	}
+	query = query.Preload("Restaurant").Preload("Tags").Preload("Dishes.Dish")
+
+	// Tag filter
+	query = applyTagFilter(query, f.TagSlugs, f.TagFilterMode, f.ViewerID)
+
+	// Rating range
+	if f.MinRating > 0 {
+		query = query.Where("reviews.rating >= ?", f.MinRating)
+	}
+	if f.MaxRating > 0 {
+		query = query.Where("reviews.rating <= ?", f.MaxRating)
+	}
+
+	// City / country filter (requires restaurant join above)
+	if f.City != "" {
+		query = query.Where("restaurants.city ILIKE ?", "%"+f.City+"%")
+	}
+	if f.Country != "" {
+		query = query.Where("restaurants.country ILIKE ?", "%"+f.Country+"%")
+	}
+
+	// Sub-score ranges; unscored reviews never match
+	for _, sf := range f.Scores {
+		column := "reviews." + models.ScoreColumn(sf.Dimension)
+		query = query.Where(column + " IS NOT NULL")
+		if sf.Min > 0 {
+			query = query.Where(column+" >= ?", sf.Min)
+		}
+		if sf.Max > 0 {
+			query = query.Where(column+" <= ?", sf.Max)
+		}
+	}
+	return query
+}
+
+// applyTagFilter adds WHERE clauses for tag filtering based on mode (AND/OR). Slugs match
+// global tags, and personal tags that viewerID may see.
+func applyTagFilter(query *gorm.DB, slugs []string, mode v1.TagFilterMode, viewerID string) *gorm.DB {
+	return applyTagCondition(query, tagCondition("reviews", "review_tags", "review_personal_tags", "review_id"), slugs,
+		mode == v1.TagFilterMode_TAG_FILTER_MODE_AND, viewerID)
+}
+
+// tagCondition returns a SQL condition (with placeholders for a slug list, the same list
+// again and a viewer ID) that is true for rows of table carrying at least one of the slugs
+// as a global tag, or as a personal tag the viewer may see.
+func tagCondition(table, tagsJoinTable, personalTagsJoinTable, joinColumn string) string {
+	return "(EXISTS (SELECT 1 FROM " + tagsJoinTable + " jt JOIN tags t ON t.id = jt.tag_id " +
+		"WHERE jt." + joinColumn + " = " + table + ".id AND t.slug IN ?) OR " +
+		"EXISTS (SELECT 1 FROM " + personalTagsJoinTable + " jp JOIN personal_tags pt ON pt.id = jp.personal_tag_id " +
+		"WHERE jp." + joinColumn + " = " + table + ".id AND pt.slug IN ? AND (pt.owner_id = ? OR " + sharesPersonalTags("pt.owner_id") + ")))"
+}
+
+// applyTagCondition requires every slug when matchAll is set, and at least one otherwise.
+func applyTagCondition(query *gorm.DB, condition string, slugs []string, matchAll bool, viewerID string) *gorm.DB {
+	if len(slugs) == 0 {
 		return query
 	}
-	// OR (default): at least one specified tag must appear
-	conditions := make([]string, len(slugs))
-	args := make([]any, len(slugs))
-	for i, slug := range slugs {
-		conditions[i] = "reviews.tags LIKE ?"
-		args[i] = fmt.Sprintf(`%%"%s"%%`, slug)
+	if !matchAll {
+		return query.Where(condition, slugs, slugs, viewerID)
+	}
+	for _, slug := range slugs {
+		query = query.Where(condition, []string{slug}, []string{slug}, viewerID)
 	}
-	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	return query
 }
 
-// applyReviewSort adds an ORDER BY clause based on the sort field.
-func applyReviewSort(query *gorm.DB, sortBy v1.ReviewSortBy) *gorm.DB {
+// applyReviewSort adds an ORDER BY clause based on the sort field. Rating sorts use the
+// given sub-score instead when one is set, with unscored reviews last.
+func applyReviewSort(query *gorm.DB, sortBy v1.ReviewSortBy, dimension v1.ScoreDimension) *gorm.DB {
+	rating := "reviews.rating"
+	if column := models.ScoreColumn(dimension); column != "" {
+		rating = "reviews." + column
+	}
 	switch sortBy {
 	case v1.ReviewSortBy_REVIEW_SORT_BY_DATE_ASC:
 		return query.Order("reviews.created_at ASC")
 	case v1.ReviewSortBy_REVIEW_SORT_BY_RATING_DESC:
-		return query.Order("reviews.rating DESC")
+		return query.Order(rating + " DESC NULLS LAST")
 	case v1.ReviewSortBy_REVIEW_SORT_BY_RATING_ASC:
-		return query.Order("reviews.rating ASC")
+		return query.Order(rating + " ASC NULLS LAST")
 	default: // UNSPECIFIED and DATE_DESC → newest first
 		return query.Order("reviews.created_at DESC")
 	}
 }
 
+// validateScores checks that every sub-score is unset (0) or 1–5 like the rating.
+func validateScores(scores *v1.ReviewScores) error {
+	for _, v := range []float64{scores.GetFood(), scores.GetService(), scores.GetAmbience(), scores.GetValue(), scores.GetDrinks()} {
+		if v != 0 && (v < 1 || v > 5) {
+			return connect.NewError(connect.CodeInvalidArgument, errors.New("scores must be between 1 and 5"))
+		}
+	}
+	return nil
+}
+
+// ScoreAverages returns the mean of each sub-score over the reviews that have it, in
+// dimension order.
+func ScoreAverages(reviews []models.Review) []*v1.ScoreAverage {
+	sums := map[v1.ScoreDimension]float64{}
+	counts := map[v1.ScoreDimension]int32{}
+	for i := range reviews {
+		for d, v := range reviews[i].Scores() {
+			sums[d] += v
+			counts[d]++
+		}
+	}
+	averages := []*v1.ScoreAverage{}
+	for d := v1.ScoreDimension_SCORE_DIMENSION_FOOD; d <= v1.ScoreDimension_SCORE_DIMENSION_DRINKS; d++ {
+		if counts[d] > 0 {
+			averages = append(averages, &v1.ScoreAverage{Dimension: d, Average: sums[d] / float64(counts[d]), Count: counts[d]})
+		}
+	}
+	return averages
+}
+
 // Ensure RestaurantProto import is used
 var _ = &restaurantspb.RestaurantProto{}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"api/src/internal/jobs"
+	"context"
+	"log/slog"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+var purgeSessionsJob = jobs.Kind[struct{}]{Name: "sessions.purge", MaxAttempts: 3}
+
+// RegisterSessionJobs registers the nightly purge of expired session tokens on r.
+func RegisterSessionJobs(r *jobs.Runner, kv valkey.Client) error {
+	jobs.Handle(r, purgeSessionsJob, func(ctx context.Context, _ struct{}) error {
+		removed, err := PurgeExpiredSessions(ctx, kv)
+		if removed > 0 {
+			slog.Info("Purged expired sessions", slog.Int("tokens", removed))
+		}
+		return err
+	})
+	return jobs.Every(r, purgeSessionsJob.Name, "17 3 * * *", purgeSessionsJob, struct{}{})
+}
+
+// PurgeExpiredSessions removes tokens whose session:<token> key has expired from every
+// user_sessions:<uid> set and returns how many were removed. The sets only expire when a
+// user stops logging in, so without this they keep growing for active users.
+func PurgeExpiredSessions(ctx context.Context, kv valkey.Client) (int, error) {
+	removed := 0
+	var cursor uint64
+	for {
+		page, err := kv.Do(ctx, kv.B().Scan().Cursor(cursor).Match("user_sessions:*").Count(500).Build()).AsScanEntry()
+		if err != nil {
+			return removed, err
+		}
+		for _, key := range page.Elements {
+			tokens, err := kv.Do(ctx, kv.B().Smembers().Key(key).Build()).AsStrSlice()
+			if err != nil {
+				return removed, err
+			}
+			for _, token := range tokens {
+				exists, err := kv.Do(ctx, kv.B().Exists().Key("session:"+token).Build()).AsInt64()
+				if err != nil {
+					return removed, err
+				}
+				if exists > 0 {
+					continue
+				}
+				// Valkey deletes the set when its last member goes.
+				if err := kv.Do(ctx, kv.B().Srem().Key(key).Member(token).Build()).Error(); err != nil {
+					return removed, err
+				}
+				removed++
+			}
+		}
+		if cursor = page.Cursor; cursor == 0 {
+			return removed, nil
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	reviewsv1 "api/src/generated/reviews/v1"
+	sharedlistsv1 "api/src/generated/shared_lists/v1"
+	"api/src/generated/shared_lists/v1/v1connect"
+	"api/src/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/valkey-io/valkey-go"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+const (
+	sharedListOwnerFilter   = "id = ? AND owner_id = ?"
+	maxSharedListTitleLen   = 100
+	maxSharedListDescLen    = 1000
+	maxSharedListReviews    = 200
+	sharedListSlugByteCount = 16
+)
+
+type SharedListsService struct {
+	v1connect.UnimplementedSharedListsServiceHandler
+	DB     *gorm.DB
+	Valkey valkey.Client
+}
+
+func NewSharedListsService(db *gorm.DB, kv valkey.Client) *SharedListsService {
+	return &SharedListsService{DB: db, Valkey: kv}
+}
+
+func (s *SharedListsService) CreateSharedList(
+	ctx context.Context,
+	req *connect.Request[sharedlistsv1.CreateSharedListRequest],
+) (*connect.Response[sharedlistsv1.CreateSharedListResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateSharedListRequest(req.Msg, time.Now()); err != nil {
+		return nil, err
+	}
+
+	slug, err := newSharedListSlug()
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	list := models.SharedList{
+		OwnerID:     userID,
+		Slug:        slug,
+		Title:       strings.TrimSpace(req.Msg.Title),
+		Description: strings.TrimSpace(req.Msg.Description),
+		Mode:        models.SharedListModeFromProto(req.Msg.Mode),
+		Filter:      models.SharedListFilterFromProto(req.Msg.Filter),
+	}
+	if req.Msg.ExpiresAt > 0 {
+		expiresAt := time.Unix(req.Msg.ExpiresAt, 0)
+		list.ExpiresAt = &expiresAt
+	}
+
+	switch {
+	case list.Filter == nil:
+		// Hand-picked lists are always a fixed set of reviews.
+		list.Mode = models.SharedListModeSnapshot
+		list.ReviewIDs, err = resolvePickedReviews(ctx, s.DB, userID, req.Msg.ReviewIds)
+	case list.Mode == models.SharedListModeSnapshot:
+		list.ReviewIDs, err = snapshotReviewIDs(ctx, s.DB, userID, list.Filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if list.Mode == models.SharedListModeSnapshot {
+		if list.Snapshot, err = renderSharedListSnapshot(ctx, s.DB, &list); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.DB.WithContext(ctx).Create(&list).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&sharedlistsv1.CreateSharedListResponse{List: list.ToProto()}), nil
+}
+
+func (s *SharedListsService) ListSharedLists(
+	ctx context.Context,
+	req *connect.Request[sharedlistsv1.ListSharedListsRequest],
+) (*connect.Response[sharedlistsv1.ListSharedListsResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []models.SharedList
+	if err := s.DB.WithContext(ctx).Where("owner_id = ?", userID).
+		Order("created_at DESC").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+
+	protos := make([]*sharedlistsv1.SharedListProto, len(lists))
+	for i, l := range lists {
+		protos[i] = l.ToProto()
+	}
+
+	return connect.NewResponse(&sharedlistsv1.ListSharedListsResponse{Lists: protos}), nil
+}
+
+func (s *SharedListsService) RevokeSharedList(
+	ctx context.Context,
+	req *connect.Request[sharedlistsv1.RevokeSharedListRequest],
+) (*connect.Response[sharedlistsv1.RevokeSharedListResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
+	}
+
+	var list models.SharedList
+	if err := s.DB.WithContext(ctx).First(&list, sharedListOwnerFilter, req.Msg.Id, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New(errSharedListNotFound))
+		}
+		return nil, err
+	}
+
+	if list.RevokedAt == nil {
+		now := time.Now()
+		list.RevokedAt = &now
+		if err := s.DB.WithContext(ctx).Save(&list).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return connect.NewResponse(&sharedlistsv1.RevokeSharedListResponse{List: list.ToProto()}), nil
+}
+
+func (s *SharedListsService) DeleteSharedList(
+	ctx context.Context,
+	req *connect.Request[sharedlistsv1.DeleteSharedListRequest],
+) (*connect.Response[sharedlistsv1.DeleteSharedListResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.Id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errIDRequired))
+	}
+
+	result := s.DB.WithContext(ctx).Where(sharedListOwnerFilter, req.Msg.Id, userID).Delete(&models.SharedList{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New(errSharedListNotFound))
+	}
+
+	return connect.NewResponse(&sharedlistsv1.DeleteSharedListResponse{Success: true}), nil
+}
+
+func (s *SharedListsService) GetSharedList(
+	ctx context.Context,
+	req *connect.Request[sharedlistsv1.GetSharedListRequest],
+) (*connect.Response[sharedlistsv1.GetSharedListResponse], error) {
+	if req.Msg.Slug == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("slug is required"))
+	}
+
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+
+	// Unknown, revoked and expired links are indistinguishable to the caller.
+	var list models.SharedList
+	if err := s.DB.WithContext(ctx).Preload("Owner").First(&list, "slug = ?", req.Msg.Slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New(errSharedListNotFound))
+		}
+		return nil, err
+	}
+	if !list.IsAccessible(time.Now()) {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New(errSharedListNotFound))
+	}
+
+	protos, err := sharedListContent(ctx, s.DB, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	// UpdateColumn skips hooks and updated_at, so views don't look like edits.
+	if err := s.DB.WithContext(ctx).Model(&models.SharedList{}).Where("id = ?", list.ID).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&sharedlistsv1.GetSharedListResponse{
+		Title:       list.Title,
+		Description: list.Description,
+		OwnerName:   list.Owner.Name,
+		Reviews:     protos,
+		CreatedAt:   list.CreatedAt.Unix(),
+		UpdatedAt:   list.UpdatedAt.Unix(),
+	}), nil
+}
+
+// validateSharedListRequest checks a CreateSharedList request without touching the database.
+func validateSharedListRequest(msg *sharedlistsv1.CreateSharedListRequest, now time.Time) error {
+	title := strings.TrimSpace(msg.Title)
+	if title == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("title is required"))
+	}
+	if len([]rune(title)) > maxSharedListTitleLen {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("title must be at most 100 characters"))
+	}
+	if len([]rune(strings.TrimSpace(msg.Description))) > maxSharedListDescLen {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("description must be at most 1000 characters"))
+	}
+
+	if (msg.Filter == nil) == (len(msg.ReviewIds) == 0) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("exactly one of filter or review_ids is required"))
+	}
+	if len(msg.ReviewIds) > maxSharedListReviews {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("a shared list can contain at most 200 reviews"))
+	}
+	if f := msg.Filter; f != nil && f.MinRating > 0 && f.MaxRating > 0 && f.MinRating > f.MaxRating {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("min_rating must not exceed max_rating"))
+	}
+
+	if msg.ExpiresAt < 0 || (msg.ExpiresAt > 0 && !time.Unix(msg.ExpiresAt, 0).After(now)) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("expires_at must be in the future"))
+	}
+	return nil
+}
+
+// newSharedListSlug returns a random URL-safe slug with 128 bits of entropy.
+func newSharedListSlug() (string, error) {
+	b := make([]byte, sharedListSlugByteCount)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// publicReviewsQuery returns the owner's reviews that may appear on a public list.
+// Only friends-visible reviews qualify; private and circle-only reviews never leak.
+func publicReviewsQuery(ctx context.Context, db *gorm.DB, ownerID string) *gorm.DB {
+	return db.WithContext(ctx).Preload("User").Preload("Tags").Preload("Dishes.Dish").
+		Where("reviews.user_id = ? AND reviews.visibility = ?", ownerID, models.VisibilityFriends)
+}
+
+// resolvePickedReviews validates hand-picked review IDs and returns them de-duplicated, in order.
+func resolvePickedReviews(ctx context.Context, db *gorm.DB, ownerID string, ids []string) ([]string, error) {
+	reviewIDs := uniqueStrings(ids)
+
+	var reviews []models.Review
+	if err := db.WithContext(ctx).Select("id", "visibility").
+		Where("user_id = ? AND id IN ?", ownerID, reviewIDs).Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	if len(reviews) != len(reviewIDs) {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New(errReviewNotFound))
+	}
+	for _, r := range reviews {
+		if r.Visibility != models.VisibilityFriends {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("only reviews visible to all friends can be shared publicly"))
+		}
+	}
+	return reviewIDs, nil
+}
+
+// snapshotReviewIDs evaluates a filter once and returns the matching review IDs in list order.
+func snapshotReviewIDs(ctx context.Context, db *gorm.DB, ownerID string, f *models.SharedListFilter) ([]string, error) {
+	var reviews []models.Review
+	query := applySharedListFilter(publicReviewsQuery(ctx, db, ownerID), ownerID, f)
+	if err := query.Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(reviews))
+	for i, r := range reviews {
+		ids[i] = r.ID
+	}
+	return ids, nil
+}
+
+// loadSharedListReviews returns the reviews currently shown on a list. Fixed lists keep
+// their stored order and silently drop reviews that were deleted or made non-public.
+func loadSharedListReviews(ctx context.Context, db *gorm.DB, list *models.SharedList) ([]models.Review, error) {
+	if list.Mode == models.SharedListModeLive && list.Filter != nil {
+		var reviews []models.Review
+		if err := applySharedListFilter(publicReviewsQuery(ctx, db, list.OwnerID), list.OwnerID, list.Filter).Find(&reviews).Error; err != nil {
+			return nil, err
+		}
+		return reviews, nil
+	}
+
+	if len(list.ReviewIDs) == 0 {
+		return []models.Review{}, nil
+	}
+	var found []models.Review
+	if err := publicReviewsQuery(ctx, db, list.OwnerID).Preload("Restaurant").
+		Where("reviews.id IN ?", list.ReviewIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Review, len(found))
+	for _, r := range found {
+		byID[r.ID] = r
+	}
+	reviews := make([]models.Review, 0, len(found))
+	for _, id := range list.ReviewIDs {
+		if r, ok := byID[id]; ok {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews, nil
+}
+
+// renderSharedListSnapshot returns the frozen public content of a snapshot list's reviews.
+func renderSharedListSnapshot(ctx context.Context, db *gorm.DB, list *models.SharedList) ([]byte, error) {
+	reviews, err := loadSharedListReviews(ctx, db, list)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &sharedlistsv1.SharedListSnapshot{Reviews: make([]*reviewsv1.ReviewProto, len(reviews))}
+	for i, r := range reviews {
+		snapshot.Reviews[i] = r.ToPublicProto()
+	}
+	return proto.Marshal(snapshot)
+}
+
+// sharedListContent returns the public reviews a list shows. Snapshots show their
+// reviews as published, minus those since deleted or made non-public; other lists
+// show the reviews as they are now.
+func sharedListContent(ctx context.Context, db *gorm.DB, list *models.SharedList) ([]*reviewsv1.ReviewProto, error) {
+	if list.Mode == models.SharedListModeSnapshot && list.Snapshot != nil {
+		var snapshot sharedlistsv1.SharedListSnapshot
+		if err := proto.Unmarshal(list.Snapshot, &snapshot); err != nil {
+			return nil, err
+		}
+		if len(snapshot.Reviews) == 0 {
+			return []*reviewsv1.ReviewProto{}, nil
+		}
+		var public []string
+		if err := db.WithContext(ctx).Model(&models.Review{}).
+			Where("user_id = ? AND visibility = ? AND id IN ?", list.OwnerID, models.VisibilityFriends, list.ReviewIDs).
+			Pluck("id", &public).Error; err != nil {
+			return nil, err
+		}
+		return FilterSnapshotReviews(snapshot.Reviews, public), nil
+	}
+
+	reviews, err := loadSharedListReviews(ctx, db, list)
+	if err != nil {
+		return nil, err
+	}
+	protos := make([]*reviewsv1.ReviewProto, len(reviews))
+	for i, r := range reviews {
+		protos[i] = r.ToPublicProto()
+	}
+	return protos, nil
+}
+
+// FilterSnapshotReviews returns the snapshot reviews whose IDs are in public, in order.
+func FilterSnapshotReviews(reviews []*reviewsv1.ReviewProto, public []string) []*reviewsv1.ReviewProto {
+	keep := make(map[string]bool, len(public))
+	for _, id := range public {
+		keep[id] = true
+	}
+	out := make([]*reviewsv1.ReviewProto, 0, len(reviews))
+	for _, r := range reviews {
+		if keep[r.Id] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// applySharedListFilter applies a saved filter and its sort order to a reviews query.
+// The filter is the owner's, so it may match the owner's personal tags.
+func applySharedListFilter(query *gorm.DB, ownerID string, f *models.SharedListFilter) *gorm.DB {
+	query = applyReviewFilters(query, reviewFilter{
+		TagSlugs:      f.TagSlugs,
+		TagFilterMode: reviewsv1.TagFilterMode(f.TagFilterMode),
+		MinRating:     f.MinRating,
+		MaxRating:     f.MaxRating,
+		City:          f.City,
+		Country:       f.Country,
+		ViewerID:      ownerID,
+	})
+	return applyReviewSort(query, reviewsv1.ReviewSortBy(f.SortBy), reviewsv1.ScoreDimension_SCORE_DIMENSION_UNSPECIFIED)
+}
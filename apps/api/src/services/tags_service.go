@@ -6,15 +6,23 @@ import (
 	"api/src/internal/cache"
 	"api/src/internal/models"
 	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"errors"
 
 	"connectrpc.com/connect"
 	"github.com/valkey-io/valkey-go"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// tagsCacheKey prefixes the cached ListTags response of each locale.
 const tagsCacheKey = "tags:all"
 const tagsCacheTTL = time.Hour
 
@@ -30,13 +38,16 @@ func NewTagsService(db *gorm.DB, kv valkey.Client) *TagsService {
 
 func (s *TagsService) ListTags(
 	ctx context.Context,
-	_ *connect.Request[tagsv1.ListTagsRequest],
+	req *connect.Request[tagsv1.ListTagsRequest],
 ) (*connect.Response[tagsv1.ListTagsResponse], error) {
+	locale := s.listLocale(ctx, req)
+	cacheKey := tagsCacheKey + ":" + locale
+
 	// Try cache first (skip if Valkey is nil, e.g. in tests)
 	if s.Valkey != nil {
 		pc := cache.NewProtoCache(s.Valkey, tagsCacheTTL, "")
 		cached := &tagsv1.ListTagsResponse{}
-		if ok, _ := pc.Get(ctx, tagsCacheKey, cached); ok {
+		if ok, _ := pc.Get(ctx, cacheKey, cached); ok {
 			return connect.NewResponse(cached), nil
 		}
 	}
@@ -46,21 +57,503 @@ func (s *TagsService) ListTags(
 	}
 
 	var tags []models.Tag
-	if err := s.DB.WithContext(ctx).Order("category, label").Find(&tags).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Find(&tags).Error; err != nil {
 		return nil, err
 	}
+	labels := map[string]string{}
+	if locale != models.DefaultTagLocale {
+		var translations []models.TagTranslation
+		if err := s.DB.WithContext(ctx).Where("locale = ?", locale).Find(&translations).Error; err != nil {
+			return nil, err
+		}
+		for _, t := range translations {
+			labels[t.TagSlug] = t.Label
+		}
+	}
 
 	protos := make([]*tagsv1.TagProto, len(tags))
 	for i, t := range tags {
 		protos[i] = t.ToProto()
+		if label, ok := labels[t.Slug]; ok {
+			protos[i].Label = label
+		}
 	}
+	sortTagProtos(protos, locale)
 	resp := &tagsv1.ListTagsResponse{Tags: protos}
 
 	// Populate cache
 	if s.Valkey != nil {
 		pc := cache.NewProtoCache(s.Valkey, tagsCacheTTL, "")
-		pc.Set(ctx, tagsCacheKey, resp)
+		pc.Set(ctx, cacheKey, resp)
 	}
 
 	return connect.NewResponse(resp), nil
 }
+
+// listLocale picks the language for ListTags: the requested one, else the caller's
+// DefaultLanguage, else English. Anonymous callers are fine.
+func (s *TagsService) listLocale(ctx context.Context, req *connect.Request[tagsv1.ListTagsRequest]) string {
+	if locale := models.NormalizeTagLocale(req.Msg.Locale); locale != "" {
+		return locale
+	}
+	if s.Valkey != nil && s.DB != nil && sessionToken(req.Header()) != "" {
+		if userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey); err == nil {
+			var user models.User
+			if err := s.DB.WithContext(ctx).Select("id", "default_language").First(&user, "id = ?", userID).Error; err == nil {
+				if locale := models.NormalizeTagLocale(user.DefaultLanguage); locale != "" {
+					return locale
+				}
+			}
+		}
+	}
+	return models.DefaultTagLocale
+}
+
+// sortTagProtos orders tags by category, then label, using the collation of locale.
+func sortTagProtos(tags []*tagsv1.TagProto, locale string) {
+	c := collate.New(language.Make(locale), collate.IgnoreCase)
+	sort.SliceStable(tags, func(i, j int) bool {
+		if n := c.CompareString(tags[i].Category, tags[j].Category); n != 0 {
+			return n < 0
+		}
+		return c.CompareString(tags[i].Label, tags[j].Label) < 0
+	})
+}
+
+func (s *TagsService) GetTagStats(
+	ctx context.Context,
+	req *connect.Request[tagsv1.GetTagStatsRequest],
+) (*connect.Response[tagsv1.GetTagStatsResponse], error) {
+	if s.DB == nil {
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errDatabaseNotInitialized))
+	}
+	userID, err := getUserIDFromSession(ctx, req.Header(), s.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	mine, err := tagStats(ctx, s.DB, []string{userID}, "")
+	if err != nil {
+		return nil, err
+	}
+	friendIDs, err := getFriendIDs(ctx, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	friends := []*tagsv1.TagStat{}
+	if len(friendIDs) > 0 {
+		if friends, err = tagStats(ctx, s.DB, friendIDs, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return connect.NewResponse(&tagsv1.GetTagStatsResponse{Mine: mine, Friends: friends}), nil
+}
+
+// tagStats counts the tags on the reviews and wishlist items of userIDs, most used first.
+// With a viewerID, only rows shared with that viewer are counted.
+func tagStats(ctx context.Context, db *gorm.DB, userIDs []string, viewerID string) ([]*tagsv1.TagStat, error) {
+	var reviewRows []struct {
+		Slug          string
+		Count         int32
+		AverageRating float64
+	}
+	reviews := db.WithContext(ctx).Table("reviews").
+		Select("t.slug, COUNT(*) AS count, AVG(reviews.rating) AS average_rating").
+		Joins("JOIN review_tags rt ON rt.review_id = reviews.id").
+		Joins("JOIN tags t ON t.id = rt.tag_id").
+		Where("reviews.user_id IN ?", userIDs).
+		Group("t.slug")
+	if viewerID != "" {
+		reviews = applyReviewVisibility(reviews, viewerID)
+	}
+	if err := reviews.Scan(&reviewRows).Error; err != nil {
+		return nil, err
+	}
+
+	var wishlistRows []struct {
+		Slug  string
+		Count int32
+	}
+	wishlist := db.WithContext(ctx).Table("wishlist_items").
+		Select("t.slug, COUNT(*) AS count").
+		Joins("JOIN wishlist_item_tags wt ON wt.wishlist_item_id = wishlist_items.id").
+		Joins("JOIN tags t ON t.id = wt.tag_id").
+		Where("wishlist_items.user_id IN ?", userIDs).
+		Group("t.slug")
+	if viewerID != "" {
+		wishlist = applyWishlistVisibility(wishlist, viewerID)
+	}
+	if err := wishlist.Scan(&wishlistRows).Error; err != nil {
+		return nil, err
+	}
+
+	bySlug := map[string]*tagsv1.TagStat{}
+	stat := func(slug string) *tagsv1.TagStat {
+		if bySlug[slug] == nil {
+			bySlug[slug] = &tagsv1.TagStat{Slug: slug}
+		}
+		return bySlug[slug]
+	}
+	for _, r := range reviewRows {
+		st := stat(r.Slug)
+		st.ReviewCount = r.Count
+		st.AverageRating = r.AverageRating
+	}
+	for _, r := range wishlistRows {
+		stat(r.Slug).WishlistCount = r.Count
+	}
+
+	stats := make([]*tagsv1.TagStat, 0, len(bySlug))
+	for _, st := range bySlug {
+		stats = append(stats, st)
+	}
+	SortTagStats(stats)
+	return stats, nil
+}
+
+// SortTagStats orders stats by total uses, most used first, then by slug.
+func SortTagStats(stats []*tagsv1.TagStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		a := stats[i].ReviewCount + stats[i].WishlistCount
+		b := stats[j].ReviewCount + stats[j].WishlistCount
+		if a != b {
+			return a > b
+		}
+		return stats[i].Slug < stats[j].Slug
+	})
+}
+
+func (s *TagsService) CreateTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.CreateTagRequest],
+) (*connect.Response[tagsv1.CreateTagResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	tag := models.Tag{
+		Slug:     strings.TrimSpace(req.Msg.Slug),
+		Label:    strings.TrimSpace(req.Msg.Label),
+		Category: strings.TrimSpace(req.Msg.Category),
+	}
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	translations, _, err := tagTranslations(tag.Slug, req.Msg.Translations)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Slugs of deprecated tags stay taken: reviews may still carry them.
+		res := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return connect.NewError(connect.CodeAlreadyExists, errors.New(errTagExists))
+		}
+		return upsertTagTranslations(tx, translations)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := invalidateTagsCache(ctx, s.Valkey); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&tagsv1.CreateTagResponse{Tag: tag.ToProto()}), nil
+}
+
+func (s *TagsService) UpdateTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.UpdateTagRequest],
+) (*connect.Response[tagsv1.UpdateTagResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	tag, err := s.findTag(ctx, req.Msg.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.Label != nil {
+		tag.Label = strings.TrimSpace(req.Msg.GetLabel())
+	}
+	if req.Msg.Category != nil {
+		tag.Category = strings.TrimSpace(req.Msg.GetCategory())
+	}
+	if err := validateTag(*tag); err != nil {
+		return nil, err
+	}
+	translations, removed, err := tagTranslations(tag.Slug, req.Msg.Translations)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(tag).Updates(map[string]any{"label": tag.Label, "category": tag.Category}).Error; err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := tx.Where("tag_slug = ? AND locale IN ?", tag.Slug, removed).Delete(&models.TagTranslation{}).Error; err != nil {
+				return err
+			}
+		}
+		return upsertTagTranslations(tx, translations)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := invalidateTagsCache(ctx, s.Valkey); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&tagsv1.UpdateTagResponse{Tag: tag.ToProto()}), nil
+}
+
+func (s *TagsService) DeprecateTag(
+	ctx context.Context,
+	req *connect.Request[tagsv1.DeprecateTagRequest],
+) (*connect.Response[tagsv1.DeprecateTagResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	tag, err := s.findTag(ctx, req.Msg.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.Msg.Restore:
+		tag.DeprecatedAt = nil
+	case tag.DeprecatedAt == nil:
+		now := time.Now()
+		tag.DeprecatedAt = &now
+	}
+	if err := s.DB.WithContext(ctx).Model(tag).Update("deprecated_at", tag.DeprecatedAt).Error; err != nil {
+		return nil, err
+	}
+	if err := invalidateTagsCache(ctx, s.Valkey); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&tagsv1.DeprecateTagResponse{Tag: tag.ToProto()}), nil
+}
+
+func (s *TagsService) MergeTags(
+	ctx context.Context,
+	req *connect.Request[tagsv1.MergeTagsRequest],
+) (*connect.Response[tagsv1.MergeTagsResponse], error) {
+	if _, err := requireAdmin(ctx, req.Header(), s.DB, s.Valkey); err != nil {
+		return nil, err
+	}
+	sources := uniqueStrings(req.Msg.SourceSlugs)
+	if len(sources) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errMergeSourcesRequired))
+	}
+	for _, slug := range sources {
+		if slug == req.Msg.TargetSlug {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errMergeTargetIsSource))
+		}
+	}
+	target, err := s.findTag(ctx, req.Msg.TargetSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews, wishlistItems int
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sourceIDs []string
+		if err := tx.Model(&models.Tag{}).Where("slug IN ?", sources).Pluck("id", &sourceIDs).Error; err != nil {
+			return err
+		}
+		if len(sourceIDs) != len(sources) {
+			return connect.NewError(connect.CodeNotFound, errors.New(errTagNotFound))
+		}
+
+		var err error
+		if reviews, err = retagRows(tx, "review_tags", "review_id", sourceIDs, target.ID); err != nil {
+			return err
+		}
+		if wishlistItems, err = retagRows(tx, "wishlist_item_tags", "wishlist_item_id", sourceIDs, target.ID); err != nil {
+			return err
+		}
+		return tx.Where("slug IN ?", sources).Delete(&models.Tag{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := invalidateTagsCache(ctx, s.Valkey); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&tagsv1.MergeTagsResponse{
+		Tag:                  target.ToProto(),
+		ReviewsUpdated:       int32(reviews),
+		WishlistItemsUpdated: int32(wishlistItems),
+	}), nil
+}
+
+func (s *TagsService) findTag(ctx context.Context, slug string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := s.DB.WithContext(ctx).First(&tag, "slug = ?", slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New(errTagNotFound))
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// retagRows moves the rows of a tags join table from the source tags to target and returns
+// how many reviews or wishlist items carried a source tag.
+func retagRows(tx *gorm.DB, joinTable, ownerColumn string, sourceIDs []string, targetID string) (int, error) {
+	return moveTagRows(tx, joinTable, "tag_id", joinTable, ownerColumn, sourceIDs, targetID)
+}
+
+// moveTagRows replaces the rows of fromTable whose fromColumn is one of sourceIDs with
+// rows of the tags join table toTable pointing at targetID, and returns how many reviews
+// or wishlist items that touched. Both tables key the owner by ownerColumn.
+func moveTagRows(tx *gorm.DB, fromTable, fromColumn, toTable, ownerColumn string, sourceIDs []string, targetID string) (int, error) {
+	var owners int64
+	if err := tx.Table(fromTable).Where(fromColumn+" IN ?", sourceIDs).Distinct(ownerColumn).Count(&owners).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Exec(
+		"INSERT INTO "+toTable+" ("+ownerColumn+", tag_id) SELECT DISTINCT "+ownerColumn+", ? FROM "+fromTable+
+			" WHERE "+fromColumn+" IN ? ON CONFLICT DO NOTHING",
+		targetID, sourceIDs,
+	).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Exec("DELETE FROM "+fromTable+" WHERE "+fromColumn+" IN ?", sourceIDs).Error; err != nil {
+		return 0, err
+	}
+	return int(owners), nil
+}
+
+// resolveTags returns the tags for slugs, ignoring blanks and duplicates. Unknown slugs
+// are rejected; deprecated tags are allowed so edits can keep them.
+func resolveTags(ctx context.Context, db *gorm.DB, slugs []string) ([]models.Tag, error) {
+	unique := uniqueStrings(slugs)
+	tags := []models.Tag{}
+	if len(unique) == 0 {
+		return tags, nil
+	}
+	if err := db.WithContext(ctx).Where("slug IN ?", unique).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(unique) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errUnknownTag))
+	}
+	return tags, nil
+}
+
+func validateTag(tag models.Tag) error {
+	if !tagSlugPattern.MatchString(tag.Slug) {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidTagSlug))
+	}
+	if tag.Label == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errTagLabelRequired))
+	}
+	if tag.Category == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errTagCategoryRequired))
+	}
+	return nil
+}
+
+var tagSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ImportTags upserts tags by slug, updating the label, category and translations of
+// existing ones, and drops the cached tag lists. Tags and translations missing from the
+// input are left alone.
+func ImportTags(ctx context.Context, db *gorm.DB, kv valkey.Client, tags []models.Tag) error {
+	seen := make(map[string]bool, len(tags))
+	var translations []models.TagTranslation
+	for i, t := range tags {
+		if !tagSlugPattern.MatchString(t.Slug) {
+			return fmt.Errorf("tag %d: invalid slug %q", i, t.Slug)
+		}
+		if strings.TrimSpace(t.Label) == "" || strings.TrimSpace(t.Category) == "" {
+			return fmt.Errorf("tag %q: label and category are required", t.Slug)
+		}
+		if seen[t.Slug] {
+			return fmt.Errorf("tag %q appears twice", t.Slug)
+		}
+		seen[t.Slug] = true
+		for _, tr := range t.Translations {
+			if !isTranslationLocale(tr.Locale) || strings.TrimSpace(tr.Label) == "" {
+				return fmt.Errorf("tag %q: invalid translation %q: %q", t.Slug, tr.Locale, tr.Label)
+			}
+			translations = append(translations, models.TagTranslation{TagSlug: t.Slug, Locale: tr.Locale, Label: tr.Label})
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "slug"}},
+			DoUpdates: clause.AssignmentColumns([]string{"label", "category"}),
+		}).Create(&tags).Error; err != nil {
+			return err
+		}
+		return upsertTagTranslations(tx, translations)
+	})
+	if err != nil {
+		return err
+	}
+	return invalidateTagsCache(ctx, kv)
+}
+
+// tagTranslations validates the translations of an admin request. Locales with an empty
+// label are returned separately, to be removed.
+func tagTranslations(slug string, in map[string]string) (set []models.TagTranslation, remove []string, err error) {
+	for locale, label := range in {
+		if !isTranslationLocale(locale) {
+			return nil, nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidTagLocale))
+		}
+		if label = strings.TrimSpace(label); label == "" {
+			remove = append(remove, locale)
+			continue
+		}
+		set = append(set, models.TagTranslation{TagSlug: slug, Locale: locale, Label: label})
+	}
+	return set, remove, nil
+}
+
+// isTranslationLocale reports whether locale is a normalized language code other than
+// English, which lives in Tag.Label.
+func isTranslationLocale(locale string) bool {
+	return models.NormalizeTagLocale(locale) == locale && locale != models.DefaultTagLocale
+}
+
+func upsertTagTranslations(tx *gorm.DB, translations []models.TagTranslation) error {
+	if len(translations) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tag_slug"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"label"}),
+	}).Create(&translations).Error
+}
+
+// invalidateTagsCache drops the cached tag list of every locale.
+func invalidateTagsCache(ctx context.Context, kv valkey.Client) error {
+	if kv == nil {
+		return nil
+	}
+	var cursor uint64
+	for {
+		page, err := kv.Do(ctx, kv.B().Scan().Cursor(cursor).Match(tagsCacheKey+":*").Count(100).Build()).AsScanEntry()
+		if err != nil {
+			return err
+		}
+		if len(page.Elements) > 0 {
+			if err := kv.Do(ctx, kv.B().Del().Key(page.Elements...).Build()).Error(); err != nil {
+				return err
+			}
+		}
+		if cursor = page.Cursor; cursor == 0 {
+			return nil
+		}
+	}
+}
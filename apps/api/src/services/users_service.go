@@ -1,6 +1,7 @@
 package services
 
 import (
+	circlesv1 "api/src/generated/circles/v1"
 	v1 "api/src/generated/users/v1"
 	"api/src/generated/users/v1/v1connect"
 	"api/src/internal/models"
@@ -8,18 +9,21 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/valkey-io/valkey-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"connectrpc.com/connect"
 )
 
 type UserService struct {
 	v1connect.UnimplementedUsersServiceHandler
-	DB *gorm.DB
+	DB     *gorm.DB
+	Valkey valkey.Client
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{DB: db}
+func NewUserService(db *gorm.DB, kv valkey.Client) *UserService {
+	return &UserService{DB: db, Valkey: kv}
 }
 
 func (u *UserService) GetUser(
@@ -29,18 +33,43 @@ func (u *UserService) GetUser(
 	if req.Msg.Id == "" {
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("user ID cannot be empty"))
 	}
+	viewerID, err := getUserIDFromSession(ctx, req.Header(), u.Valkey)
+	if err != nil {
+		return nil, err
+	}
 	user, err := u.findUserByIDWithContext(ctx, req.Msg.Id)
 	if err != nil {
 		return nil, err
 	}
+	if user.ID == viewerID {
+		return connect.NewResponse(&v1.GetUserResponse{User: user.ToProto()}), nil
+	}
 
-	return connect.NewResponse(&v1.GetUserResponse{User: user.ToProto()}), nil
+	settings, err := privacySettingsFor(ctx, u.DB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	isFriend, err := areFriends(ctx, u.DB, viewerID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Undiscoverable users look the same as missing ones to strangers.
+	if !isFriend && !settings.DiscoverableByUsername {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("user with ID %s not found", req.Msg.Id))
+	}
+
+	return connect.NewResponse(&v1.GetUserResponse{User: user.ToPublicProto(settings.EmailVisibleTo(isFriend))}), nil
 }
 
 func (u *UserService) ListUsers(
 	ctx context.Context,
 	req *connect.Request[v1.ListUsersRequest],
 ) (*connect.Response[v1.ListUsersResponse], error) {
+	viewerID, err := getUserIDFromSession(ctx, req.Header(), u.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
 	page := int(req.Msg.Page)
 	pageSize := int(req.Msg.PageSize)
 
@@ -56,26 +85,55 @@ func (u *UserService) ListUsers(
 
 	offset := (page - 1) * pageSize
 
+	friendIDs, err := getFriendIDs(ctx, u.DB, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	isFriend := make(map[string]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		isFriend[id] = true
+	}
+
+	// Self and friends are always listed; everyone else only if discoverable by username.
+	visible := u.DB.WithContext(ctx).Model(&models.User{}).Where(
+		"users.id IN ? OR NOT EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = users.id AND ps.discoverable_by_username = false)",
+		append(friendIDs, viewerID),
+	)
+
 	var users []models.User
 	var total int64
 
-	if err := u.DB.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := visible.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 		return nil, err
 	}
 
-	if err := u.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+	if err := visible.Session(&gorm.Session{}).Order("users.created_at").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 		return nil, err
 	}
 
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	privacy, err := loadPrivacySettings(ctx, u.DB, ids...)
+	if err != nil {
+		return nil, err
+	}
+
 	userProtos := make([]*v1.UserProto, len(users))
 	for i, user := range users {
-		userProtos[i] = user.ToProto()
+		if user.ID == viewerID {
+			userProtos[i] = user.ToProto()
+			continue
+		}
+		settings := privacy[user.ID]
+		userProtos[i] = user.ToPublicProto(settings.EmailVisibleTo(isFriend[user.ID]))
 	}
 
 	return connect.NewResponse(&v1.ListUsersResponse{
@@ -86,6 +144,77 @@ func (u *UserService) ListUsers(
 	}), nil
 }
 
+func (u *UserService) GetPrivacySettings(
+	ctx context.Context,
+	req *connect.Request[v1.GetPrivacySettingsRequest],
+) (*connect.Response[v1.GetPrivacySettingsResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), u.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := privacySettingsFor(ctx, u.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.GetPrivacySettingsResponse{Settings: settings.ToProto()}), nil
+}
+
+func (u *UserService) UpdatePrivacySettings(
+	ctx context.Context,
+	req *connect.Request[v1.UpdatePrivacySettingsRequest],
+) (*connect.Response[v1.UpdatePrivacySettingsResponse], error) {
+	userID, err := getUserIDFromSession(ctx, req.Header(), u.Valkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.DefaultVisibility != nil {
+		switch req.Msg.GetDefaultVisibility() {
+		case circlesv1.Visibility_VISIBILITY_PRIVATE, circlesv1.Visibility_VISIBILITY_FRIENDS:
+		default:
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidDefaultVisibility))
+		}
+	}
+	if req.Msg.FriendRequestPolicy != nil && req.Msg.GetFriendRequestPolicy() == v1.FriendRequestPolicy_FRIEND_REQUEST_POLICY_UNSPECIFIED {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errInvalidFriendRequestPolicy))
+	}
+
+	settings, err := privacySettingsFor(ctx, u.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.DiscoverableByUsername != nil {
+		settings.DiscoverableByUsername = req.Msg.GetDiscoverableByUsername()
+	}
+	if req.Msg.DiscoverableByEmail != nil {
+		settings.DiscoverableByEmail = req.Msg.GetDiscoverableByEmail()
+	}
+	if req.Msg.ShowEmailToFriends != nil {
+		settings.ShowEmailToFriends = req.Msg.GetShowEmailToFriends()
+	}
+	if req.Msg.FriendRequestPolicy != nil {
+		settings.FriendRequestPolicy = models.FriendRequestPolicyFromProto(req.Msg.GetFriendRequestPolicy())
+	}
+	if req.Msg.DefaultVisibility != nil {
+		settings.DefaultVisibility = models.VisibilityFromProto(req.Msg.GetDefaultVisibility())
+	}
+	if req.Msg.SharePersonalTags != nil {
+		settings.SharePersonalTags = req.Msg.GetSharePersonalTags()
+	}
+
+	if err := u.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&v1.UpdatePrivacySettingsResponse{Settings: settings.ToProto()}), nil
+}
+
 func (u *UserService) findUserByIDWithContext(ctx context.Context, id string) (*models.User, error) {
 	if id == "" {
 		return nil, fmt.Errorf("user ID is required")
@@ -101,3 +230,46 @@ func (u *UserService) findUserByIDWithContext(ctx context.Context, id string) (*
 
 	return &user, nil
 }
+
+// loadPrivacySettings returns the privacy settings for each of the given users,
+// filling in DefaultPrivacySettings for users who never saved any.
+func loadPrivacySettings(ctx context.Context, db *gorm.DB, userIDs ...string) (map[string]models.PrivacySettings, error) {
+	out := make(map[string]models.PrivacySettings, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+
+	var rows []models.PrivacySettings
+	if err := db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.UserID] = row
+	}
+	for _, id := range userIDs {
+		if _, ok := out[id]; !ok {
+			out[id] = models.DefaultPrivacySettings(id)
+		}
+	}
+	return out, nil
+}
+
+// privacySettingsFor returns a single user's privacy settings (or the defaults).
+func privacySettingsFor(ctx context.Context, db *gorm.DB, userID string) (models.PrivacySettings, error) {
+	settings, err := loadPrivacySettings(ctx, db, userID)
+	if err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return settings[userID], nil
+}
+
+// areFriends reports whether a and b have an accepted friend request between them.
+func areFriends(ctx context.Context, db *gorm.DB, a, b string) (bool, error) {
+	var count int64
+	if err := db.WithContext(ctx).Model(&models.FriendRequest{}).
+		Where("pair_key = ? AND status = ?", canonicalPairKey(a, b), models.FriendRequestStatusAccepted).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -1,13 +1,12 @@
 package services
 
 import (
+	circlesv1 "api/src/generated/circles/v1"
 	wishlistv1 "api/src/generated/wishlist/v1"
 	"api/src/generated/wishlist/v1/v1connect"
 	"api/src/internal/models"
 	"context"
 	"errors"
-	"fmt"
-	"strings"
 
 	"connectrpc.com/connect"
 	"github.com/valkey-io/valkey-go"
@@ -18,10 +17,11 @@ type WishlistService struct {
 	v1connect.UnimplementedWishlistServiceHandler
 	DB     *gorm.DB
 	Valkey valkey.Client
+	Places *PlaceResolver
 }
 
-func NewWishlistService(db *gorm.DB, kv valkey.Client) *WishlistService {
-	return &WishlistService{DB: db, Valkey: kv}
+func NewWishlistService(db *gorm.DB, kv valkey.Client, places *PlaceResolver) *WishlistService {
+	return &WishlistService{DB: db, Valkey: kv, Places: places}
 }
 
 func (s *WishlistService) AddToWishlist(
@@ -37,39 +37,41 @@ func (s *WishlistService) AddToWishlist(
 		return nil, err
 	}
 
-	if req.Msg.GooglePlacesId == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errGooglePlacesIDRequired))
+	if err := validateGooglePlacesID(req.Msg.GooglePlacesId); err != nil {
+		return nil, err
+	}
+	if err := validateCoordinates(req.Msg.Latitude, req.Msg.Longitude); err != nil {
+		return nil, err
 	}
 
-	var restaurant models.Restaurant
-	result := s.DB.WithContext(ctx).
-		Where(models.Restaurant{GoogleID: req.Msg.GooglePlacesId}).
-		Attrs(models.Restaurant{
-			Name:           req.Msg.RestaurantName,
-			Address:        req.Msg.RestaurantAddress,
-			City:           req.Msg.City,
-			Country:        req.Msg.Country,
-			PhotoReference: req.Msg.PhotoReference,
-		}).
-		FirstOrCreate(&restaurant)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	// Backfill city/country/photo_reference on existing restaurants that were created before these fields were tracked.
-	if updates := missingRestaurantFields(&restaurant, req.Msg.City, req.Msg.Country, req.Msg.PhotoReference); len(updates) > 0 {
-		if err := s.DB.WithContext(ctx).Model(&restaurant).Updates(updates).Error; err != nil {
-			return nil, err
-		}
-		if v, ok := updates["city"].(string); ok {
-			restaurant.City = v
-		}
-		if v, ok := updates["country"].(string); ok {
-			restaurant.Country = v
-		}
-		if v, ok := updates["photo_reference"].(string); ok {
-			restaurant.PhotoReference = v
-		}
+	visibility, circles, err := resolveAudience(ctx, s.DB, userID, req.Msg.Visibility, req.Msg.CircleIds)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := resolveTags(ctx, s.DB, req.Msg.TagSlugs)
+	if err != nil {
+		return nil, err
+	}
+	personalTags, err := resolvePersonalTags(ctx, s.DB, userID, req.Msg.PersonalTagSlugs.GetSlugs())
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.Places.Resolve(ctx, s.DB, req.Msg.GooglePlacesId, RestaurantHints{
+		Name:           req.Msg.RestaurantName,
+		Address:        req.Msg.RestaurantAddress,
+		City:           req.Msg.City,
+		Country:        req.Msg.Country,
+		PhotoReference: req.Msg.PhotoReference,
+		Latitude:       req.Msg.Latitude,
+		Longitude:      req.Msg.Longitude,
+	})
+	if err != nil {
+		return nil, err
+	}
+	restaurant, err := saveRestaurant(s.DB.WithContext(ctx), details)
+	if err != nil {
+		return nil, err
 	}
 
 	// Block if the user already reviewed this restaurant
@@ -83,34 +85,59 @@ func (s *WishlistService) AddToWishlist(
 		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("you have already reviewed this restaurant"))
 	}
 
-	tags := req.Msg.TagSlugs
-	if tags == nil {
-		tags = []string{}
-	}
-
 	item := models.WishlistItem{
 		UserID:         userID,
 		RestaurantID:   restaurant.ID,
 		GooglePlacesID: req.Msg.GooglePlacesId,
-		Tags:           tags,
+		Visibility:     visibility,
 	}
 
-	// Use FirstOrCreate so calling Add twice is idempotent; update tags if already exists
+	// Use FirstOrCreate so calling Add twice is idempotent; update tags if already exists.
+	// The item, its audience and its tags change together or not at all.
 	var existing models.WishlistItem
-	res := s.DB.WithContext(ctx).
-		Where("user_id = ? AND restaurant_id = ?", userID, restaurant.ID).
-		FirstOrCreate(&existing, item)
-	if res.Error != nil {
-		return nil, res.Error
-	}
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND restaurant_id = ?", userID, restaurant.ID).
+			FirstOrCreate(&existing, item)
+		if res.Error != nil {
+			return res.Error
+		}
 
-	// If item already existed, always overwrite tags with whatever was sent.
-	// The UI always sends the full intended tag list (including empty = clear).
-	if res.RowsAffected == 0 {
-		if err := s.DB.WithContext(ctx).Model(&existing).Update("tags", tags).Error; err != nil {
-			return nil, err
+		// If item already existed, always overwrite tags with whatever was sent.
+		// The UI always sends the full intended tag list (including empty = clear).
+		// Personal tags and the audience are only changed when sent; otherwise the stored ones are kept.
+		keepAudience := res.RowsAffected == 0 && req.Msg.Visibility == circlesv1.Visibility_VISIBILITY_UNSPECIFIED
+		if res.RowsAffected == 0 && !keepAudience {
+			if err := tx.Model(&existing).Update("visibility", visibility).Error; err != nil {
+				return err
+			}
+			existing.Visibility = visibility
+		}
+
+		if err := tx.Model(&existing).Association("Tags").Replace(tags); err != nil {
+			return err
 		}
 		existing.Tags = tags
+		if res.RowsAffected == 0 && req.Msg.PersonalTagSlugs == nil {
+			if err := tx.Model(&existing).Association("PersonalTags").Find(&existing.PersonalTags); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Model(&existing).Association("PersonalTags").Replace(personalTags); err != nil {
+				return err
+			}
+			existing.PersonalTags = personalTags
+		}
+		if keepAudience {
+			return tx.Model(&existing).Association("Circles").Find(&existing.Circles)
+		}
+		if err := tx.Model(&existing).Association("Circles").Replace(circles); err != nil {
+			return err
+		}
+		existing.Circles = circles
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
 
 	existing.Restaurant = restaurant
@@ -137,14 +164,36 @@ func (s *WishlistService) RemoveFromWishlist(
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errGooglePlacesIDRequired))
 	}
 
-	result := s.DB.WithContext(ctx).
-		Where("user_id = ? AND google_places_id = ?", userID, req.Msg.GooglePlacesId).
-		Delete(&models.WishlistItem{})
-	if result.Error != nil {
-		return nil, result.Error
+	var removed int64
+	txErr := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_circles WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND google_places_id = ?)",
+			userID, req.Msg.GooglePlacesId,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND google_places_id = ?)",
+			userID, req.Msg.GooglePlacesId,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"DELETE FROM wishlist_item_personal_tags WHERE wishlist_item_id IN (SELECT id FROM wishlist_items WHERE user_id = ? AND google_places_id = ?)",
+			userID, req.Msg.GooglePlacesId,
+		).Error; err != nil {
+			return err
+		}
+		result := tx.Where("user_id = ? AND google_places_id = ?", userID, req.Msg.GooglePlacesId).
+			Delete(&models.WishlistItem{})
+		removed = result.RowsAffected
+		return result.Error
+	})
+	if txErr != nil {
+		return nil, txErr
 	}
 
-	return connect.NewResponse(&wishlistv1.RemoveFromWishlistResponse{Success: result.RowsAffected > 0}), nil
+	return connect.NewResponse(&wishlistv1.RemoveFromWishlistResponse{Success: removed > 0}), nil
 }
 
 func (s *WishlistService) ListWishlist(
@@ -169,30 +218,19 @@ func (s *WishlistService) ListWishlist(
 		targetUserID = req.Msg.TargetUserId
 	}
 
-	needsJoin := req.Msg.City != "" || req.Msg.Country != "" ||
-		req.Msg.SortBy == wishlistv1.WishlistSortBy_WISHLIST_SORT_BY_NAME_ASC ||
-		req.Msg.SortBy == wishlistv1.WishlistSortBy_WISHLIST_SORT_BY_NAME_DESC
-
-	query := s.DB.WithContext(ctx).
+	query := preloadPersonalTags(s.DB.WithContext(ctx), callerID).
 		Preload("Restaurant").
+		Preload("Tags").
 		Where("wishlist_items.user_id = ?", targetUserID)
 
-	if needsJoin {
-		query = query.Joins("JOIN restaurants ON restaurants.id = wishlist_items.restaurant_id")
-	}
-
-	if req.Msg.GooglePlacesId != "" {
-		query = query.Where("wishlist_items.google_places_id = ?", req.Msg.GooglePlacesId)
+	// Friends only see what the owner shared with them; the owner sees everything plus its audience.
+	if targetUserID != callerID {
+		query = applyWishlistVisibility(query, callerID)
+	} else {
+		query = query.Preload("Circles")
 	}
 
-	if req.Msg.City != "" {
-		query = query.Where("restaurants.city ILIKE ?", "%"+req.Msg.City+"%")
-	}
-	if req.Msg.Country != "" {
-		query = query.Where("restaurants.country ILIKE ?", "%"+req.Msg.Country+"%")
-	}
-
-	query = applyWishlistTagFilter(query, req.Msg.TagSlugs, req.Msg.TagFilterMode)
+	query = applyListWishlistFilters(query, req.Msg, callerID)
 
 	switch req.Msg.SortBy {
 	case wishlistv1.WishlistSortBy_WISHLIST_SORT_BY_DATE_ASC:
@@ -218,23 +256,33 @@ func (s *WishlistService) ListWishlist(
 	return connect.NewResponse(&wishlistv1.ListWishlistResponse{Items: protos}), nil
 }
 
-// applyWishlistTagFilter adds WHERE clauses for tag filtering based on mode (AND/OR).
-func applyWishlistTagFilter(query *gorm.DB, slugs []string, mode wishlistv1.WishlistTagFilterMode) *gorm.DB {
-	if len(slugs) == 0 {
-		return query
+// applyListWishlistFilters applies the filters of a ListWishlist request (all but
+// target_user_id) for viewerID, joining restaurants when the filters or sort order need its columns.
+func applyListWishlistFilters(query *gorm.DB, msg *wishlistv1.ListWishlistRequest, viewerID string) *gorm.DB {
+	needsJoin := msg.City != "" || msg.Country != "" ||
+		msg.SortBy == wishlistv1.WishlistSortBy_WISHLIST_SORT_BY_NAME_ASC ||
+		msg.SortBy == wishlistv1.WishlistSortBy_WISHLIST_SORT_BY_NAME_DESC
+	if needsJoin {
+		query = query.Joins("JOIN restaurants ON restaurants.id = wishlist_items.restaurant_id")
+	}
+
+	if msg.GooglePlacesId != "" {
+		query = query.Where("wishlist_items.google_places_id = ?", msg.GooglePlacesId)
 	}
-	if mode == wishlistv1.WishlistTagFilterMode_WISHLIST_TAG_FILTER_MODE_AND {
-		for _, slug := range slugs {
-			query = query.Where("wishlist_items.tags LIKE ?", fmt.Sprintf(`%%"%s"%%`, slug))
-		}
-		return query
+
+	if msg.City != "" {
+		query = query.Where("restaurants.city ILIKE ?", "%"+msg.City+"%")
 	}
-	// OR (default): at least one specified tag must appear
-	conditions := make([]string, len(slugs))
-	args := make([]any, len(slugs))
-	for i, slug := range slugs {
-		conditions[i] = "wishlist_items.tags LIKE ?"
-		args[i] = fmt.Sprintf(`%%"%s"%%`, slug)
+	if msg.Country != "" {
+		query = query.Where("restaurants.country ILIKE ?", "%"+msg.Country+"%")
 	}
-	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+
+	return applyWishlistTagFilter(query, msg.TagSlugs, msg.TagFilterMode, viewerID)
+}
+
+// applyWishlistTagFilter adds WHERE clauses for tag filtering based on mode (AND/OR). Slugs
+// match global tags, and personal tags that viewerID may see.
+func applyWishlistTagFilter(query *gorm.DB, slugs []string, mode wishlistv1.WishlistTagFilterMode, viewerID string) *gorm.DB {
+	return applyTagCondition(query, tagCondition("wishlist_items", "wishlist_item_tags", "wishlist_item_personal_tags", "wishlist_item_id"), slugs,
+		mode == wishlistv1.WishlistTagFilterMode_WISHLIST_TAG_FILTER_MODE_AND, viewerID)
 }
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"api/src/internal/models"
+	"api/src/internal/utils"
+	"api/src/services"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportTags_RejectsInvalidTags(t *testing.T) {
+	cases := map[string]models.Tag{
+		"uppercase slug":   {Slug: "Thai", Label: "Thai", Category: "Cuisine"},
+		"spaces in slug":   {Slug: "late night", Label: "Late Night", Category: "Occasion"},
+		"trailing dash":    {Slug: "vegan-", Label: "Vegan", Category: "Dietary"},
+		"missing label":    {Slug: "ramen", Category: "Cuisine"},
+		"missing category": {Slug: "ramen", Label: "Ramen"},
+	}
+	for name, tag := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Validation runs before the database is touched.
+			if err := services.ImportTags(context.Background(), nil, nil, []models.Tag{tag}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestImportTags_RejectsDuplicateSlugs(t *testing.T) {
+	tags := []models.Tag{
+		{Slug: "ramen", Label: "Ramen", Category: "Cuisine"},
+		{Slug: "ramen", Label: "Noodles", Category: "Cuisine"},
+	}
+	err := services.ImportTags(context.Background(), nil, nil, tags)
+	if err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Fatalf("expected a duplicate error, got %v", err)
+	}
+}
+
+// The built-in tags must be importable, so the bad tag appended last is the only one
+// reported.
+func TestImportTags_AcceptsRequiredTags(t *testing.T) {
+	tags := append(append([]models.Tag{}, utils.RequiredTags...), models.Tag{Slug: "Bad Slug"})
+	err := services.ImportTags(context.Background(), nil, nil, tags)
+	if err == nil || !strings.Contains(err.Error(), `"Bad Slug"`) {
+		t.Fatalf("expected only the appended tag to fail, got %v", err)
+	}
+}
+
+func TestFindUser_EmptyReference(t *testing.T) {
+	if _, err := services.FindUser(context.Background(), nil, "  "); !errors.Is(err, services.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
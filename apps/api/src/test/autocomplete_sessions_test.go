@@ -0,0 +1,185 @@
+package test
+
+import (
+	v1 "api/src/generated/google_maps/v1"
+	"api/src/internal/utils"
+	"api/src/services"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"connectrpc.com/connect"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// memorySessions is an AutocompleteSessionStore over a map.
+type memorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]services.AutocompleteSession
+}
+
+func (s *memorySessions) Get(_ context.Context, owner string) (services.AutocompleteSession, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[owner]
+	return session, ok, nil
+}
+
+func (s *memorySessions) Put(_ context.Context, owner string, session services.AutocompleteSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[string]services.AutocompleteSession{}
+	}
+	s.sessions[owner] = session
+	return nil
+}
+
+func (s *memorySessions) Delete(_ context.Context, owner string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sessions[owner]
+	delete(s.sessions, owner)
+	return ok, nil
+}
+
+// newAutocompleteSessions returns sessions owned by the X-User header, on a clock the
+// test moves.
+func newAutocompleteSessions(t *testing.T) (*services.AutocompleteSessions, *time.Time, *prometheus.Registry) {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	reg := prometheus.NewRegistry()
+	return &services.AutocompleteSessions{
+		Store:   &memorySessions{},
+		Metrics: utils.NewAutocompleteSessionMetrics(reg),
+		Owner:   func(_ context.Context, h http.Header) string { return h.Get("X-User") },
+		Now:     func() time.Time { return now },
+	}, &now, reg
+}
+
+// sessionOutcomes returns places_autocomplete_sessions_total by outcome.
+func sessionOutcomes(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			got[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		}
+	}
+	return got
+}
+
+func TestAutocompleteSessions(t *testing.T) {
+	sessions, now, reg := newAutocompleteSessions(t)
+	ctx := context.Background()
+	ala := http.Header{"X-User": {"ala"}}
+
+	first := sessions.Continue(ctx, ala, "")
+	if first == "" || len(first) > 36 {
+		t.Fatalf("token = %q", first)
+	}
+	if got := sessions.Continue(ctx, ala, first); got != first {
+		t.Errorf("echoing the token gave %q, want the same session", got)
+	}
+	if got := sessions.Continue(ctx, http.Header{"X-User": {"ola"}}, first); got == first {
+		t.Error("another user continued the session")
+	}
+	if got := sessions.Complete(ctx, ala, "made-up"); got != "" {
+		t.Errorf("an unknown token completed %q", got)
+	}
+	if got := sessions.Complete(ctx, ala, first); got != first {
+		t.Errorf("Complete = %q, want %q", got, first)
+	}
+	sessions.Ended(first, nil)
+	if got := sessions.Complete(ctx, ala, first); got != "" {
+		t.Errorf("a session completed twice")
+	}
+
+	// A session past Google's limit is replaced, and cannot end a details call.
+	second := sessions.Continue(ctx, ala, first)
+	*now = now.Add(4 * time.Minute)
+	third := sessions.Continue(ctx, ala, second)
+	if third == second || third == first {
+		t.Errorf("an expired session was continued")
+	}
+	*now = now.Add(4 * time.Minute)
+	if got := sessions.Complete(ctx, ala, third); got != "" {
+		t.Errorf("an expired session completed %q", got)
+	}
+
+	// A details call that fails abandons its session.
+	fourth := sessions.Continue(ctx, ala, "")
+	if got := sessions.Complete(ctx, ala, fourth); got != fourth {
+		t.Errorf("Complete = %q, want %q", got, fourth)
+	}
+	sessions.Ended(fourth, errors.New("unavailable"))
+	sessions.Ended("", nil)
+
+	got := sessionOutcomes(t, reg)
+	if got["started"] != 5 || got["completed"] != 1 || got["abandoned"] != 3 {
+		t.Errorf("metrics = %v", got)
+	}
+	if token := sessions.Continue(ctx, http.Header{}, ""); token != "" {
+		t.Errorf("a signed-out request got token %q", token)
+	}
+}
+
+// sessionPlaces records the session tokens sent to Google.
+type sessionPlaces struct {
+	services.PlacesProvider
+	autocomplete, details []string
+}
+
+func (p *sessionPlaces) AutocompletePlaces(_ context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error) {
+	p.autocomplete = append(p.autocomplete, req.SessionToken)
+	return &placespb.AutocompletePlacesResponse{}, nil
+}
+
+func (p *sessionPlaces) GetPlace(_ context.Context, req *placespb.GetPlaceRequest, _ []string) (*placespb.Place, error) {
+	p.details = append(p.details, req.SessionToken)
+	return &placespb.Place{Name: req.Name}, nil
+}
+
+func TestGooglePlacesAPIService_AutocompleteSessionReachesDetails(t *testing.T) {
+	sessions, _, _ := newAutocompleteSessions(t)
+	places := &sessionPlaces{}
+	svc := services.NewGooglePlacesAPIService(places, sessions)
+	ctx := context.Background()
+
+	autocomplete := func(token string) string {
+		req := connect.NewRequest(&v1.AutocompletePlacesRequest{Input: "pho", SessionToken: token})
+		req.Header().Set("X-User", "ala")
+		resp, err := svc.AutocompletePlaces(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Msg.SessionToken
+	}
+	token := autocomplete("client-chosen")
+	if autocomplete(token) != token {
+		t.Fatal("the session changed between keystrokes")
+	}
+	details := connect.NewRequest(&v1.GetRestaurantDetailsRequest{Name: "places/abc", SessionToken: token})
+	details.Header().Set("X-User", "ala")
+	if _, err := svc.GetRestaurantDetails(ctx, details); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetRestaurantDetails(ctx, details); err != nil {
+		t.Fatal(err)
+	}
+
+	if places.autocomplete[0] != token || places.autocomplete[1] != token {
+		t.Errorf("autocomplete tokens = %q, want %q", places.autocomplete, token)
+	}
+	if places.details[0] != token || places.details[1] != "" {
+		t.Errorf("details tokens = %q, want the session once", places.details)
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	circlesv1 "api/src/generated/circles/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+)
+
+func TestCirclesService_CreateCircle_NoSession(t *testing.T) {
+	svc := &services.CirclesService{}
+	req := connect.NewRequest(&circlesv1.CreateCircleRequest{Name: "close friends"})
+	_, err := svc.CreateCircle(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestCirclesService_ListCircles_NilDB(t *testing.T) {
+	svc := &services.CirclesService{}
+	req := connect.NewRequest(&circlesv1.ListCirclesRequest{})
+	_, err := svc.ListCircles(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error from nil DB, got nil")
+	}
+}
+
+func TestCirclesService_DeleteCircle_NilDB(t *testing.T) {
+	svc := &services.CirclesService{}
+	req := connect.NewRequest(&circlesv1.DeleteCircleRequest{Id: "some-id"})
+	_, err := svc.DeleteCircle(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error from nil DB, got nil")
+	}
+}
+
+func TestCirclesService_AddCircleMembers_NilDB(t *testing.T) {
+	svc := &services.CirclesService{}
+	req := connect.NewRequest(&circlesv1.AddCircleMembersRequest{CircleId: "some-id", UserIds: []string{"friend"}})
+	_, err := svc.AddCircleMembers(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error from nil DB, got nil")
+	}
+}
+
+func TestVisibility_RoundTrip(t *testing.T) {
+	cases := map[circlesv1.Visibility]string{
+		circlesv1.Visibility_VISIBILITY_PRIVATE: models.VisibilityPrivate,
+		circlesv1.Visibility_VISIBILITY_CIRCLES: models.VisibilityCircles,
+		circlesv1.Visibility_VISIBILITY_FRIENDS: models.VisibilityFriends,
+	}
+	for v, stored := range cases {
+		if got := models.VisibilityFromProto(v); got != stored {
+			t.Errorf("VisibilityFromProto(%v) = %q, want %q", v, got, stored)
+		}
+		if got := models.VisibilityToProto(stored); got != v {
+			t.Errorf("VisibilityToProto(%q) = %v, want %v", stored, got, v)
+		}
+	}
+}
+
+func TestVisibility_UnspecifiedDefaultsToFriends(t *testing.T) {
+	if got := models.VisibilityFromProto(circlesv1.Visibility_VISIBILITY_UNSPECIFIED); got != models.VisibilityFriends {
+		t.Fatalf("expected unspecified visibility to map to %q, got %q", models.VisibilityFriends, got)
+	}
+	if got := models.VisibilityToProto(""); got != circlesv1.Visibility_VISIBILITY_FRIENDS {
+		t.Fatalf("expected empty stored visibility to map to FRIENDS, got %v", got)
+	}
+}
+
+func TestCirclesService_DeleteCircle_WithMembersAndGrants(t *testing.T) {
+	db := openTestDB(t)
+	kv := openTestValkey(t)
+	seed := seedSharedReview(t, db)
+	svc := services.NewCirclesService(db, kv)
+
+	req := connect.NewRequest(&circlesv1.DeleteCircleRequest{Id: seed.CircleID})
+	req.Header().Set("Cookie", signIn(t, kv, seed.FriendID))
+	if _, err := svc.DeleteCircle(context.Background(), req); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("deleting another user's circle gave %v, want NotFound", err)
+	}
+	if n := countRows(t, db, "circle_members", "circle_id = ?", seed.CircleID); n != 1 {
+		t.Fatalf("a refused delete removed members: %d left", n)
+	}
+
+	req = connect.NewRequest(&circlesv1.DeleteCircleRequest{Id: seed.CircleID})
+	req.Header().Set("Cookie", signIn(t, kv, seed.OwnerID))
+	if _, err := svc.DeleteCircle(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"circle_members", "review_circles", "wishlist_item_circles"} {
+		if n := countRows(t, db, table, "circle_id = ?", seed.CircleID); n != 0 {
+			t.Errorf("%d %s rows left", n, table)
+		}
+	}
+	if n := countRows(t, db, "circles", "id = ?", seed.CircleID); n != 0 {
+		t.Error("the circle is still there")
+	}
+	if n := countRows(t, db, "reviews", "id = ?", seed.ReviewID); n != 1 {
+		t.Error("the review shared with the circle was deleted")
+	}
+}
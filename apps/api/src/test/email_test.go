@@ -0,0 +1,334 @@
+package test
+
+import (
+	"api/src/internal/email"
+	"api/src/internal/models"
+	"api/src/services"
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts every message and records it.
+type smtpStandIn struct {
+	listener net.Listener
+	messages chan string
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStandIn{listener: l, messages: make(chan string, 10)}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.messages <- data.String()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPTransport_SendsMultipartMessage(t *testing.T) {
+	server := startSMTPStandIn(t)
+	_, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	transport := &email.SMTPTransport{Host: "127.0.0.1", Port: p}
+
+	err := transport.Send(context.Background(), email.Message{
+		From:    "Resto Rate <no-reply@example.com>",
+		To:      "ania@example.com",
+		Subject: "Zażółć gęślą jaźń",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	select {
+	case raw := <-server.messages:
+		for _, want := range []string{"To: ania@example.com", "List-Unsubscribe: <https://example.com/u>", "text/plain", "text/html", "plain body", "=?utf-8?q?"} {
+			if !strings.Contains(raw, want) {
+				t.Errorf("expected message to contain %q, got:\n%s", want, raw)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stand-in server received no message")
+	}
+}
+
+func TestFileTransport_WritesEML(t *testing.T) {
+	dir := t.TempDir()
+	transport := &email.FileTransport{Dir: dir}
+	if err := transport.Send(context.Background(), email.Message{From: "a@example.com", To: "b@example.com", Subject: "hi", Text: "body"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %d", len(files))
+	}
+	raw, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(raw), "Subject: hi") {
+		t.Fatalf("expected subject header in file, got:\n%s", raw)
+	}
+}
+
+func TestRender_LocalizesNotification(t *testing.T) {
+	params := email.Params{ActorName: "Ania", PlaceName: "Ramen Bar", RecipientName: "Tomek", UnsubscribeURL: "https://example.com/u"}
+
+	params.Locale = "en"
+	en, err := email.Render(email.TemplateFriendReviewedWishlistPlace, params)
+	if err != nil {
+		t.Fatalf("Render en: %v", err)
+	}
+	if en.Subject != "Ania reviewed Ramen Bar" {
+		t.Fatalf("unexpected en subject %q", en.Subject)
+	}
+
+	params.Locale = "pl-PL"
+	pl, err := email.Render(email.TemplateFriendReviewedWishlistPlace, params)
+	if err != nil {
+		t.Fatalf("Render pl: %v", err)
+	}
+	if !strings.Contains(pl.Text, "Cześć Tomek") || !strings.Contains(pl.HTML, "https://example.com/u") {
+		t.Fatalf("expected Polish greeting and unsubscribe link, got text %q", pl.Text)
+	}
+}
+
+func TestRender_EscapesHTML(t *testing.T) {
+	r, err := email.Render(email.TemplateFriendRequestReceived, email.Params{ActorName: "<script>x</script>"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(r.HTML, "<script>") {
+		t.Fatal("expected actor name to be escaped in HTML")
+	}
+}
+
+func TestRender_WeeklyDigest(t *testing.T) {
+	digest := &email.Digest{
+		Since:        time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC),
+		WishlistHits: []email.DigestEntry{{ActorName: "Ania", PlaceName: "Ramen Bar", Rating: 4.5}},
+	}
+	r, err := email.Render(email.TemplateWeeklyDigest, email.Params{Locale: "pl", Digest: digest})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(r.Text, "Ania ocenił(a) Ramen Bar na 4,5/5") {
+		t.Fatalf("expected localized digest line, got:\n%s", r.Text)
+	}
+	if _, err := email.Render(email.TemplateWeeklyDigest, email.Params{Digest: &email.Digest{}}); err == nil {
+		t.Fatal("expected empty digest to fail rendering")
+	}
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	cases := map[string]string{"": "en", "pl": "pl", "pl_PL": "pl", "PL-pl": "pl", "de": "en"}
+	for in, want := range cases {
+		if got := email.NormalizeLocale(in); got != want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUnsubscribeToken_RoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	token := email.SignUnsubscribeToken(secret, "user-1", email.TemplateWeeklyDigest)
+
+	userID, scope, err := email.ParseUnsubscribeToken(secret, token)
+	if err != nil {
+		t.Fatalf("ParseUnsubscribeToken: %v", err)
+	}
+	if userID != "user-1" || scope != email.TemplateWeeklyDigest {
+		t.Fatalf("unexpected token contents %q %q", userID, scope)
+	}
+
+	if _, _, err := email.ParseUnsubscribeToken([]byte("other"), token); err == nil {
+		t.Fatal("expected token signed with another secret to be rejected")
+	}
+	if _, _, err := email.ParseUnsubscribeToken(secret, "x"+token); err == nil {
+		t.Fatal("expected tampered token to be rejected")
+	}
+}
+
+func TestEmailRetryDelay_BacksOffAndCaps(t *testing.T) {
+	if got := services.EmailRetryDelay(1); got != time.Minute {
+		t.Fatalf("expected first retry after 1m, got %v", got)
+	}
+	if got := services.EmailRetryDelay(3); got != 4*time.Minute {
+		t.Fatalf("expected third retry after 4m, got %v", got)
+	}
+	if got := services.EmailRetryDelay(50); got != 6*time.Hour {
+		t.Fatalf("expected delay to be capped at 6h, got %v", got)
+	}
+}
+
+func TestWeeklyDigestPeriod_CoversPreviousISOWeek(t *testing.T) {
+	// Sunday 18 Oct 2026 belongs to the week starting Monday 12 Oct.
+	since, until := services.WeeklyDigestPeriod(time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC); !since.Equal(want) {
+		t.Fatalf("since = %v, want %v", since, want)
+	}
+	if want := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC); !until.Equal(want) {
+		t.Fatalf("until = %v, want %v", until, want)
+	}
+}
+
+// outboxTransport records what it sends, along with the outbox row's status at that moment.
+type outboxTransport struct {
+	db       *gorm.DB
+	rowID    string
+	fail     error
+	sent     []email.Message
+	statuses []string
+}
+
+func (o *outboxTransport) Send(ctx context.Context, msg email.Message) error {
+	var row models.EmailOutbox
+	if err := o.db.First(&row, "id = ?", o.rowID).Error; err != nil {
+		return err
+	}
+	o.statuses = append(o.statuses, row.Status)
+	if o.fail != nil {
+		return o.fail
+	}
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+func TestEmailDispatcher_DeliverDue_SendsAfterClaiming(t *testing.T) {
+	db := openTestDB(t)
+	address := "ada@example.com"
+	user := models.User{Name: "Ada", Email: &address}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	row := models.EmailOutbox{UserID: user.ID, Template: email.TemplateFriendRequestReceived,
+		Params: map[string]string{}, Status: models.EmailStatusPending, NextAttemptAt: now}
+	if err := db.Create(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	transport := &outboxTransport{db: db, rowID: row.ID, fail: errors.New("connection refused")}
+	d := services.NewEmailDispatcher(db, transport, services.EmailConfig{From: "test@example.com",
+		AppURL: "http://app", APIURL: "http://api", UnsubscribeSecret: []byte("secret")})
+
+	// The claim is committed before sending, so other connections see the row as sending.
+	if n, err := d.DeliverDue(context.Background(), now); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1, nil", n, err)
+	}
+	if len(transport.statuses) != 1 || transport.statuses[0] != models.EmailStatusSending {
+		t.Fatalf("expected the row to be claimed while sending, saw %v", transport.statuses)
+	}
+	var got models.EmailOutbox
+	if err := db.First(&got, "id = ?", row.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.EmailStatusPending || got.Attempts != 1 || !got.NextAttemptAt.After(now) {
+		t.Fatalf("expected a failed send to be retried later, got %+v", got)
+	}
+
+	// Not due yet, so nothing is claimed.
+	if n, err := d.DeliverDue(context.Background(), now); err != nil || n != 0 {
+		t.Fatalf("DeliverDue before the retry = %d, %v; want 0, nil", n, err)
+	}
+
+	transport.fail = nil
+	later := got.NextAttemptAt
+	if n, err := d.DeliverDue(context.Background(), later); err != nil || n != 1 {
+		t.Fatalf("DeliverDue on retry = %d, %v; want 1, nil", n, err)
+	}
+	if err := db.First(&got, "id = ?", row.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.EmailStatusSent || got.Attempts != 2 || len(transport.sent) != 1 {
+		t.Fatalf("expected one email sent on the second attempt, got %+v after %d sends", got, len(transport.sent))
+	}
+}
+
+func TestEmailDispatcher_DeliverDue_RetriesExpiredClaims(t *testing.T) {
+	db := openTestDB(t)
+	address := "ada@example.com"
+	user := models.User{Name: "Ada", Email: &address}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	// Claimed by a replica that died before recording the outcome.
+	row := models.EmailOutbox{UserID: user.ID, Template: email.TemplateFriendRequestReceived,
+		Params: map[string]string{}, Status: models.EmailStatusSending, Attempts: 1, NextAttemptAt: now.Add(time.Minute)}
+	if err := db.Create(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	transport := &outboxTransport{db: db, rowID: row.ID}
+	d := services.NewEmailDispatcher(db, transport, services.EmailConfig{UnsubscribeSecret: []byte("secret")})
+
+	if n, err := d.DeliverDue(context.Background(), now); err != nil || n != 0 {
+		t.Fatalf("DeliverDue while claimed = %d, %v; want 0, nil", n, err)
+	}
+	if n, err := d.DeliverDue(context.Background(), now.Add(2*time.Minute)); err != nil || n != 1 {
+		t.Fatalf("DeliverDue after the claim ran out = %d, %v; want 1, nil", n, err)
+	}
+	var got models.EmailOutbox
+	if err := db.First(&got, "id = ?", row.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.EmailStatusSent || got.Attempts != 2 {
+		t.Fatalf("expected the expired claim to be sent, got %+v", got)
+	}
+}
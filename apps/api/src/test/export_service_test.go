@@ -0,0 +1,129 @@
+package test
+
+import (
+	exportsv1 "api/src/generated/exports/v1"
+	reviewsv1 "api/src/generated/reviews/v1"
+	wishlistv1 "api/src/generated/wishlist/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExportPlaces_NilDB(t *testing.T) {
+	svc := &services.ExportService{}
+	_, err := svc.ExportPlaces(context.Background(), connect.NewRequest(&exportsv1.ExportPlacesRequest{}))
+	if connect.CodeOf(err) != connect.CodeInternal {
+		t.Errorf("expected Internal, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	svc.GeoJSONHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export/places.geojson", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("handler status = %d, want 500", rec.Code)
+	}
+}
+
+func TestExportPlacesRequestFromQuery(t *testing.T) {
+	q := url.Values{
+		"city":            {"Warsaw"},
+		"tag_slugs":       {"thai", "casual"},
+		"tag_filter_mode": {"AND"},
+		"min_rating":      {"3.5"},
+		"comment_search":  {"noodles"},
+		"friend_ids":      {"u1", "u2"},
+		"skip_wishlist":   {"true"},
+	}
+	got, err := services.ExportPlacesRequestFromQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &exportsv1.ExportPlacesRequest{
+		ReviewFilter: &reviewsv1.ListReviewsRequest{
+			City:          "Warsaw",
+			TagSlugs:      []string{"thai", "casual"},
+			TagFilterMode: reviewsv1.TagFilterMode_TAG_FILTER_MODE_AND,
+			MinRating:     3.5,
+			CommentSearch: "noodles",
+		},
+		WishlistFilter: &wishlistv1.ListWishlistRequest{
+			City:          "Warsaw",
+			TagSlugs:      []string{"thai", "casual"},
+			TagFilterMode: wishlistv1.WishlistTagFilterMode_WISHLIST_TAG_FILTER_MODE_AND,
+		},
+		FriendIds:    []string{"u1", "u2"},
+		SkipWishlist: true,
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+
+	for _, bad := range []url.Values{
+		{"min_rating": {"high"}},
+		{"tag_filter_mode": {"xor"}},
+		{"all_friends": {"maybe"}},
+	} {
+		if _, err := services.ExportPlacesRequestFromQuery(bad); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%v: expected InvalidArgument, got %v", bad, err)
+		}
+	}
+}
+
+func TestPlaceFeatures(t *testing.T) {
+	restaurant := models.Restaurant{GoogleID: "places/abc", Name: "Pho Hanoi", City: "Warsaw"}
+	restaurant.SetLocation(52.23, 21.01)
+	created := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	review := models.Review{
+		UserID:     "u1",
+		Rating:     4.5,
+		Restaurant: restaurant,
+		Tags:       []models.Tag{{Slug: "vietnamese"}, {Slug: "casual"}},
+		CreatedAt:  created,
+	}
+	review.ID = "r1"
+	feature, ok := services.ReviewFeature(&review)
+	if !ok {
+		t.Fatal("expected a feature")
+	}
+	if feature.Geometry.Coordinates != [2]float64{21.01, 52.23} {
+		t.Errorf("coordinates = %v, want [lng, lat]", feature.Geometry.Coordinates)
+	}
+	props := feature.Properties
+	if props.Status != services.PlaceStatusReviewed || props.Layer != "u1" || props.Rating == nil || *props.Rating != 4.5 ||
+		!reflect.DeepEqual(props.Tags, []string{"casual", "vietnamese"}) || props.CreatedAt != "2026-05-01T12:00:00Z" {
+		t.Errorf("review properties = %+v", props)
+	}
+
+	data, err := json.Marshal(feature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["type"] != "Feature" || decoded["id"] != "r1" || decoded["geometry"].(map[string]any)["type"] != "Point" {
+		t.Errorf("not a GeoJSON point feature: %s", data)
+	}
+
+	item := models.WishlistItem{UserID: "u2", Restaurant: restaurant, CreatedAt: created}
+	feature, ok = services.WishlistFeature(&item)
+	if !ok || feature.Properties.Status != services.PlaceStatusWishlisted || feature.Properties.Rating != nil {
+		t.Errorf("wishlist feature = %+v, %v", feature, ok)
+	}
+
+	item.Restaurant = models.Restaurant{Name: "No coordinates"}
+	if _, ok := services.WishlistFeature(&item); ok {
+		t.Error("expected no feature for a restaurant without coordinates")
+	}
+}
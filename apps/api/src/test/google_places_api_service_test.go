@@ -2,38 +2,42 @@ package test
 
 import (
 	v1 "api/src/generated/google_maps/v1"
+	"api/src/internal/placestest"
 	environment "api/src/internal/utils"
 	"api/src/services"
 
 	"context"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
-	placespb "cloud.google.com/go/maps/places/apiv1/placespb"
+	"cloud.google.com/go/maps/places/apiv1/placespb"
 	"connectrpc.com/connect"
 	"google.golang.org/genproto/googleapis/type/localized_text"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// MockGooglePlacesClient is a mock implementation of the Google Places client
-// This is kept for future use when mocking is needed
-type MockGooglePlacesClient struct{}
-
-func (m *MockGooglePlacesClient) SearchText(ctx context.Context, req *placespb.SearchTextRequest, opts ...interface{}) (*placespb.SearchTextResponse, error) {
-	// Return a mock response for "Banja Luka"
-	return &placespb.SearchTextResponse{
-		Places: []*placespb.Place{
-			{
-				Id:               "mock_place_id",
-				FormattedAddress: "Banja Luka, Bosnia and Herzegovina",
-				DisplayName: &localized_text.LocalizedText{
-					Text:         "Banja Luka",
-					LanguageCode: "en",
-				},
-			},
-		},
-	}, nil
+// recordedPlaces replays the fixtures in testdata/places, or re-records them against the
+// real API when PLACES_RECORD is set.
+func recordedPlaces(t *testing.T) *placestest.Provider {
+	t.Helper()
+	provider, err := placestest.FromEnv(filepath.Join("testdata", "places"), func() (placestest.Upstream, error) {
+		environment.MustLoadEnvironmentVariables()
+		client, err := services.NewGooglePlacesAPIClient()
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { client.Close() })
+		return services.NewGooglePlacesProvider(client), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
 }
 
 func TestGooglePlacesApi(t *testing.T) {
@@ -56,7 +60,7 @@ func TestGooglePlacesApi(t *testing.T) {
 	}
 	defer client.Close()
 
-	placesService := services.NewGooglePlacesAPIService(client)
+	placesService := services.NewGooglePlacesAPIService(services.NewGooglePlacesProvider(client), nil)
 
 	protoRequest := &v1.SearchTextRequest{
 		TextQuery:           "Banja Luka",
@@ -102,7 +106,7 @@ func TestDynamicFieldMask(t *testing.T) {
 	}
 	defer client.Close()
 
-	placesService := services.NewGooglePlacesAPIService(client)
+	placesService := services.NewGooglePlacesAPIService(services.NewGooglePlacesProvider(client), nil)
 
 	// Test with specific requested fields
 	protoRequest := &v1.SearchTextRequest{
@@ -142,3 +146,241 @@ func TestDynamicFieldMask(t *testing.T) {
 
 	t.Logf("Dynamic FieldMask test passed. Place: %s", place.DisplayName.Text)
 }
+
+func TestGooglePlacesAPIService_SearchText_Replay(t *testing.T) {
+	provider := recordedPlaces(t)
+	svc := services.NewGooglePlacesAPIService(provider, nil)
+
+	resp, err := svc.SearchText(context.Background(), connect.NewRequest(&v1.SearchTextRequest{
+		TextQuery:       "pho warsaw",
+		MaxResultCount:  2,
+		RequestedFields: []string{"places.id", "places.display_name", "places.formatted_address", "places.rating"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := provider.Calls()
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0].Fields, []string{"places.id", "places.display_name", "places.formatted_address", "places.rating"}) {
+		t.Errorf("calls = %+v, want the requested fields as the mask", calls)
+	}
+	if len(resp.Msg.Places) != 2 {
+		t.Fatalf("got %d places, want 2", len(resp.Msg.Places))
+	}
+	first := resp.Msg.Places[0]
+	if first.Id != "ChIJpho1" || first.DisplayName.GetText() != "Pho Hanoi" || first.Rating != 4.6 ||
+		!strings.Contains(first.FormattedAddress, "Warszawa") {
+		t.Errorf("first place = %v", first)
+	}
+}
+
+func TestGooglePlacesAPIService_SearchRestaurants_Replay(t *testing.T) {
+	provider := recordedPlaces(t)
+	svc := services.NewGooglePlacesAPIService(provider, nil)
+
+	resp, err := svc.SearchRestaurants(context.Background(), connect.NewRequest(&v1.SearchRestaurantsRequest{TextQuery: "pierogi krakow"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := provider.Calls()[0].Request.(*placespb.SearchTextRequest)
+	if req.IncludedType != "restaurant" || req.GetMinRating() != 4 || req.GetMaxResultCount() != 20 {
+		t.Errorf("request = %v", req)
+	}
+	if fields := provider.Calls()[0].Fields; len(fields) == 0 || fields[0] != "id" {
+		t.Errorf("mask = %v, want the restaurant details fields", fields)
+	}
+	if len(resp.Msg.Places) != 1 {
+		t.Fatalf("got %d places, want 1", len(resp.Msg.Places))
+	}
+	place := resp.Msg.Places[0]
+	if place.PostalAddress.GetCountry() != "Poland" || place.PostalAddress.GetLocality() != "Kraków" {
+		t.Errorf("postal address = %v, want the region code mapped to a country name", place.PostalAddress)
+	}
+	if place.BusinessStatus != v1.BusinessStatus_BUSINESS_STATUS_OPERATIONAL || place.PriceLevel != v1.PriceLevel_PRICE_LEVEL_INEXPENSIVE ||
+		place.UserRatingCount != 1520 || !place.DineIn || place.AllowsDogs {
+		t.Errorf("place = %v", place)
+	}
+	if len(place.Photos) != 1 || place.Photos[0].Name != "places/ChIJpierogi/photos/AUc7" {
+		t.Errorf("photos = %v", place.Photos)
+	}
+}
+
+func TestGooglePlacesAPIService_GetRestaurantDetails_Replay(t *testing.T) {
+	provider := recordedPlaces(t)
+	svc := services.NewGooglePlacesAPIService(provider, nil)
+
+	place, err := svc.GetRestaurantDetails(context.Background(), connect.NewRequest(&v1.GetRestaurantDetailsRequest{Name: "places/ChIJpho1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if place.Msg.Name != "places/ChIJpho1" || place.Msg.BusinessStatus != v1.BusinessStatus_BUSINESS_STATUS_CLOSED_PERMANENTLY {
+		t.Errorf("place = %v", place.Msg)
+	}
+
+	_, err = svc.GetRestaurantDetails(context.Background(), connect.NewRequest(&v1.GetRestaurantDetailsRequest{Name: "places/missing"}))
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected the recorded NotFound, got %v", err)
+	}
+}
+
+func TestGooglePlacesAPIService_AutocompletePlaces_Replay(t *testing.T) {
+	provider := recordedPlaces(t)
+	svc := services.NewGooglePlacesAPIService(provider, nil)
+
+	resp, err := svc.AutocompletePlaces(context.Background(), connect.NewRequest(&v1.AutocompletePlacesRequest{Input: "pho"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := provider.Calls()[0].Request.(*placespb.AutocompletePlacesRequest)
+	if !reflect.DeepEqual(req.IncludedPrimaryTypes, []string{"restaurant"}) || !req.IncludeQueryPredictions {
+		t.Errorf("request = %v, want restaurants and query predictions", req)
+	}
+	if len(resp.Msg.Suggestions) != 2 {
+		t.Fatalf("got %d suggestions, want 2", len(resp.Msg.Suggestions))
+	}
+	place := resp.Msg.Suggestions[0].PlacePrediction
+	if place.GetPlace() != "places/ChIJpho1" || place.StructuredFormat.MainText.GetText() != "Pho Hanoi" ||
+		len(place.Text.GetMatches()) != 1 || place.Text.Matches[0].EndOffset != 3 {
+		t.Errorf("place prediction = %v", place)
+	}
+	if query := resp.Msg.Suggestions[1].QueryPrediction; query.Text.GetText() != "pho near me" {
+		t.Errorf("query prediction = %v", query)
+	}
+}
+
+func TestPlacestest_MissingFixture(t *testing.T) {
+	provider := placestest.Replay(t.TempDir())
+	_, err := provider.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/x"}, nil)
+	if err == nil || !strings.Contains(err.Error(), placestest.RecordEnv) {
+		t.Errorf("expected a hint to record the fixture, got %v", err)
+	}
+}
+
+// stubPlaces answers GetPlace with a fixed place, or NotFound for other names.
+type stubPlaces struct {
+	services.PlacesProvider
+	place *placespb.Place
+}
+
+func (s stubPlaces) GetPlace(_ context.Context, req *placespb.GetPlaceRequest, _ []string) (*placespb.Place, error) {
+	if req.Name != s.place.Name {
+		return nil, status.Error(codes.NotFound, "no such place")
+	}
+	return s.place, nil
+}
+
+func TestPlacestest_RecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	place := &placespb.Place{Name: "places/abc", DisplayName: &localized_text.LocalizedText{Text: "Pho Hanoi"}}
+	recorder := placestest.Record(dir, stubPlaces{place: place})
+
+	if _, err := recorder.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/abc"}, []string{"display_name"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/gone"}, nil); status.Code(err) != codes.NotFound {
+		t.Fatalf("recording passed on %v, want NotFound", err)
+	}
+
+	replay := placestest.Replay(dir)
+	got, err := replay.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/abc"}, []string{"display_name"})
+	if err != nil || got.GetDisplayName().GetText() != "Pho Hanoi" {
+		t.Errorf("replayed %v, %v", got, err)
+	}
+	if _, err := replay.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/gone"}, nil); status.Code(err) != codes.NotFound {
+		t.Errorf("replayed error %v, want NotFound", err)
+	}
+	// The mask is part of the recording: a different one is a different call.
+	if _, err := replay.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/abc"}, []string{"id"}); err == nil {
+		t.Error("expected no fixture for another field mask")
+	}
+}
+
+// capturedSearch records the SearchText request the service sends to Google.
+type capturedSearch struct {
+	services.PlacesProvider
+	req *placespb.SearchTextRequest
+}
+
+func (c *capturedSearch) SearchText(_ context.Context, req *placespb.SearchTextRequest, _ []string) (*placespb.SearchTextResponse, error) {
+	c.req = req
+	return &placespb.SearchTextResponse{}, nil
+}
+
+func TestGooglePlacesAPIService_SearchText_MapsRequest(t *testing.T) {
+	places := &capturedSearch{}
+	svc := services.NewGooglePlacesAPIService(places, nil)
+	_, err := svc.SearchText(context.Background(), connect.NewRequest(&v1.SearchTextRequest{
+		TextQuery:           "ramen",
+		RankPreference:      v1.RankPreference_RANK_PREFERENCE_DISTANCE,
+		IncludedType:        "ramen_restaurant",
+		StrictTypeFiltering: true,
+		MinRating:           4.5,
+		MaxResultCount:      10,
+		PriceLevels:         []v1.PriceLevel{v1.PriceLevel_PRICE_LEVEL_INEXPENSIVE, v1.PriceLevel_PRICE_LEVEL_MODERATE},
+		LocationRestriction: &v1.LocationRestriction{Type: &v1.LocationRestriction_Rectangle{Rectangle: &v1.Rectangle{
+			Low: &v1.LatLng{Lat: 52.1, Lng: 20.9}, High: &v1.LatLng{Lat: 52.3, Lng: 21.1},
+		}}},
+		IncludePureServiceAreaBusinesses: true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := places.req
+	rect := got.GetLocationRestriction().GetRectangle()
+	if got.RankPreference != placespb.SearchTextRequest_DISTANCE || !got.StrictTypeFiltering || got.MinRating != 4.5 ||
+		got.MaxResultCount != 10 || !got.IncludePureServiceAreaBusinesses ||
+		!reflect.DeepEqual(got.PriceLevels, []placespb.PriceLevel{placespb.PriceLevel_PRICE_LEVEL_INEXPENSIVE, placespb.PriceLevel_PRICE_LEVEL_MODERATE}) ||
+		rect.GetLow().GetLatitude() != 52.1 || rect.GetHigh().GetLongitude() != 21.1 || got.LocationBias != nil {
+		t.Errorf("request = %v", got)
+	}
+
+	_, err = svc.SearchText(context.Background(), connect.NewRequest(&v1.SearchTextRequest{
+		TextQuery:    "ramen",
+		LocationBias: &v1.LocationBias{Type: &v1.LocationBias_Circle{Circle: &v1.Circle{Center: &v1.LatLng{Lat: 52.2, Lng: 21}, Radius: 1500}}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := places.req.GetLocationBias().GetCircle(); c.GetRadius() != 1500 || c.GetCenter().GetLatitude() != 52.2 || places.req.LocationRestriction != nil {
+		t.Errorf("bias = %v", places.req.LocationBias)
+	}
+}
+
+func TestValidateSearchTextRequest(t *testing.T) {
+	rect := func(lowLat, lowLng, highLat, highLng float64) *v1.Rectangle {
+		return &v1.Rectangle{Low: &v1.LatLng{Lat: lowLat, Lng: lowLng}, High: &v1.LatLng{Lat: highLat, Lng: highLng}}
+	}
+	circle := &v1.Circle{Center: &v1.LatLng{Lat: 52.2, Lng: 21}, Radius: 500}
+	restrict := func(r *v1.Rectangle) *v1.LocationRestriction {
+		return &v1.LocationRestriction{Type: &v1.LocationRestriction_Rectangle{Rectangle: r}}
+	}
+	bias := &v1.LocationBias{Type: &v1.LocationBias_Circle{Circle: circle}}
+
+	for name, req := range map[string]*v1.SearchTextRequest{
+		"bias and restriction":     {LocationBias: bias, LocationRestriction: restrict(rect(52, 20, 53, 21))},
+		"restriction circle":       {LocationRestriction: &v1.LocationRestriction{Type: &v1.LocationRestriction_Circle{Circle: circle}}},
+		"empty bias":               {LocationBias: &v1.LocationBias{}},
+		"radius too large":         {LocationBias: &v1.LocationBias{Type: &v1.LocationBias_Circle{Circle: &v1.Circle{Center: circle.Center, Radius: 50001}}}},
+		"circle without center":    {LocationBias: &v1.LocationBias{Type: &v1.LocationBias_Circle{Circle: &v1.Circle{Radius: 10}}}},
+		"inverted latitudes":       {LocationRestriction: restrict(rect(53, 20, 52, 21))},
+		"latitude off Earth":       {LocationRestriction: restrict(rect(52, 20, 91, 21))},
+		"min rating":               {MinRating: 5.5},
+		"max results":              {MaxResultCount: 21},
+		"unspecified price":        {PriceLevels: []v1.PriceLevel{v1.PriceLevel_PRICE_LEVEL_UNSPECIFIED}},
+		"unknown price":            {PriceLevels: []v1.PriceLevel{42}},
+		"unknown rank":             {RankPreference: 7},
+		"distance without a place": {RankPreference: v1.RankPreference_RANK_PREFERENCE_DISTANCE},
+		"strict without a type":    {StrictTypeFiltering: true},
+		"ev options":               {EvOptions: &v1.EVOptions{IncludeEvCharging: true}},
+		"routing":                  {RoutingParameters: &v1.RoutingParameters{Origin: "a"}},
+	} {
+		req.TextQuery = "pho"
+		if err := services.ValidateSearchTextRequest(req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+
+	// Crossing the antimeridian is a valid rectangle.
+	if err := services.ValidateSearchTextRequest(&v1.SearchTextRequest{TextQuery: "pho", LocationRestriction: restrict(rect(-20, 170, -10, -170))}); err != nil {
+		t.Errorf("antimeridian rectangle: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package test
+
+import (
+	"api/src/internal/jobs"
+	"api/src/internal/models"
+	"api/src/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func mustParseSchedule(t *testing.T, spec string) jobs.Schedule {
+	t.Helper()
+	s, err := jobs.ParseSchedule(spec)
+	if err != nil {
+		t.Fatalf("ParseSchedule(%q): %v", spec, err)
+	}
+	return s
+}
+
+func TestParseSchedule_Next(t *testing.T) {
+	// Wednesday.
+	base := time.Date(2025, 3, 12, 10, 17, 30, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 12, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, 3, 12, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 12, 11, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2025, 3, 13, 3, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 6 * * 1", time.Date(2025, 3, 17, 6, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2025, 3, 12, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches (the 13th, or any Friday).
+		{"0 0 13 * 5", time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"5,10 12 * * *", time.Date(2025, 3, 12, 12, 5, 0, 0, time.UTC)},
+		{"@every 5m", time.Date(2025, 3, 12, 10, 20, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2025, 3, 12, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := mustParseSchedule(t, c.spec).Next(base); !got.Equal(c.want) {
+			t.Errorf("%q: expected %v, got %v", c.spec, c.want, got)
+		}
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 31 2 *",
+		"@every",
+		"@every 10ms",
+		"@yearly-ish",
+	} {
+		if _, err := jobs.ParseSchedule(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		50: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := jobs.DefaultBackoff(attempts); got != want {
+			t.Errorf("DefaultBackoff(%d): expected %v, got %v", attempts, want, got)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("boom")
+	err := jobs.Permanent(base)
+	if !jobs.IsPermanent(err) || !errors.Is(err, base) {
+		t.Fatalf("expected a permanent error wrapping the original, got %v", err)
+	}
+	if jobs.IsPermanent(base) {
+		t.Fatal("expected a plain error not to be permanent")
+	}
+	if jobs.Permanent(nil) != nil {
+		t.Fatal("expected Permanent(nil) to be nil")
+	}
+}
+
+type greetPayload struct {
+	Name string `json:"name"`
+}
+
+func newTestJob(t *testing.T, kind string, payload any, attempts, maxAttempts int32) *models.Job {
+	t.Helper()
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.Job{Kind: kind, Payload: raw, Status: models.JobStatusRunning, Attempts: attempts, MaxAttempts: maxAttempts}
+}
+
+func TestRunner_Process(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	runner := jobs.NewRunner(nil, utils.NewJobMetrics(reg), jobs.Config{})
+
+	var got string
+	greet := jobs.Kind[greetPayload]{Name: "test.greet", Backoff: func(int) time.Duration { return time.Hour }}
+	jobs.Handle(runner, greet, func(ctx context.Context, p greetPayload) error {
+		got = p.Name
+		switch p.Name {
+		case "retry":
+			return errors.New("try again")
+		case "permanent":
+			return jobs.Permanent(errors.New("never"))
+		case "panic":
+			panic("handler bug")
+		}
+		return nil
+	})
+
+	job := newTestJob(t, greet.Name, greetPayload{Name: "ala"}, 1, 5)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeSucceeded || job.Status != models.JobStatusSucceeded {
+		t.Fatalf("expected success, got %s/%s", outcome, job.Status)
+	}
+	if got != "ala" || job.FinishedAt == nil {
+		t.Fatalf("expected the decoded payload to reach the handler and the job to be finished, got %q", got)
+	}
+
+	job = newTestJob(t, greet.Name, greetPayload{Name: "retry"}, 1, 5)
+	before := time.Now()
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeRetried || job.Status != models.JobStatusPending {
+		t.Fatalf("expected a retry, got %s/%s", outcome, job.Status)
+	}
+	if job.RunAt.Before(before.Add(59*time.Minute)) || job.LastError != "try again" {
+		t.Fatalf("expected the kind's backoff and the error to be recorded, got %v %q", job.RunAt, job.LastError)
+	}
+
+	job = newTestJob(t, greet.Name, greetPayload{Name: "retry"}, 5, 5)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeFailed || job.Status != models.JobStatusFailed {
+		t.Fatalf("expected the last attempt to fail the job, got %s/%s", outcome, job.Status)
+	}
+
+	job = newTestJob(t, greet.Name, greetPayload{Name: "permanent"}, 1, 5)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeFailed {
+		t.Fatalf("expected a permanent error to fail the job, got %s", outcome)
+	}
+
+	job = newTestJob(t, greet.Name, greetPayload{Name: "panic"}, 1, 5)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeRetried {
+		t.Fatalf("expected a panic to be retried, got %s", outcome)
+	}
+
+	job = &models.Job{Kind: greet.Name, Payload: json.RawMessage(`"not an object"`), Attempts: 1, MaxAttempts: 5}
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeFailed {
+		t.Fatalf("expected an undecodable payload to fail permanently, got %s", outcome)
+	}
+
+	job = newTestJob(t, "test.unknown", struct{}{}, 1, 5)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeFailed {
+		t.Fatalf("expected a job without a handler to fail, got %s", outcome)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "jobs_processed_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "outcome" {
+					counts[l.GetValue()] += m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	// The job without a handler never reaches the metrics.
+	if counts[jobs.OutcomeSucceeded] != 1 || counts[jobs.OutcomeRetried] != 2 || counts[jobs.OutcomeFailed] != 3 {
+		t.Fatalf("unexpected jobs_processed_total: %v", counts)
+	}
+}
+
+func TestRunner_Process_Timeout(t *testing.T) {
+	runner := jobs.NewRunner(nil, nil, jobs.Config{})
+	slow := jobs.Kind[struct{}]{Name: "test.slow", Timeout: 10 * time.Millisecond}
+	jobs.Handle(runner, slow, func(ctx context.Context, _ struct{}) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job := newTestJob(t, slow.Name, struct{}{}, 1, 3)
+	if outcome := runner.Process(context.Background(), job); outcome != jobs.OutcomeRetried {
+		t.Fatalf("expected a timed-out job to be retried, got %s", outcome)
+	}
+}
+
+func TestHandle_DuplicatePanics(t *testing.T) {
+	runner := jobs.NewRunner(nil, nil, jobs.Config{})
+	kind := jobs.Kind[struct{}]{Name: "test.dup"}
+	jobs.Handle(runner, kind, func(context.Context, struct{}) error { return nil })
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a kind twice to panic")
+		}
+	}()
+	jobs.Handle(runner, kind, func(context.Context, struct{}) error { return nil })
+}
+
+func TestEvery_RejectsInvalidSpec(t *testing.T) {
+	runner := jobs.NewRunner(nil, nil, jobs.Config{})
+	kind := jobs.Kind[struct{}]{Name: "test.every"}
+	if err := jobs.Every(runner, "test.every", "every tuesday", kind, struct{}{}); err == nil {
+		t.Fatal("expected an invalid spec to be rejected")
+	}
+}
+
+func TestRunner_ReapExpiredLeases_DoesNotConflictWithPendingDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	runner := jobs.NewRunner(db, nil, jobs.Config{})
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	key := "schedule:test.tick"
+	rows := []models.Job{
+		// A running job still holding the key, as claimed before keys were dropped on claim,
+		// and the next activation of its schedule, queued since.
+		{Kind: "test.tick", Payload: json.RawMessage(`{}`), Status: models.JobStatusRunning, Attempts: 1, MaxAttempts: 3,
+			RunAt: expired, DedupeKey: &key, LockedBy: "gone", LeaseExpiresAt: &expired},
+		{Kind: "test.tick", Payload: json.RawMessage(`{}`), Status: models.JobStatusPending, MaxAttempts: 3,
+			RunAt: now, DedupeKey: &key},
+		{Kind: "test.other", Payload: json.RawMessage(`{}`), Status: models.JobStatusRunning, Attempts: 1, MaxAttempts: 3,
+			RunAt: expired, LockedBy: "gone", LeaseExpiresAt: &expired},
+		{Kind: "test.other", Payload: json.RawMessage(`{}`), Status: models.JobStatusRunning, Attempts: 3, MaxAttempts: 3,
+			RunAt: expired, LockedBy: "gone", LeaseExpiresAt: &expired},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.ReapExpiredLeases(context.Background(), now); err != nil {
+		t.Fatalf("ReapExpiredLeases: %v", err)
+	}
+	want := []string{models.JobStatusPending, models.JobStatusPending, models.JobStatusPending, models.JobStatusFailed}
+	for i, row := range rows {
+		var got models.Job
+		if err := db.First(&got, "id = ?", row.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want[i] || got.LeaseExpiresAt != nil {
+			t.Errorf("job %d: status %q, lease %v; want %q and no lease", i, got.Status, got.LeaseExpiresAt, want[i])
+		}
+	}
+	if n := countRows(t, db, "jobs", "dedupe_key = ?", key); n != 1 {
+		t.Fatalf("expected the key to stay on the queued job only, found it on %d", n)
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"api/src/internal/migrations"
+	"api/src/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/gorm/schema"
+)
+
+func TestLoadMigrations_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id text);")},
+		"0002_add_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+		"0001_baseline.up.sql":      {Data: []byte("CREATE TABLE users (id text);")},
+		"0010_irreversible.up.sql":  {Data: []byte("UPDATE users SET id = id;")},
+	}
+	got, err := migrations.Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d migrations, want 3", len(got))
+	}
+	for i, want := range []struct {
+		version int64
+		name    string
+		hasDown bool
+	}{{1, "baseline", false}, {2, "add_widgets", true}, {10, "irreversible", false}} {
+		if got[i].Version != want.version || got[i].Name != want.name || (got[i].Down != "") != want.hasDown {
+			t.Errorf("migration %d = %d_%s (down %v), want %d_%s (down %v)",
+				i, got[i].Version, got[i].Name, got[i].Down != "", want.version, want.name, want.hasDown)
+		}
+	}
+}
+
+func TestLoadMigrations_Rejects(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"bad name": {
+			"0001-baseline.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"missing up": {
+			"0001_baseline.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"empty up": {
+			"0001_baseline.up.sql": {Data: []byte("  \n")},
+		},
+		"two names for one version": {
+			"0001_baseline.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_other.up.sql":    {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := migrations.Load(fsys); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations_AreReversible(t *testing.T) {
+	all, err := migrations.Load(migrations.Files())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(all) == 0 || all[0].Version != 1 {
+		t.Fatal("expected the baseline to be migration 1")
+	}
+	for i, m := range all {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s breaks the numbering; want version %d", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+// Every model must have its table created by some migration now that AutoMigrate is gone.
+func TestEmbeddedMigrations_CreateEveryModelTable(t *testing.T) {
+	all, err := migrations.Load(migrations.Files())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var up strings.Builder
+	for _, m := range all {
+		up.WriteString(m.Up)
+	}
+	sql := up.String()
+
+	for _, model := range []any{
+		&models.User{}, &models.Restaurant{}, &models.PrivacySettings{}, &models.Circle{}, &models.CircleMember{},
+		&models.Review{}, &models.Tag{}, &models.TagTranslation{}, &models.WishlistItem{}, &models.FriendRequest{},
+		&models.SharedList{}, &models.PersonalTag{}, &models.Dish{}, &models.ReviewDish{},
+		&models.Notification{}, &models.NotificationPreference{}, &models.EmailOutbox{},
+		&models.PushSubscription{}, &models.PushOutbox{}, &models.Job{}, &models.JobSchedule{}, &models.PlaceLink{},
+		&models.RestaurantRefreshUsage{},
+	} {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		if !strings.Contains(sql, "CREATE TABLE IF NOT EXISTS "+s.Table+" (") {
+			t.Errorf("no migration creates table %q for %T", s.Table, model)
+		}
+	}
+}
+
+func TestCreateMigration_NumbersAfterLatest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0001_baseline.up.sql", "0001_baseline.down.sql", "0007_later.up.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := migrations.Create(dir, "Add Widget Colours!")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []string{filepath.Join(dir, "0008_add_widget_colours.up.sql"), filepath.Join(dir, "0008_add_widget_colours.down.sql")}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+	// The new pair must itself load.
+	if _, err := migrations.Load(os.DirFS(dir)); err != nil {
+		t.Errorf("Load after Create: %v", err)
+	}
+
+	if _, err := migrations.Create(dir, "!!!"); err == nil {
+		t.Error("expected an error for a name without letters or digits")
+	}
+}
+
+func TestCreateMigration_EmptyDir(t *testing.T) {
+	paths, err := migrations.Create(t.TempDir(), "first")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(paths[0]) != "0001_first.up.sql" {
+		t.Errorf("got %s, want 0001_first.up.sql", filepath.Base(paths[0]))
+	}
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	notificationsv1 "api/src/generated/notifications/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+)
+
+func TestNotificationsService_ListNotifications_NoSession(t *testing.T) {
+	svc := &services.NotificationsService{}
+	req := connect.NewRequest(&notificationsv1.ListNotificationsRequest{})
+	_, err := svc.ListNotifications(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestNotificationsService_MarkRead_NoSession(t *testing.T) {
+	svc := &services.NotificationsService{}
+	req := connect.NewRequest(&notificationsv1.MarkReadRequest{Ids: []string{"n1"}})
+	_, err := svc.MarkRead(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestNotification_AddActor_Coalesces(t *testing.T) {
+	n := models.Notification{}
+	if !n.AddActor("a") || !n.AddActor("b") {
+		t.Fatal("expected new actors to be reported as new")
+	}
+	if n.AddActor("a") {
+		t.Fatal("expected repeated actor not to be reported as new")
+	}
+	if n.ActorCount != 2 {
+		t.Fatalf("expected actor count 2, got %d", n.ActorCount)
+	}
+	if n.ActorIDs[0] != "a" || n.ActorIDs[1] != "b" {
+		t.Fatalf("expected most recent actor first, got %v", n.ActorIDs)
+	}
+}
+
+func TestNotification_AddActor_CapsActorIDs(t *testing.T) {
+	n := models.Notification{}
+	for i := 0; i < models.MaxNotificationActors+5; i++ {
+		n.AddActor(fmt.Sprintf("user-%d", i))
+	}
+	if len(n.ActorIDs) != models.MaxNotificationActors {
+		t.Fatalf("expected %d actor IDs, got %d", models.MaxNotificationActors, len(n.ActorIDs))
+	}
+	if int(n.ActorCount) != models.MaxNotificationActors+5 {
+		t.Fatalf("expected actor count to keep counting, got %d", n.ActorCount)
+	}
+}
+
+func TestNotification_ToProto_SkipsUnknownActors(t *testing.T) {
+	n := models.Notification{ActorIDs: []string{"gone", "a"}, ActorCount: 2}
+	p := n.ToProto(map[string]models.User{"a": {UUIDv7: models.UUIDv7{ID: "a"}, Name: "Ania"}})
+	if len(p.Actors) != 1 || p.Actors[0].Name != "Ania" {
+		t.Fatalf("expected only the known actor, got %v", p.Actors)
+	}
+	if p.ActorCount != 2 {
+		t.Fatalf("expected actor count 2, got %d", p.ActorCount)
+	}
+	if p.Read {
+		t.Fatal("expected notification without read_at to be unread")
+	}
+}
+
+func TestNotificationType_RoundTrip(t *testing.T) {
+	for _, stored := range models.NotificationTypes {
+		if got := models.NotificationTypeFromProto(models.NotificationTypeToProto(stored)); got != stored {
+			t.Errorf("notification type %q round-tripped to %q", stored, got)
+		}
+	}
+	if got := models.NotificationTypeFromProto(notificationsv1.NotificationType_NOTIFICATION_TYPE_UNSPECIFIED); got != "" {
+		t.Fatalf("expected unspecified type to map to empty string, got %q", got)
+	}
+}
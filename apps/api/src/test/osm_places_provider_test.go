@@ -0,0 +1,284 @@
+package test
+
+import (
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"google.golang.org/genproto/googleapis/geo/type/viewport"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const nominatimPho = `[
+  {"osm_type":"node","osm_id":101,"lat":"52.2301","lon":"21.0102","category":"amenity","type":"restaurant","name":"Pho Hanoi",
+   "address":{"house_number":"1","road":"Nowy Świat","city":"Warszawa","postcode":"00-001","country":"Polska","country_code":"pl"},
+   "extratags":{"cuisine":"vietnamese","outdoor_seating":"yes","takeaway":"only","website":"https://pho.example"}},
+  {"osm_type":"way","osm_id":202,"lat":"52.2400","lon":"21.0200","category":"highway","type":"residential","name":"Phoenix Street",
+   "address":{"road":"Phoenix Street","city":"Warszawa","country_code":"pl"}},
+  {"osm_type":"node","osm_id":303,"lat":"52.2500","lon":"21.0300","category":"amenity","type":"fast_food","name":"Pho Express",
+   "address":{"road":"Marszałkowska","house_number":"10","city":"Warszawa","country_code":"pl"}}
+]`
+
+const overpassNode101 = `{"elements":[
+  {"type":"node","id":101,"lat":52.2301,"lon":21.0102,"tags":{"amenity":"restaurant","name":"Pho Hanoi","cuisine":"vietnamese",
+   "addr:street":"Nowy Świat","addr:housenumber":"1","addr:city":"Warszawa","addr:postcode":"00-001","addr:country":"PL",
+   "diet:vegetarian":"yes","dog":"no","phone":"+48 22 000 00 00"}}
+]}`
+
+const overpassNearby = `{"elements":[
+  {"type":"node","id":900,"lat":52.2302,"lon":21.0103,"tags":{"amenity":"cafe","name":"Kawiarnia"}},
+  {"type":"way","id":101,"center":{"lat":52.2301,"lon":21.0102},"tags":{"amenity":"restaurant","name":"PHO HANOI!"}}
+]}`
+
+// osmStandIn serves Nominatim searches and Overpass queries from canned responses.
+type osmStandIn struct {
+	mu        sync.Mutex
+	overpass  map[string]string // response by a substring of the query
+	queries   []string
+	searches  []string
+	userAgent string
+	status    int
+}
+
+func (s *osmStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userAgent = r.Header.Get("User-Agent")
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	switch r.URL.Path {
+	case "/search":
+		s.searches = append(s.searches, r.URL.RawQuery)
+		w.Write([]byte(nominatimPho))
+	case "/interpreter":
+		query := r.PostFormValue("data")
+		s.queries = append(s.queries, query)
+		for substr, resp := range s.overpass {
+			if strings.Contains(query, substr) {
+				w.Write([]byte(resp))
+				return
+			}
+		}
+		w.Write([]byte(`{"elements":[]}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// memoryLinks is a PlaceLinkStore over maps.
+type memoryLinks struct {
+	links       map[string]string
+	restaurants map[string]*models.Restaurant
+}
+
+func (l *memoryLinks) OSMName(_ context.Context, googleName string) (string, error) {
+	return l.links[googleName], nil
+}
+
+func (l *memoryLinks) Restaurant(_ context.Context, googleName string) (*models.Restaurant, error) {
+	return l.restaurants[googleName], nil
+}
+
+func (l *memoryLinks) Link(_ context.Context, googleName, osmName string) error {
+	if l.links == nil {
+		l.links = map[string]string{}
+	}
+	l.links[googleName] = osmName
+	return nil
+}
+
+func newOSMProvider(t *testing.T) (*services.OSMPlacesProvider, *osmStandIn, *memoryLinks) {
+	t.Helper()
+	standIn := &osmStandIn{overpass: map[string]string{"node(101)": overpassNode101, "around:": overpassNearby}}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	links := &memoryLinks{}
+	provider := services.NewOSMPlacesProvider(services.OSMConfig{
+		NominatimURL: srv.URL,
+		OverpassURL:  srv.URL + "/interpreter",
+		UserAgent:    "resto-rate-test",
+		// The stand-in has no usage policy; keep the tests quick.
+		NominatimRate: 1000,
+	}, links)
+	return provider, standIn, links
+}
+
+func TestOSMPlaceName(t *testing.T) {
+	name := services.OSMPlaceName("way", 42)
+	if typ, id, ok := services.ParseOSMPlaceName(name); !ok || typ != "way" || id != 42 {
+		t.Errorf("ParseOSMPlaceName(%q) = %q, %d, %v", name, typ, id, ok)
+	}
+	for _, bad := range []string{"places/ChIJabc", "places/osm-area-1", "places/osm-node-x", "places/osm-node-0"} {
+		if _, _, ok := services.ParseOSMPlaceName(bad); ok {
+			t.Errorf("ParseOSMPlaceName(%q) accepted", bad)
+		}
+	}
+}
+
+func TestOSMPlacesProvider_SearchText(t *testing.T) {
+	provider, standIn, _ := newOSMProvider(t)
+	resp, err := provider.SearchText(context.Background(), &placespb.SearchTextRequest{
+		TextQuery: "pho warszawa", IncludedType: "restaurant", LanguageCode: "pl", RegionCode: "PL",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Places) != 2 {
+		t.Fatalf("got %d places, want the restaurant and the fast food place", len(resp.Places))
+	}
+	pho := resp.Places[0]
+	if pho.Name != "places/osm-node-101" || pho.Id != "osm-node-101" || pho.GetDisplayName().GetText() != "Pho Hanoi" ||
+		pho.FormattedAddress != "Nowy Świat 1, 00-001 Warszawa, Polska" || pho.GetPostalAddress().GetRegionCode() != "PL" ||
+		pho.GetLocation().GetLatitude() != 52.2301 || pho.PrimaryType != "restaurant" || pho.Types[0] != "vietnamese_restaurant" ||
+		pho.WebsiteUri != "https://pho.example" || !pho.GetOutdoorSeating() || !pho.GetTakeout() || pho.Delivery != nil ||
+		pho.BusinessStatus != placespb.Place_OPERATIONAL {
+		t.Errorf("place = %v", pho)
+	}
+	if resp.Places[1].PrimaryType != "fast_food_restaurant" {
+		t.Errorf("second place = %v", resp.Places[1])
+	}
+	if q := standIn.searches[0]; !strings.Contains(q, "accept-language=pl") || !strings.Contains(q, "countrycodes=pl") {
+		t.Errorf("search query = %s", q)
+	}
+	if standIn.userAgent != "resto-rate-test" {
+		t.Errorf("User-Agent = %q", standIn.userAgent)
+	}
+}
+
+func TestOSMPlacesProvider_AutocompletePlaces(t *testing.T) {
+	provider, _, _ := newOSMProvider(t)
+	resp, err := provider.AutocompletePlaces(context.Background(), &placespb.AutocompletePlacesRequest{
+		Input: "pho", IncludedPrimaryTypes: []string{"restaurant"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Suggestions) != 2 {
+		t.Fatalf("got %d suggestions, want 2", len(resp.Suggestions))
+	}
+	p := resp.Suggestions[0].GetPlacePrediction()
+	if p.Place != "places/osm-node-101" || p.GetText().GetText() != "Pho Hanoi, Nowy Świat 1, Warszawa" ||
+		p.GetStructuredFormat().GetMainText().GetMatches()[0].GetEndOffset() != 3 ||
+		p.GetStructuredFormat().GetSecondaryText().GetText() != "Nowy Świat 1, Warszawa" {
+		t.Errorf("prediction = %v", p)
+	}
+}
+
+func TestOSMPlacesProvider_AutocompletePlaces_NotOnThePublicInstance(t *testing.T) {
+	provider := services.NewOSMPlacesProvider(services.OSMConfig{NominatimURL: "https://nominatim.openstreetmap.org"}, &memoryLinks{})
+	_, err := provider.AutocompletePlaces(context.Background(), &placespb.AutocompletePlacesRequest{Input: "pho"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("autocompletion on the public instance gave %v, want FailedPrecondition", err)
+	}
+}
+
+func TestOSMPlacesProvider_RateLimitsNominatim(t *testing.T) {
+	standIn := &osmStandIn{}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	cfg := services.OSMConfig{NominatimURL: srv.URL, NominatimRate: 1}
+	first := services.NewOSMPlacesProvider(cfg, &memoryLinks{})
+	// A second provider for the same instance shares the limit.
+	second := services.NewOSMPlacesProvider(cfg, &memoryLinks{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := first.SearchText(ctx, &placespb.SearchTextRequest{TextQuery: "pho"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.SearchText(ctx, &placespb.SearchTextRequest{TextQuery: "pho"}, nil); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("a second search within the second gave %v, want ResourceExhausted", err)
+	}
+	if len(standIn.searches) != 1 {
+		t.Errorf("Nominatim got %d searches, want 1", len(standIn.searches))
+	}
+}
+
+func TestOSMPlacesProvider_GetPlace(t *testing.T) {
+	provider, _, _ := newOSMProvider(t)
+	place, err := provider.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/osm-node-101"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if place.Name != "places/osm-node-101" || place.FormattedAddress != "Nowy Świat 1, 00-001 Warszawa, Poland" ||
+		!place.GetServesVegetarianFood() || place.AllowsDogs == nil || place.GetAllowsDogs() ||
+		place.InternationalPhoneNumber != "+48 22 000 00 00" {
+		t.Errorf("place = %v", place)
+	}
+
+	if _, err := provider.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/osm-node-5"}, nil); status.Code(err) != codes.NotFound {
+		t.Errorf("a missing element gave %v, want NotFound", err)
+	}
+}
+
+func TestOSMPlacesProvider_GetPlace_LinksGooglePlaces(t *testing.T) {
+	provider, standIn, links := newOSMProvider(t)
+	lat, lng := 52.2301, 21.0102
+	links.restaurants = map[string]*models.Restaurant{
+		"places/ChIJpho": {GoogleID: "places/ChIJpho", Name: "Pho Hanoi", Latitude: &lat, Longitude: &lng},
+	}
+	standIn.overpass["way(101)"] = strings.Replace(overpassNode101, `"type":"node"`, `"type":"way"`, 1)
+
+	for i := 0; i < 2; i++ {
+		place, err := provider.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/ChIJpho"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if place.Name != "places/ChIJpho" || place.GetDisplayName().GetText() != "Pho Hanoi" {
+			t.Errorf("place = %v", place)
+		}
+	}
+	if links.links["places/ChIJpho"] != "places/osm-way-101" {
+		t.Errorf("links = %v", links.links)
+	}
+	nearby := 0
+	for _, q := range standIn.queries {
+		if strings.Contains(q, "around:") {
+			nearby++
+		}
+	}
+	if nearby != 1 {
+		t.Errorf("searched nearby %d times, want once", nearby)
+	}
+
+	if _, err := provider.GetPlace(context.Background(), &placespb.GetPlaceRequest{Name: "places/ChIJunknown"}, nil); status.Code(err) != codes.NotFound {
+		t.Errorf("an unknown Google place gave %v, want NotFound", err)
+	}
+}
+
+func TestOSMPlacesProvider_Errors(t *testing.T) {
+	provider, standIn, _ := newOSMProvider(t)
+	for code, want := range map[int]codes.Code{http.StatusTooManyRequests: codes.ResourceExhausted, http.StatusBadGateway: codes.Unavailable} {
+		standIn.status = code
+		if _, err := provider.SearchText(context.Background(), &placespb.SearchTextRequest{TextQuery: "pho"}, nil); status.Code(err) != want {
+			t.Errorf("HTTP %d gave %v, want %v", code, err, want)
+		}
+	}
+}
+
+func TestOSMPlacesProvider_SearchText_LocationRestriction(t *testing.T) {
+	provider, standIn, _ := newOSMProvider(t)
+	_, err := provider.SearchText(context.Background(), &placespb.SearchTextRequest{
+		TextQuery: "pho",
+		LocationRestriction: &placespb.SearchTextRequest_LocationRestriction{Type: &placespb.SearchTextRequest_LocationRestriction_Rectangle{
+			Rectangle: &viewport.Viewport{Low: &latlng.LatLng{Latitude: 52.1, Longitude: 20.9}, High: &latlng.LatLng{Latitude: 52.3, Longitude: 21.1}},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := standIn.searches[0]; !strings.Contains(q, "viewbox=20.9%2C52.1%2C21.1%2C52.3") || !strings.Contains(q, "bounded=1") {
+		t.Errorf("search query = %s", q)
+	}
+}
@@ -0,0 +1,273 @@
+package test
+
+import (
+	"api/src/internal/utils"
+	"api/src/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const testPhotoName = "places/ChIJabc/photos/AUc7t"
+
+// memoryPhotoURIs is a PhotoURIStore without expiry.
+type memoryPhotoURIs struct {
+	mu   sync.Mutex
+	uris map[string]string
+}
+
+func (s *memoryPhotoURIs) Get(_ context.Context, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uri, ok := s.uris[key]
+	return uri, ok
+}
+
+func (s *memoryPhotoURIs) Set(_ context.Context, key, uri string, _ time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uris == nil {
+		s.uris = map[string]string{}
+	}
+	s.uris[key] = uri
+}
+
+// photoMediaStandIn answers Places photo media requests with status, counting them.
+type photoMediaStandIn struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+}
+
+func (s *photoMediaStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	if s.status != http.StatusOK {
+		w.WriteHeader(s.status)
+		w.Write([]byte(`{"error":{"code":400,"message":"key=secret is invalid","status":"INVALID_ARGUMENT"}}`))
+		return
+	}
+	w.Write([]byte(`{"name":"` + r.URL.Path[len("/v1/"):] + `","photoUri":"https://lh3.example/photo?w=` + r.URL.Query().Get("maxWidthPx") + `"}`))
+}
+
+func (s *photoMediaStandIn) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func newPhotoService(t *testing.T, cfg services.PlacePhotoConfig) (*services.PlacePhotoService, *photoMediaStandIn, *prometheus.Registry) {
+	t.Helper()
+	standIn := &photoMediaStandIn{status: http.StatusOK}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	cfg.BaseURL = srv.URL + "/v1/"
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	cfg.Timeout = time.Second
+	reg := prometheus.NewRegistry()
+	authorize := func(req *http.Request) error {
+		req.Header.Set("X-Goog-Api-Key", "secret")
+		return nil
+	}
+	return services.NewPlacePhotoService(cfg, &memoryPhotoURIs{}, authorize, utils.NewPlacePhotoMetrics(reg)), standIn, reg
+}
+
+func getPhoto(t *testing.T, h http.Handler, query string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/place-photo?"+query, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// photoResults returns place_photo_requests_total by result.
+func photoResults(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "place_photo_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			got[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		}
+	}
+	return got
+}
+
+func TestPlacePhotoService_CachesAndRedirects(t *testing.T) {
+	svc, standIn, reg := newPhotoService(t, services.PlacePhotoConfig{CacheTTL: 30 * time.Minute})
+	h := svc.Handler()
+
+	first := getPhoto(t, h, "name="+testPhotoName, nil)
+	if first.Code != http.StatusFound || first.Header().Get("Location") != "https://lh3.example/photo?w=800" {
+		t.Fatalf("got %d to %q", first.Code, first.Header().Get("Location"))
+	}
+	if first.Header().Get("Cache-Control") != "public, max-age=1800" || first.Header().Get("ETag") == "" {
+		t.Errorf("headers = %v", first.Header())
+	}
+	if req := standIn.requests[0]; req.Header.Get("X-Goog-Api-Key") != "secret" || req.URL.Query().Get("skipHttpRedirect") != "true" {
+		t.Errorf("upstream request = %v", req.URL)
+	}
+
+	if again := getPhoto(t, h, "name="+testPhotoName, nil); again.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("cached redirect = %v", again.Header())
+	}
+	notModified := getPhoto(t, h, "name="+testPhotoName, http.Header{"If-None-Match": {`"other", ` + first.Header().Get("ETag")}})
+	if notModified.Code != http.StatusNotModified {
+		t.Errorf("conditional request got %d", notModified.Code)
+	}
+	small := getPhoto(t, h, "name="+testPhotoName+"&width=200&height=100", nil)
+	if small.Header().Get("ETag") == first.Header().Get("ETag") || small.Header().Get("Location") != "https://lh3.example/photo?w=200" {
+		t.Errorf("another size = %v", small.Header())
+	}
+	if standIn.count() != 2 || standIn.requests[1].URL.Query().Get("maxHeightPx") != "100" {
+		t.Errorf("upstream called %d times, want once per size", standIn.count())
+	}
+
+	got := photoResults(t, reg)
+	if got["hit"] != 1 || got["miss"] != 2 || got["not_modified"] != 1 {
+		t.Errorf("metrics = %v", got)
+	}
+
+	// Once the cached URI expires and Google hands out another, revalidation gets it.
+	uris := svc.URIs.(*memoryPhotoURIs)
+	uris.mu.Lock()
+	for key := range uris.uris {
+		uris.uris[key] = "https://lh3.example/photo?rotated"
+	}
+	uris.mu.Unlock()
+	revalidated := getPhoto(t, h, "name="+testPhotoName, http.Header{"If-None-Match": {first.Header().Get("ETag")}})
+	if revalidated.Code != http.StatusFound || revalidated.Header().Get("Location") != "https://lh3.example/photo?rotated" ||
+		revalidated.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Errorf("revalidation after the URI changed got %d: %v", revalidated.Code, revalidated.Header())
+	}
+}
+
+func TestPlacePhotoService_RejectsBadRequests(t *testing.T) {
+	svc, standIn, _ := newPhotoService(t, services.PlacePhotoConfig{})
+	for _, query := range []string{
+		"",
+		"name=places/abc",
+		"name=places/abc/photos/x/../../other",
+		"name=" + testPhotoName + "&width=801",
+		"name=" + testPhotoName + "&height=big",
+	} {
+		if rec := getPhoto(t, svc.Handler(), query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q got %d, want 400", query, rec.Code)
+		}
+	}
+	if standIn.count() != 0 {
+		t.Errorf("bad requests reached Google %d times", standIn.count())
+	}
+}
+
+func TestPlacePhotoService_UpstreamErrors(t *testing.T) {
+	svc, standIn, reg := newPhotoService(t, services.PlacePhotoConfig{})
+	h := svc.Handler()
+
+	standIn.status = http.StatusBadRequest
+	if rec := getPhoto(t, h, "name="+testPhotoName, nil); rec.Code != http.StatusNotFound || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("a rejected name got %d, %v", rec.Code, rec.Header())
+	}
+
+	// Google's own failures open the circuit; rejected names above did not count.
+	standIn.status = http.StatusInternalServerError
+	for i := 0; i < 5; i++ {
+		if rec := getPhoto(t, h, "name="+testPhotoName, nil); rec.Code != http.StatusBadGateway {
+			t.Fatalf("failure %d got %d", i, rec.Code)
+		}
+	}
+	standIn.status = http.StatusOK
+	if rec := getPhoto(t, h, "name="+testPhotoName, nil); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("open circuit got %d, %v", rec.Code, rec.Header())
+	}
+	if standIn.count() != 6 {
+		t.Errorf("upstream called %d times, want 6", standIn.count())
+	}
+	got := photoResults(t, reg)
+	if got["upstream_error"] != 6 || got["circuit_open"] != 1 {
+		t.Errorf("metrics = %v", got)
+	}
+}
+
+func TestPlacePhotoService_RateLimitsClients(t *testing.T) {
+	svc, _, reg := newPhotoService(t, services.PlacePhotoConfig{RateLimit: 4})
+	h := svc.Handler()
+	from := func(addr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/place-photo?name="+testPhotoName, nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	// A burst of half a minute's worth, then nothing until the bucket refills.
+	for i, want := range []int{http.StatusFound, http.StatusFound, http.StatusTooManyRequests} {
+		if got := from("192.0.2.1:5000"); got != want {
+			t.Errorf("request %d got %d, want %d", i, got, want)
+		}
+	}
+	if got := from("192.0.2.2:5000"); got != http.StatusFound {
+		t.Errorf("another client got %d", got)
+	}
+	if got := photoResults(t, reg)["rate_limited"]; got != 1 {
+		t.Errorf("rate_limited = %v", got)
+	}
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &services.CircuitBreaker{Threshold: 2, Cooldown: time.Minute, Now: func() time.Time { return now }}
+	b.Record(true)
+	if !b.Allow() {
+		t.Fatal("one failure opened the circuit")
+	}
+	b.Record(true)
+	if b.Allow() {
+		t.Fatal("the circuit is closed after two failures")
+	}
+	now = now.Add(time.Minute)
+	if !b.Allow() || b.Allow() {
+		t.Fatal("after the cooldown exactly one probe should be allowed")
+	}
+	b.Record(true)
+	if b.Allow() {
+		t.Fatal("a failed probe should reopen the circuit")
+	}
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Record(false)
+	if !b.Allow() || !b.Allow() {
+		t.Error("a successful probe should close the circuit")
+	}
+}
+
+func TestPlacePhotoConfigFromEnv(t *testing.T) {
+	t.Setenv("PLACE_PHOTO_CACHE_TTL", "")
+	t.Setenv("PLACE_PHOTO_TIMEOUT", "")
+	t.Setenv("PLACE_PHOTO_RATE_LIMIT", "")
+	t.Setenv("PLACE_PHOTO_TRUST_FORWARDED_FOR", "")
+	cfg, err := services.PlacePhotoConfigFromEnv()
+	if err != nil || cfg.CacheTTL != time.Hour || cfg.Timeout != 5*time.Second || cfg.RateLimit != 120 || cfg.TrustForwardedFor {
+		t.Errorf("defaults = %+v, %v", cfg, err)
+	}
+	t.Setenv("PLACE_PHOTO_RATE_LIMIT", "-1")
+	if _, err := services.PlacePhotoConfigFromEnv(); err == nil {
+		t.Error("expected an error for a negative rate limit")
+	}
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/genproto/googleapis/type/localized_text"
+	"google.golang.org/genproto/googleapis/type/postaladdress"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlaceResolver_Lookup(t *testing.T) {
+	fetcher := &fakePlaceFetcher{place: &placespb.Place{Id: "abc"}}
+	resolver := services.NewPlaceResolver(fetcher)
+
+	place, err := resolver.Lookup(context.Background(), "places/abc")
+	if err != nil || place.GetId() != "abc" || fetcher.name != "places/abc" {
+		t.Fatalf("Lookup = %v, %v (fetched %q)", place, err, fetcher.name)
+	}
+	for _, want := range []string{"display_name", "formatted_address", "postal_address", "location"} {
+		found := false
+		for _, f := range fetcher.fields {
+			found = found || f == want
+		}
+		if !found {
+			t.Errorf("field mask %v lacks %q", fetcher.fields, want)
+		}
+	}
+
+	fetcher.place, fetcher.err = nil, status.Error(codes.Unavailable, "down")
+	if _, err := resolver.Lookup(context.Background(), "places/abc"); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected the fetch error, got %v", err)
+	}
+	fetcher.err = nil
+	if _, err := resolver.Lookup(context.Background(), "places/abc"); err == nil {
+		t.Error("expected an error for an empty place")
+	}
+}
+
+func TestPlaceResolver_Details(t *testing.T) {
+	fetcher := &fakePlaceFetcher{place: &placespb.Place{DisplayName: &localized_text.LocalizedText{Text: "Pho Hanoi"}}}
+	resolver := services.NewPlaceResolver(fetcher)
+	hints := services.RestaurantHints{Name: "Totally Real Bistro"}
+
+	got, err := resolver.Details(context.Background(), "places/abc", hints)
+	if err != nil || got.Name != "Pho Hanoi" || got.RefreshedAt == nil {
+		t.Errorf("a known place = %+v, %v", got, err)
+	}
+
+	fetcher.place = nil
+	for _, code := range []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted} {
+		fetcher.err = status.Error(code, "try later")
+		if got, err := resolver.Details(context.Background(), "places/abc", hints); err != nil || got.Name != hints.Name {
+			t.Errorf("%v: %+v, %v; want the hints", code, got, err)
+		}
+	}
+	for _, code := range []codes.Code{codes.NotFound, codes.InvalidArgument} {
+		fetcher.err = status.Error(code, "no such place")
+		if _, err := resolver.Details(context.Background(), "places/made-up", hints); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%v: got %v, want InvalidArgument", code, err)
+		}
+	}
+	fetcher.err = status.Error(codes.PermissionDenied, "bad key")
+	if _, err := resolver.Details(context.Background(), "places/abc", hints); connect.CodeOf(err) != connect.CodeInternal {
+		t.Errorf("a misconfigured provider gave %v, want Internal", err)
+	}
+}
+
+func TestPlaceResolver_Resolve(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	// Looked up before, but Google has no photo of it: nothing to look up again.
+	looked := models.Restaurant{GoogleID: "places/nophoto", Name: "Pho Hanoi", Address: "1 Main St", RefreshedAt: &now}
+	// Stored from hints while Google Places was down.
+	hinted := models.Restaurant{GoogleID: "places/hinted", Name: "Whatever the client said", Address: "2 Main St"}
+	for _, r := range []*models.Restaurant{&looked, &hinted} {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	fetcher := &fakePlaceFetcher{place: &placespb.Place{DisplayName: &localized_text.LocalizedText{Text: "Bun Cha"}}}
+	resolver := services.NewPlaceResolver(fetcher)
+
+	if got, err := resolver.Resolve(ctx, db, looked.GoogleID, services.RestaurantHints{}); err != nil || got.ID != looked.ID || fetcher.name != "" {
+		t.Errorf("a looked-up place = %+v, %v (fetched %q); want the stored row", got, err, fetcher.name)
+	}
+	if got, err := resolver.Resolve(ctx, db, hinted.GoogleID, services.RestaurantHints{}); err != nil || got.Name != "Bun Cha" || fetcher.name != hinted.GoogleID {
+		t.Errorf("a place stored from hints = %+v, %v; want it looked up", got, err)
+	}
+
+	var none *services.PlaceResolver
+	if _, err := none.Resolve(ctx, db, "places/new", services.RestaurantHints{Name: "Made up"}); connect.CodeOf(err) != connect.CodeInternal {
+		t.Errorf("a resolver without a provider gave %v, want Internal", err)
+	}
+	if got, err := none.Resolve(ctx, db, looked.GoogleID, services.RestaurantHints{}); err != nil || got.ID != looked.ID {
+		t.Errorf("a resolver without a provider should still return stored places, got %+v, %v", got, err)
+	}
+}
+
+func TestRestaurantFromPlace(t *testing.T) {
+	got := services.RestaurantFromPlace("places/abc", &placespb.Place{
+		DisplayName:      &localized_text.LocalizedText{Text: "Pho Hanoi"},
+		FormattedAddress: "Nowy Świat 1, 00-001 Warszawa, Poland",
+		PostalAddress:    &postaladdress.PostalAddress{Locality: "Warszawa", RegionCode: "PL"},
+		Photos:           []*placespb.Photo{{Name: "places/abc/photos/1"}, {Name: "places/abc/photos/2"}},
+		BusinessStatus:   placespb.Place_OPERATIONAL,
+		Location:         &latlng.LatLng{Latitude: 52.23, Longitude: 21.01},
+	})
+	if got.GoogleID != "places/abc" || got.Name != "Pho Hanoi" || got.Address != "Nowy Świat 1, 00-001 Warszawa, Poland" ||
+		got.City != "Warszawa" || got.Country != "Poland" || got.PhotoReference != "places/abc/photos/1" ||
+		got.BusinessStatus != models.BusinessStatusOperational || !got.HasLocation() || got.Geohash == "" {
+		t.Errorf("RestaurantFromPlace = %+v", got)
+	}
+
+	if empty := services.RestaurantFromPlace("places/x", &placespb.Place{}); empty.BusinessStatus != "" || empty.HasLocation() {
+		t.Errorf("a place without details = %+v", empty)
+	}
+}
+
+func TestRestaurantFromHints(t *testing.T) {
+	lat, lng := 52.23, 21.01
+	got := services.RestaurantFromHints("places/abc", services.RestaurantHints{
+		Name: "Pho", Address: "Somewhere 1", City: "Warsaw", Country: "Poland", Latitude: &lat,
+	})
+	if got.Name != "Pho" || got.City != "Warsaw" || got.HasLocation() {
+		t.Errorf("hints with half a location = %+v", got)
+	}
+	got = services.RestaurantFromHints("places/abc", services.RestaurantHints{Latitude: &lat, Longitude: &lng})
+	if !got.HasLocation() || got.Geohash != models.Geohash(lat, lng, models.GeohashPrecision) {
+		t.Errorf("hints with a location = %+v", got)
+	}
+}
@@ -0,0 +1,303 @@
+package test
+
+import (
+	v1 "api/src/generated/google_maps/v1"
+	"api/src/internal/cache"
+	"api/src/internal/utils"
+	"api/src/services"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"connectrpc.com/connect"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/type/localized_text"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// memoryStore is a PlacesCacheStore without expiry. It builds keys like the cache.ProtoCache
+// it embeds, which it never lets reach Valkey.
+type memoryStore struct {
+	*cache.ProtoCache
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryStore(prefix string) *memoryStore {
+	return &memoryStore{ProtoCache: cache.NewProtoCache(nil, 0, prefix)}
+}
+
+func (s *memoryStore) Get(_ context.Context, key string, dst proto.Message) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, ok := s.data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, proto.Unmarshal(raw, dst)
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, msg proto.Message) {
+	raw, _ := proto.Marshal(msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = map[string][]byte{}
+	}
+	s.data[key] = raw
+}
+
+func (s *memoryStore) CachedFetch(ctx context.Context, key string, dst proto.Message, fetchFn func() (proto.Message, error)) (proto.Message, error) {
+	if ok, _ := s.Get(ctx, key, dst); ok {
+		return dst, nil
+	}
+	msg, err := fetchFn()
+	if err != nil {
+		return nil, err
+	}
+	s.Set(ctx, key, msg)
+	return msg, nil
+}
+
+func (s *memoryStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// countingPlaces answers every call and counts them by method.
+type countingPlaces struct {
+	mu    sync.Mutex
+	calls map[string]int
+	err   error
+}
+
+func (c *countingPlaces) count(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = map[string]int{}
+	}
+	c.calls[method]++
+	return c.err
+}
+
+func (c *countingPlaces) SearchText(_ context.Context, req *placespb.SearchTextRequest, _ []string) (*placespb.SearchTextResponse, error) {
+	if err := c.count("SearchText"); err != nil {
+		return nil, err
+	}
+	return &placespb.SearchTextResponse{Places: []*placespb.Place{{Id: req.TextQuery}}}, nil
+}
+
+func (c *countingPlaces) GetPlace(_ context.Context, req *placespb.GetPlaceRequest, _ []string) (*placespb.Place, error) {
+	if err := c.count("GetPlace"); err != nil {
+		return nil, err
+	}
+	return &placespb.Place{Name: req.Name, DisplayName: &localized_text.LocalizedText{Text: "Pho " + req.LanguageCode}}, nil
+}
+
+func (c *countingPlaces) AutocompletePlaces(_ context.Context, req *placespb.AutocompletePlacesRequest) (*placespb.AutocompletePlacesResponse, error) {
+	if err := c.count("AutocompletePlaces"); err != nil {
+		return nil, err
+	}
+	return &placespb.AutocompletePlacesResponse{}, nil
+}
+
+func newCachedPlaces(t *testing.T) (*services.CachedPlacesProvider, *countingPlaces, *prometheus.Registry) {
+	t.Helper()
+	provider, upstream, reg, _ := newCachedPlacesWithStores(t)
+	return provider, upstream, reg
+}
+
+func newCachedPlacesWithStores(t *testing.T) (*services.CachedPlacesProvider, *countingPlaces, *prometheus.Registry, services.PlacesCacheStores) {
+	t.Helper()
+	upstream := &countingPlaces{}
+	reg := prometheus.NewRegistry()
+	stores := services.PlacesCacheStores{
+		Details:      newMemoryStore("places:details:"),
+		Search:       newMemoryStore("places:search:"),
+		Autocomplete: newMemoryStore("places:autocomplete:"),
+	}
+	return services.NewCachedPlacesProvider(upstream, stores, utils.NewPlacesCacheMetrics(reg)), upstream, reg, stores
+}
+
+// cacheResults returns places_cache_requests_total by "method/result".
+func cacheResults(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			got[labels["method"]+"/"+labels["result"]] = m.GetCounter().GetValue()
+		}
+	}
+	return got
+}
+
+func TestCachedPlacesProvider_GetPlace(t *testing.T) {
+	provider, upstream, reg := newCachedPlaces(t)
+	ctx := context.Background()
+	fields := []string{"id", "display_name"}
+
+	for i := 0; i < 3; i++ {
+		place, err := provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc"}, fields)
+		if err != nil || place.GetDisplayName().GetText() != "Pho " {
+			t.Fatalf("GetPlace = %v, %v", place, err)
+		}
+		// Callers must not be able to corrupt the cached copy.
+		place.DisplayName.Text = "changed"
+	}
+	// Mask order does not matter; the mask itself, language and region do.
+	if _, err := provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc"}, []string{"display_name", "id"}); err != nil {
+		t.Fatal(err)
+	}
+	if upstream.calls["GetPlace"] != 1 {
+		t.Errorf("upstream called %d times, want once", upstream.calls["GetPlace"])
+	}
+	provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc"}, []string{"id"})
+	place, _ := provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc", LanguageCode: "pl"}, fields)
+	provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc", RegionCode: "PL"}, fields)
+	if upstream.calls["GetPlace"] != 4 || place.GetDisplayName().GetText() != "Pho pl" {
+		t.Errorf("upstream called %d times, want 4 (another mask, language and region each miss)", upstream.calls["GetPlace"])
+	}
+
+	got := cacheResults(t, reg)
+	if got["GetPlace/hit"] != 3 || got["GetPlace/miss"] != 4 {
+		t.Errorf("metrics = %v, want 3 hits and 4 misses", got)
+	}
+}
+
+func TestCachedPlacesProvider_ErrorsAreNotCached(t *testing.T) {
+	provider, upstream, _ := newCachedPlaces(t)
+	upstream.err = status.Error(codes.Unavailable, "down")
+	req := &placespb.SearchTextRequest{TextQuery: "pho"}
+	if _, err := provider.SearchText(context.Background(), req, nil); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the upstream error, got %v", err)
+	}
+	upstream.err = nil
+	resp, err := provider.SearchText(context.Background(), req, nil)
+	if err != nil || len(resp.Places) != 1 || upstream.calls["SearchText"] != 2 {
+		t.Errorf("SearchText = %v, %v after %d calls", resp, err, upstream.calls["SearchText"])
+	}
+}
+
+func TestCachedPlacesProvider_AutocompleteSessionsBypass(t *testing.T) {
+	provider, upstream, reg := newCachedPlaces(t)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		provider.AutocompletePlaces(ctx, &placespb.AutocompletePlacesRequest{Input: "pho", SessionToken: "tok"})
+		provider.AutocompletePlaces(ctx, &placespb.AutocompletePlacesRequest{Input: "pho"})
+	}
+	if upstream.calls["AutocompletePlaces"] != 3 {
+		t.Errorf("upstream called %d times, want 3", upstream.calls["AutocompletePlaces"])
+	}
+	got := cacheResults(t, reg)
+	if got["AutocompletePlaces/bypass"] != 2 || got["AutocompletePlaces/hit"] != 1 || got["AutocompletePlaces/miss"] != 1 {
+		t.Errorf("metrics = %v", got)
+	}
+}
+
+func TestCachedPlacesProvider_SessionDetailsReachGoogle(t *testing.T) {
+	provider, upstream, reg := newCachedPlaces(t)
+	sessions, _, sessionReg := newAutocompleteSessions(t)
+	svc := services.NewGooglePlacesAPIService(provider, sessions)
+	ctx := context.Background()
+	ala := http.Header{"X-User": {"ala"}}
+	details := func(token string) error {
+		req := connect.NewRequest(&v1.GetRestaurantDetailsRequest{Name: "places/abc", SessionToken: token})
+		req.Header().Set("X-User", "ala")
+		_, err := svc.GetRestaurantDetails(ctx, req)
+		return err
+	}
+
+	if err := details(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := details(sessions.Continue(ctx, ala, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := details(""); err != nil {
+		t.Fatal(err)
+	}
+	if upstream.calls["GetPlace"] != 2 {
+		t.Errorf("upstream called %d times, want the session's details call to skip the cache", upstream.calls["GetPlace"])
+	}
+	if got := cacheResults(t, reg); got["GetPlace/miss"] != 1 || got["GetPlace/bypass"] != 1 || got["GetPlace/hit"] != 1 {
+		t.Errorf("cache metrics = %v", got)
+	}
+
+	upstream.err = status.Error(codes.Unavailable, "down")
+	if err := details(sessions.Continue(ctx, ala, "")); err == nil {
+		t.Fatal("expected the upstream error")
+	}
+	if got := sessionOutcomes(t, sessionReg); got["completed"] != 1 || got["abandoned"] != 1 {
+		t.Errorf("session metrics = %v, want a failed details call not to complete its session", got)
+	}
+}
+
+func TestCachedPlacesProvider_KeysArePrefixedByRPC(t *testing.T) {
+	provider, _, _, stores := newCachedPlacesWithStores(t)
+	ctx := context.Background()
+	provider.GetPlace(ctx, &placespb.GetPlaceRequest{Name: "places/abc"}, nil)
+	provider.SearchText(ctx, &placespb.SearchTextRequest{TextQuery: "pho"}, nil)
+	provider.AutocompletePlaces(ctx, &placespb.AutocompletePlacesRequest{Input: "pho"})
+
+	for prefix, store := range map[string]services.PlacesCacheStore{
+		"places:details:GetPlace:":                stores.Details,
+		"places:search:SearchText:":               stores.Search,
+		"places:autocomplete:AutocompletePlaces:": stores.Autocomplete,
+	} {
+		keys := store.(*memoryStore).keys()
+		if len(keys) != 1 || !strings.HasPrefix(keys[0], prefix) {
+			t.Errorf("keys = %q, want one starting %q", keys, prefix)
+		}
+	}
+
+	valkeyStores := services.ValkeyPlacesCacheStores(nil)
+	if key := valkeyStores.Details.BuildKey("GetPlace", "x"); !strings.HasPrefix(key, "places:details:GetPlace:") {
+		t.Errorf("Valkey details key = %q", key)
+	}
+	if valkeyStores.Search.BuildKey("GetPlace", "x") == valkeyStores.Details.BuildKey("GetPlace", "x") {
+		t.Error("two RPC stores share keys")
+	}
+}
+
+func TestPlacesCacheKey(t *testing.T) {
+	key := func(req *placespb.SearchTextRequest, fields ...string) string {
+		k, err := services.PlacesCacheKey(req, fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	base := key(&placespb.SearchTextRequest{TextQuery: "pho"}, "places.id", "places.rating")
+	if key(&placespb.SearchTextRequest{TextQuery: "pho"}, "places.rating", "places.id") != base {
+		t.Error("mask order changed the key")
+	}
+	for name, other := range map[string]string{
+		"mask":     key(&placespb.SearchTextRequest{TextQuery: "pho"}, "places.id"),
+		"language": key(&placespb.SearchTextRequest{TextQuery: "pho", LanguageCode: "pl"}, "places.id", "places.rating"),
+		"region":   key(&placespb.SearchTextRequest{TextQuery: "pho", RegionCode: "pl"}, "places.id", "places.rating"),
+		"query":    key(&placespb.SearchTextRequest{TextQuery: "ramen"}, "places.id", "places.rating"),
+	} {
+		if other == base {
+			t.Errorf("a different %s gave the same key", name)
+		}
+	}
+}
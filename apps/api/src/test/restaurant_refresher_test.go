@@ -0,0 +1,263 @@
+package test
+
+import (
+	restaurantsv1 "api/src/generated/restaurants/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/maps/places/apiv1/placespb"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/genproto/googleapis/type/localized_text"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakePlaceFetcher answers GetPlace with place or err, remembering the request. Places
+// named in failing get their own error.
+type fakePlaceFetcher struct {
+	services.PlacesProvider
+	place   *placespb.Place
+	err     error
+	failing map[string]error
+	name    string
+	fields  []string
+}
+
+func (f *fakePlaceFetcher) GetPlace(_ context.Context, req *placespb.GetPlaceRequest, fields []string) (*placespb.Place, error) {
+	f.name, f.fields = req.Name, fields
+	if err, ok := f.failing[req.Name]; ok {
+		return nil, err
+	}
+	return f.place, f.err
+}
+
+// memoryRefreshes is a RestaurantRefreshStore over a slice, kept in refresh order.
+type memoryRefreshes struct {
+	restaurants []*models.Restaurant
+	saved       map[string]map[string]any
+	requests    map[string]int
+}
+
+func (s *memoryRefreshes) Requests(_ context.Context, now time.Time) (int, error) {
+	return s.requests[now.UTC().Format(time.DateOnly)], nil
+}
+
+func (s *memoryRefreshes) CountRequest(_ context.Context, now time.Time) error {
+	if s.requests == nil {
+		s.requests = map[string]int{}
+	}
+	s.requests[now.UTC().Format(time.DateOnly)]++
+	return nil
+}
+
+func (s *memoryRefreshes) Due(_ context.Context, before time.Time, limit int) ([]models.Restaurant, error) {
+	var due []models.Restaurant
+	for _, r := range s.restaurants {
+		if len(due) < limit && (r.RefreshedAt == nil || r.RefreshedAt.Before(before)) {
+			due = append(due, *r)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryRefreshes) Save(_ context.Context, restaurant *models.Restaurant, updates map[string]any, now time.Time) error {
+	for _, r := range s.restaurants {
+		if r.GoogleID == restaurant.GoogleID {
+			r.RefreshedAt = &now
+		}
+	}
+	if s.saved == nil {
+		s.saved = map[string]map[string]any{}
+	}
+	s.saved[restaurant.GoogleID] = updates
+	return nil
+}
+
+func TestRestaurantRefresher_FetchUpdates(t *testing.T) {
+	fetcher := &fakePlaceFetcher{place: &placespb.Place{
+		Id:               "abc",
+		DisplayName:      &localized_text.LocalizedText{Text: "Pho Hanoi Bistro"},
+		FormattedAddress: "Nowy Świat 1, Warsaw",
+		Photos:           []*placespb.Photo{{Name: "places/abc/photos/new"}},
+		BusinessStatus:   placespb.Place_CLOSED_PERMANENTLY,
+		Location:         &latlng.LatLng{Latitude: 52.23, Longitude: 21.01},
+	}}
+	refresher := services.NewRestaurantRefresher(nil, fetcher, services.RestaurantRefreshConfig{})
+	restaurant := &models.Restaurant{
+		GoogleID:       "places/abc",
+		Name:           "Pho Hanoi",
+		Address:        "Nowy Świat 1, Warsaw",
+		PhotoReference: "places/abc/photos/old",
+	}
+
+	updates, err := refresher.FetchUpdates(context.Background(), restaurant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.name != "places/abc" {
+		t.Errorf("fetched %q", fetcher.name)
+	}
+	for _, f := range fetcher.fields {
+		if f == "reviews" || f == "rating" || f == "opening_hours" {
+			t.Errorf("field mask %v asks for %q", fetcher.fields, f)
+		}
+	}
+	want := map[string]any{
+		"name":            "Pho Hanoi Bistro",
+		"photo_reference": "places/abc/photos/new",
+		"business_status": models.BusinessStatusClosedPermanently,
+		"latitude":        52.23,
+		"longitude":       21.01,
+		"geohash":         models.Geohash(52.23, 21.01, models.GeohashPrecision),
+	}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("updates = %v\nwant %v", updates, want)
+	}
+
+	restaurant.BusinessStatus = models.BusinessStatusClosedPermanently
+	if !restaurant.Closed() || restaurant.ToProto().GetStatus() != restaurantsv1.RestaurantStatus_RESTAURANT_STATUS_CLOSED_PERMANENTLY {
+		t.Errorf("closed restaurant: Closed() = %v, status %v", restaurant.Closed(), restaurant.ToProto().GetStatus())
+	}
+	review := models.Review{Restaurant: *restaurant}
+	if !review.ToProto().GetRestaurantClosed() {
+		t.Error("review of a permanently closed restaurant is not flagged")
+	}
+}
+
+func TestRestaurantRefresher_FetchUpdates_Errors(t *testing.T) {
+	restaurant := &models.Restaurant{GoogleID: "places/gone", Name: "Gone"}
+
+	fetcher := &fakePlaceFetcher{err: status.Error(codes.NotFound, "not found")}
+	refresher := services.NewRestaurantRefresher(nil, fetcher, services.RestaurantRefreshConfig{})
+	updates, err := refresher.FetchUpdates(context.Background(), restaurant)
+	if err != nil || len(updates) != 0 {
+		t.Errorf("a place Google dropped: updates %v, err %v; want none", updates, err)
+	}
+
+	fetcher.err = status.Error(codes.ResourceExhausted, "quota")
+	if _, err := refresher.FetchUpdates(context.Background(), restaurant); status.Code(errors.Unwrap(err)) != codes.ResourceExhausted {
+		t.Errorf("expected the quota error, got %v", err)
+	}
+}
+
+func TestRestaurantRefresher_RefreshDue(t *testing.T) {
+	now := time.Date(2026, 6, 1, 23, 30, 0, 0, time.UTC)
+	store := &memoryRefreshes{}
+	for _, id := range []string{"places/broken", "places/gone", "places/a", "places/b", "places/c"} {
+		store.restaurants = append(store.restaurants, &models.Restaurant{GoogleID: id, Name: "Old"})
+	}
+	// Looked up for a user today; that does not count against the budget.
+	resolved := now.Add(-time.Minute)
+	store.restaurants = append(store.restaurants, &models.Restaurant{GoogleID: "places/fresh", Name: "Fresh", RefreshedAt: &resolved})
+	fetcher := &fakePlaceFetcher{
+		place: &placespb.Place{DisplayName: &localized_text.LocalizedText{Text: "New"}},
+		failing: map[string]error{
+			"places/broken": status.Error(codes.InvalidArgument, "bad name"),
+			"places/gone":   status.Error(codes.NotFound, "not found"),
+		},
+	}
+	refresher := &services.RestaurantRefresher{
+		Store:  store,
+		Places: fetcher,
+		Config: services.RestaurantRefreshConfig{DailyBudget: 4, MaxAge: time.Hour},
+	}
+
+	n, err := refresher.RefreshDue(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("refreshed %d, want all but the failing place of the four due", n)
+	}
+	if got := store.requests["2026-06-01"]; got != 4 {
+		t.Errorf("counted %d requests, want 4", got)
+	}
+	if updates, ok := store.saved["places/broken"]; !ok || len(updates) != 0 {
+		t.Errorf("the failing place was not recorded as attempted: %v", store.saved)
+	}
+	if store.saved["places/b"]["name"] != "New" || store.saved["places/c"] != nil {
+		t.Errorf("saved = %v, want the budget spent on the first four", store.saved)
+	}
+	if n, _ := refresher.RefreshDue(context.Background(), now); n != 0 {
+		t.Errorf("a second run refreshed %d past the budget", n)
+	}
+
+	// Running out of quota ends the run cleanly, so the job is not retried, without
+	// recording the place.
+	refresher.Config.DailyBudget = 10
+	fetcher.err = status.Error(codes.ResourceExhausted, "quota")
+	if n, err := refresher.RefreshDue(context.Background(), now); n != 0 || err != nil {
+		t.Errorf("RefreshDue out of quota = %d, %v; want 0, nil", n, err)
+	}
+	if _, ok := store.saved["places/c"]; ok {
+		t.Error("a place was recorded although the quota ran out")
+	}
+}
+
+func TestRefreshAllowance(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2026, 6, 1, hour, 30, 0, 0, time.UTC) }
+	cases := []struct {
+		budget, used, hour, want int
+	}{
+		{240, 0, 0, 10},
+		{240, 10, 0, 0},
+		{240, 10, 5, 50},
+		{240, 0, 23, 240},
+		{240, 300, 23, 0},
+		{5, 0, 0, 1},
+	}
+	for _, c := range cases {
+		if got := services.RefreshAllowance(c.budget, c.used, at(c.hour)); got != c.want {
+			t.Errorf("RefreshAllowance(%d, %d, %02d:30) = %d, want %d", c.budget, c.used, c.hour, got, c.want)
+		}
+	}
+}
+
+func TestRestaurantRefreshConfigFromEnv(t *testing.T) {
+	t.Setenv("PLACES_REFRESH_DAILY_BUDGET", "")
+	t.Setenv("PLACES_REFRESH_MAX_AGE", "")
+	cfg, err := services.RestaurantRefreshConfigFromEnv()
+	if err != nil || cfg.DailyBudget != 200 || cfg.MaxAge != 720*time.Hour {
+		t.Errorf("defaults = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("PLACES_REFRESH_DAILY_BUDGET", "0")
+	t.Setenv("PLACES_REFRESH_MAX_AGE", "168h")
+	cfg, err = services.RestaurantRefreshConfigFromEnv()
+	if err != nil || cfg.DailyBudget != 0 || cfg.MaxAge != 168*time.Hour {
+		t.Errorf("overrides = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("PLACES_REFRESH_DAILY_BUDGET", "lots")
+	if _, err := services.RestaurantRefreshConfigFromEnv(); err == nil {
+		t.Error("expected an error for a non-numeric budget")
+	}
+}
+
+func TestRestaurantRefreshStore_CountsRequestsPerDay(t *testing.T) {
+	db := openTestDB(t)
+	store := services.NewRestaurantRefreshStore(db)
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 23, 30, 0, 0, time.UTC)
+	// A restaurant a user's lookup created today is not a refresh request.
+	if err := db.Create(&models.Restaurant{GoogleID: "places/fresh", Name: "Fresh", Address: "1 Main St", RefreshedAt: &now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := store.CountRequest(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := store.Requests(ctx, now.Add(-time.Hour)); err != nil || n != 2 {
+		t.Fatalf("Requests = %d, %v; want 2, nil", n, err)
+	}
+	if n, err := store.Requests(ctx, now.Add(time.Hour)); err != nil || n != 0 {
+		t.Fatalf("Requests the next day = %d, %v; want 0, nil", n, err)
+	}
+}
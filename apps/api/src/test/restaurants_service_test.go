@@ -0,0 +1,162 @@
+package test
+
+import (
+	restaurantsv1 "api/src/generated/restaurants/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+)
+
+func TestRestaurantsService_ListNearby_NoSession(t *testing.T) {
+	svc := &services.RestaurantsService{}
+	lat, lng := 52.23, 21.01
+	_, err := svc.ListNearby(context.Background(), connect.NewRequest(&restaurantsv1.ListNearbyRequest{Latitude: &lat, Longitude: &lng}))
+	if code := connect.CodeOf(err); code != connect.CodeUnauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", code)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	if got := models.Geohash(57.64911, 10.40744, 11); got != "u4pruydqqvj" {
+		t.Errorf("Geohash = %q, want u4pruydqqvj", got)
+	}
+	var r models.Restaurant
+	r.SetLocation(52.2297, 21.0122)
+	if !r.HasLocation() || len(r.Geohash) != models.GeohashPrecision || !strings.HasPrefix(r.Geohash, "u3qcn") {
+		t.Errorf("SetLocation: geohash %q", r.Geohash)
+	}
+	if p := r.ToProto(); p.GetLatitude() != 52.2297 || p.GetLongitude() != 21.0122 {
+		t.Errorf("ToProto coordinates = %v, %v", p.GetLatitude(), p.GetLongitude())
+	}
+}
+
+func TestDistanceMeters(t *testing.T) {
+	// London to Paris is about 343.5 km.
+	if d := models.DistanceMeters(51.5074, -0.1278, 48.8566, 2.3522); math.Abs(d-343_500) > 1000 {
+		t.Errorf("London–Paris = %.0f m", d)
+	}
+	if d := models.DistanceMeters(10, 20, 10, 20); d != 0 {
+		t.Errorf("same point = %v m", d)
+	}
+}
+
+func TestBoundingBoxAround_CrossesAntimeridian(t *testing.T) {
+	box := models.BoundingBoxAround(0, 179.99, 5000)
+	if box.West <= box.East {
+		t.Fatalf("expected a box crossing the antimeridian, got %+v", box)
+	}
+	if !box.Contains(0, -179.99) || !box.Contains(0, 179.99) || box.Contains(0, 0) {
+		t.Errorf("Contains is wrong for %+v", box)
+	}
+	if parts := box.Split(); len(parts) != 2 {
+		t.Errorf("Split = %+v, want two boxes", parts)
+	}
+	if polar := models.BoundingBoxAround(89.99, 0, 5000); polar.West != -180 || polar.East != 180 {
+		t.Errorf("a box over the pole should span every longitude, got %+v", polar)
+	}
+}
+
+func TestGeohashCover_CoversBox(t *testing.T) {
+	for _, box := range []models.BoundingBox{
+		models.BoundingBoxAround(52.2297, 21.0122, 300),
+		models.BoundingBoxAround(52.2297, 21.0122, 20000),
+		models.BoundingBoxAround(-33.86, 179.99, 5000),
+		{South: 40.70, West: -74.02, North: 40.80, East: -73.93},
+	} {
+		cover := models.GeohashCover(box)
+		if len(cover) == 0 {
+			t.Fatalf("no cover for %+v", box)
+		}
+		for _, part := range box.Split() {
+			for i := 0; i <= 10; i++ {
+				for j := 0; j <= 10; j++ {
+					lat := part.South + (part.North-part.South)*float64(i)/10
+					lng := part.West + (part.East-part.West)*float64(j)/10
+					hash := models.Geohash(lat, lng, models.GeohashPrecision)
+					covered := false
+					for _, prefix := range cover {
+						covered = covered || strings.HasPrefix(hash, prefix)
+					}
+					if !covered {
+						t.Errorf("%+v: point %v,%v (%s) not covered by %v", box, lat, lng, hash, cover)
+					}
+				}
+			}
+		}
+	}
+	if cover := models.GeohashCover(models.BoundingBox{South: -80, West: -170, North: 80, East: 170}); cover != nil {
+		t.Errorf("expected no cover for most of the world, got %d prefixes", len(cover))
+	}
+}
+
+func TestNearbyAreaFor_Validation(t *testing.T) {
+	lat, lng, bad := 52.23, 21.01, 123.0
+	cases := map[string]*restaurantsv1.ListNearbyRequest{
+		"no origin or bounds": {},
+		"latitude only":       {Latitude: &lat},
+		"latitude too large":  {Latitude: &bad, Longitude: &lng},
+		"south above north":   {Bounds: &restaurantsv1.BoundingBox{South: 53, West: 20, North: 52, East: 22}},
+		"longitude too large": {Bounds: &restaurantsv1.BoundingBox{South: 52, West: 20, North: 53, East: 190}},
+	}
+	for name, req := range cases {
+		if _, err := services.NearbyAreaFor(req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%s: expected InvalidArgument, got %v", name, err)
+		}
+	}
+
+	area, err := services.NearbyAreaFor(&restaurantsv1.ListNearbyRequest{Latitude: &lat, Longitude: &lng, RadiusMeters: 1e9})
+	if err != nil || area.Radius != 50000 {
+		t.Errorf("radius = %v (%v), want it capped at 50000", area.Radius, err)
+	}
+	area, err = services.NearbyAreaFor(&restaurantsv1.ListNearbyRequest{Bounds: &restaurantsv1.BoundingBox{South: 52, West: 20, North: 53, East: 22}})
+	if err != nil || area.Radius != 0 || area.OriginLat != 52.5 || area.OriginLng != 21 {
+		t.Errorf("bounds area = %+v (%v), want origin at the centre and no radius", area, err)
+	}
+}
+
+func TestNearbyArea_Places(t *testing.T) {
+	at := func(name string, lat, lng float64) models.Restaurant {
+		r := models.Restaurant{Name: name}
+		r.SetLocation(lat, lng)
+		return r
+	}
+	restaurants := []models.Restaurant{
+		at("far", 52.30, 21.01),      // ~7.8 km north
+		at("near", 52.231, 21.011),   // ~130 m
+		at("nearer", 52.2301, 21.01), // ~10 m
+		{Name: "no coordinates"},
+	}
+	lat, lng := 52.23, 21.01
+
+	area, err := services.NearbyAreaFor(&restaurantsv1.ListNearbyRequest{Latitude: &lat, Longitude: &lng, RadiusMeters: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range area.Places(restaurants) {
+		got = append(got, p.Restaurant.Name)
+	}
+	if strings.Join(got, ",") != "nearer,near" {
+		t.Errorf("within 1 km = %v, want [nearer near]", got)
+	}
+
+	area, err = services.NearbyAreaFor(&restaurantsv1.ListNearbyRequest{
+		Latitude: &lat, Longitude: &lng,
+		Bounds: &restaurantsv1.BoundingBox{South: 52.2305, West: 21, North: 52.4, East: 21.02},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	places := area.Places(restaurants)
+	if len(places) != 2 || places[0].Restaurant.Name != "near" || places[1].Restaurant.Name != "far" {
+		t.Fatalf("in bounds = %v, want near then far", places)
+	}
+	if d := places[1].DistanceMeters; math.Abs(d-7784) > 100 {
+		t.Errorf("distance to far = %.0f m", d)
+	}
+}
@@ -2,6 +2,8 @@ package test
 
 import (
 	reviewsv1 "api/src/generated/reviews/v1"
+	tagsv1 "api/src/generated/tags/v1"
+	"api/src/internal/models"
 	"api/src/services"
 	"context"
 	"errors"
@@ -54,3 +56,197 @@ func TestReviewsService_ListReviews_NilDB(t *testing.T) {
 		t.Fatal("expected error from nil DB, got nil")
 	}
 }
+
+func TestReviewsService_ListReviews_InvalidScoreFilters(t *testing.T) {
+	svc := &services.ReviewsService{}
+	for name, msg := range map[string]*reviewsv1.ListReviewsRequest{
+		"unspecified dimension": {ScoreFilters: []*reviewsv1.ScoreFilter{{Min: 3}}},
+		"min above max": {ScoreFilters: []*reviewsv1.ScoreFilter{
+			{Dimension: reviewsv1.ScoreDimension_SCORE_DIMENSION_FOOD, Min: 4, Max: 2},
+		}},
+		"unknown sort dimension": {SortDimension: reviewsv1.ScoreDimension(42)},
+	} {
+		_, err := svc.ListReviews(context.Background(), connect.NewRequest(msg))
+		if connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%s: expected CodeInvalidArgument, got %v", name, err)
+		}
+	}
+}
+
+func TestReview_Scores(t *testing.T) {
+	var r models.Review
+	r.SetScores(&reviewsv1.ReviewScores{Food: 5, Service: 3})
+	if r.FoodScore == nil || *r.FoodScore != 5 || r.AmbienceScore != nil {
+		t.Fatalf("SetScores left food=%v ambience=%v", r.FoodScore, r.AmbienceScore)
+	}
+	if got := r.ScoresProto(); got.Food != 5 || got.Service != 3 || got.Drinks != 0 {
+		t.Errorf("ScoresProto = %v", got)
+	}
+
+	// (0.4*5 + 0.2*3) / 0.6 = 4.33
+	if rating, ok := r.RatingFromScores(); !ok || rating != 4.3 {
+		t.Errorf("RatingFromScores = %v, %v; want 4.3, true", rating, ok)
+	}
+	r.SetScores(nil)
+	if _, ok := r.RatingFromScores(); ok {
+		t.Error("expected no rating without scores")
+	}
+}
+
+func TestScoreAverages(t *testing.T) {
+	four, two, five := 4.0, 2.0, 5.0
+	reviews := []models.Review{
+		{FoodScore: &four, DrinksScore: &five},
+		{FoodScore: &two},
+		{},
+	}
+	got := services.ScoreAverages(reviews)
+	if len(got) != 2 {
+		t.Fatalf("got %d averages, want 2: %v", len(got), got)
+	}
+	if got[0].Dimension != reviewsv1.ScoreDimension_SCORE_DIMENSION_FOOD || got[0].Average != 3 || got[0].Count != 2 {
+		t.Errorf("food average = %v", got[0])
+	}
+	if got[1].Dimension != reviewsv1.ScoreDimension_SCORE_DIMENSION_DRINKS || got[1].Average != 5 || got[1].Count != 1 {
+		t.Errorf("drinks average = %v", got[1])
+	}
+}
+
+func TestDishNames(t *testing.T) {
+	if got := models.CleanDishName("  Pierogi   ruskie \t"); got != "Pierogi ruskie" {
+		t.Errorf("CleanDishName = %q", got)
+	}
+	if got := models.NormalizeDishName("  Pierogi   RUSKIE "); got != "pierogi ruskie" {
+		t.Errorf("NormalizeDishName = %q", got)
+	}
+}
+
+func TestReviewToProto_DishesInListedOrder(t *testing.T) {
+	review := models.Review{Dishes: []models.ReviewDish{
+		{DishID: "b", Dish: models.Dish{Name: "Żurek"}, Position: 1},
+		{DishID: "a", Dish: models.Dish{Name: "Pierogi"}, Position: 0, Rating: 5},
+	}}
+	got := review.ToProto().Dishes
+	if len(got) != 2 || got[0].Name != "Pierogi" || got[0].Rating != 5 || got[1].Name != "Żurek" {
+		t.Errorf("dishes = %v", got)
+	}
+}
+
+func TestSummarizeDishes(t *testing.T) {
+	yes, no := true, false
+	pierogi := models.Dish{UUIDv7: models.UUIDv7{ID: "d1"}, Name: "Pierogi", NormalizedName: "pierogi"}
+	zurek := models.Dish{UUIDv7: models.UUIDv7{ID: "d2"}, Name: "Żurek", NormalizedName: "żurek"}
+	reviews := []models.Review{
+		{UserID: "u2", User: models.User{Name: "Bea"}, Dishes: []models.ReviewDish{
+			{DishID: "d2", Dish: zurek, Rating: 3},
+			{DishID: "d1", Dish: pierogi, Rating: 4, Price: 30, WouldOrderAgain: &yes},
+		}},
+		{UserID: "u1", User: models.User{Name: "Ada"}, Dishes: []models.ReviewDish{
+			{DishID: "d1", Dish: pierogi, Price: 25, WouldOrderAgain: &no},
+		}},
+	}
+
+	got := services.SummarizeDishes(reviews, nil)
+	if len(got) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(got))
+	}
+	p := got[0]
+	if p.Name != "Pierogi" || p.OrderCount != 2 || p.AverageRating != 4 || p.AveragePrice != 28 || p.WouldOrderAgainCount != 1 {
+		t.Errorf("pierogi summary = %v", p)
+	}
+	if len(p.OrderedBy) != 2 || p.OrderedBy[0] != "Bea" || p.OrderedBy[1] != "Ada" {
+		t.Errorf("ordered_by = %v", p.OrderedBy)
+	}
+
+	only := services.SummarizeDishes(reviews, func(d models.Dish) bool { return d.NormalizedName == "żurek" })
+	if len(only) != 1 || only[0].DishId != "d2" {
+		t.Errorf("filtered summaries = %v", only)
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	for in, want := range map[string]string{
+		"pierogi":   "%pierogi%",
+		"100% mąki": `%100\% mąki%`,
+		"a_b":       `%a\_b%`,
+		`c:\d`:      `%c:\\d%`,
+	} {
+		if got := services.ContainsPattern(in); got != want {
+			t.Errorf("ContainsPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReviewsService_DishRPCs_NoSession(t *testing.T) {
+	svc := &services.ReviewsService{}
+	ctx := context.Background()
+	if _, err := svc.ListRestaurantDishes(ctx, connect.NewRequest(&reviewsv1.ListRestaurantDishesRequest{GooglePlacesId: "places/abc"})); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("ListRestaurantDishes: expected Unauthenticated, got %v", err)
+	}
+	if _, err := svc.SearchDishes(ctx, connect.NewRequest(&reviewsv1.SearchDishesRequest{Query: "pierogi"})); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("SearchDishes: expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestReviewsService_DeleteReview_WithChildRows(t *testing.T) {
+	db := openTestDB(t)
+	kv := openTestValkey(t)
+	seed := seedSharedReview(t, db)
+	svc := services.NewReviewsService(db, kv, nil)
+
+	// Someone else's review is not found, and keeps its rows.
+	req := connect.NewRequest(&reviewsv1.DeleteReviewRequest{Id: seed.ReviewID})
+	req.Header().Set("Cookie", signIn(t, kv, seed.FriendID))
+	if _, err := svc.DeleteReview(context.Background(), req); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("deleting another user's review gave %v, want NotFound", err)
+	}
+	if n := countRows(t, db, "review_tags", "review_id = ?", seed.ReviewID); n != 1 {
+		t.Fatalf("a refused delete removed tags: %d left", n)
+	}
+
+	req = connect.NewRequest(&reviewsv1.DeleteReviewRequest{Id: seed.ReviewID})
+	req.Header().Set("Cookie", signIn(t, kv, seed.OwnerID))
+	if _, err := svc.DeleteReview(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"review_circles", "review_tags", "review_personal_tags", "review_dishes"} {
+		if n := countRows(t, db, table, "review_id = ?", seed.ReviewID); n != 0 {
+			t.Errorf("%d %s rows left", n, table)
+		}
+	}
+	if n := countRows(t, db, "reviews", "id = ?", seed.ReviewID); n != 0 {
+		t.Error("the review is still there")
+	}
+}
+
+func TestReviewsService_UpdateReview_KeepsPersonalTagsUnlessSent(t *testing.T) {
+	db := openTestDB(t)
+	kv := openTestValkey(t)
+	seed := seedSharedReview(t, db)
+	svc := services.NewReviewsService(db, kv, nil)
+	cookie := signIn(t, kv, seed.OwnerID)
+
+	update := func(personal *tagsv1.PersonalTagSlugs) *reviewsv1.ReviewProto {
+		t.Helper()
+		req := connect.NewRequest(&reviewsv1.UpdateReviewRequest{Id: seed.ReviewID, Comment: "Still great", Rating: 4, PersonalTags: personal})
+		req.Header().Set("Cookie", cookie)
+		res, err := svc.UpdateReview(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Msg.Review
+	}
+
+	if got := update(nil); len(got.PersonalTags) != 1 || got.PersonalTags[0].Slug != "date-night" {
+		t.Fatalf("expected an update without personal tags to keep them, got %v", got.PersonalTags)
+	}
+	if n := countRows(t, db, "review_personal_tags", "review_id = ?", seed.ReviewID); n != 1 {
+		t.Fatalf("expected the personal tag to stay stored, found %d", n)
+	}
+	if got := update(&tagsv1.PersonalTagSlugs{}); len(got.PersonalTags) != 0 {
+		t.Fatalf("expected an empty list to clear personal tags, got %v", got.PersonalTags)
+	}
+	if n := countRows(t, db, "review_personal_tags", "review_id = ?", seed.ReviewID); n != 0 {
+		t.Fatalf("expected no personal tags left, found %d", n)
+	}
+}
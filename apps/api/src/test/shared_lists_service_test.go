@@ -0,0 +1,166 @@
+package test
+
+import (
+	reviewsv1 "api/src/generated/reviews/v1"
+	sharedlistsv1 "api/src/generated/shared_lists/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+func TestSharedListsService_CreateSharedList_NoSession(t *testing.T) {
+	svc := &services.SharedListsService{}
+	req := connect.NewRequest(&sharedlistsv1.CreateSharedListRequest{Title: "Best ramen", ReviewIds: []string{"r1"}})
+	_, err := svc.CreateSharedList(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestSharedListsService_GetSharedList_EmptySlug(t *testing.T) {
+	svc := &services.SharedListsService{}
+	req := connect.NewRequest(&sharedlistsv1.GetSharedListRequest{})
+	_, err := svc.GetSharedList(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for empty slug, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeInvalidArgument {
+		t.Fatalf("expected CodeInvalidArgument, got %v", connectErr.Code())
+	}
+}
+
+func TestSharedListsService_GetSharedList_NoSessionNeeded(t *testing.T) {
+	svc := &services.SharedListsService{}
+	req := connect.NewRequest(&sharedlistsv1.GetSharedListRequest{Slug: "abc"})
+	_, err := svc.GetSharedList(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error from nil DB, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() == connect.CodeUnauthenticated {
+		t.Fatal("GetSharedList must not require a session")
+	}
+}
+
+func TestSharedList_IsAccessible(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	if !(&models.SharedList{}).IsAccessible(now) {
+		t.Fatal("expected list without expiry to be accessible")
+	}
+	if !(&models.SharedList{ExpiresAt: &future}).IsAccessible(now) {
+		t.Fatal("expected list expiring in the future to be accessible")
+	}
+	if (&models.SharedList{ExpiresAt: &past}).IsAccessible(now) {
+		t.Fatal("expected expired list to be inaccessible")
+	}
+	if (&models.SharedList{RevokedAt: &past}).IsAccessible(now) {
+		t.Fatal("expected revoked list to be inaccessible")
+	}
+}
+
+func TestSharedListMode_UnspecifiedDefaultsToSnapshot(t *testing.T) {
+	if got := models.SharedListModeFromProto(sharedlistsv1.SharedListMode_SHARED_LIST_MODE_UNSPECIFIED); got != models.SharedListModeSnapshot {
+		t.Fatalf("expected unspecified mode to map to %q, got %q", models.SharedListModeSnapshot, got)
+	}
+	if got := models.SharedListModeFromProto(sharedlistsv1.SharedListMode_SHARED_LIST_MODE_LIVE); got != models.SharedListModeLive {
+		t.Fatalf("expected LIVE to map to %q, got %q", models.SharedListModeLive, got)
+	}
+}
+
+func TestSharedListFilter_RoundTrip(t *testing.T) {
+	in := &sharedlistsv1.SharedListFilter{
+		TagSlugs:      []string{"ramen"},
+		TagFilterMode: reviewsv1.TagFilterMode_TAG_FILTER_MODE_AND,
+		MinRating:     4,
+		City:          "Kraków",
+		SortBy:        reviewsv1.ReviewSortBy_REVIEW_SORT_BY_RATING_DESC,
+	}
+	out := models.SharedListFilterFromProto(in).ToProto()
+	if out.City != in.City || out.MinRating != in.MinRating || out.SortBy != in.SortBy ||
+		out.TagFilterMode != in.TagFilterMode || len(out.TagSlugs) != 1 || out.TagSlugs[0] != "ramen" {
+		t.Fatalf("filter did not round-trip: got %+v", out)
+	}
+	if models.SharedListFilterFromProto(nil) != nil {
+		t.Fatal("expected nil filter to stay nil")
+	}
+}
+
+func TestReview_ToPublicProto_StripsAudience(t *testing.T) {
+	r := models.Review{UserID: "u1", Visibility: models.VisibilityFriends, Circles: []models.Circle{{UUIDv7: models.UUIDv7{ID: "c1"}}}}
+	p := r.ToPublicProto()
+	if p.UserId != "" {
+		t.Fatalf("expected user ID to be stripped, got %q", p.UserId)
+	}
+	if len(p.CircleIds) != 0 {
+		t.Fatalf("expected circle IDs to be stripped, got %v", p.CircleIds)
+	}
+}
+
+func TestSharedListsService_SnapshotKeepsPublishedContent(t *testing.T) {
+	db := openTestDB(t)
+	kv := openTestValkey(t)
+	seed := seedSharedReview(t, db)
+	svc := services.NewSharedListsService(db, kv)
+	ctx := context.Background()
+	if err := db.Model(&models.Review{}).Where("id = ?", seed.ReviewID).
+		Updates(map[string]any{"visibility": models.VisibilityFriends, "comment": "Best broth in town"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	req := connect.NewRequest(&sharedlistsv1.CreateSharedListRequest{Title: "Pho", ReviewIds: []string{seed.ReviewID}})
+	req.Header().Set("Cookie", signIn(t, kv, seed.OwnerID))
+	created, err := svc.CreateSharedList(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := func() []*reviewsv1.ReviewProto {
+		t.Helper()
+		res, err := svc.GetSharedList(ctx, connect.NewRequest(&sharedlistsv1.GetSharedListRequest{Slug: created.Msg.List.Slug}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Msg.Reviews
+	}
+
+	if err := db.Model(&models.Review{}).Where("id = ?", seed.ReviewID).Update("comment", "Gone downhill").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := view(); len(got) != 1 || got[0].Comment != "Best broth in town" {
+		t.Fatalf("expected the comment as published, got %v", got)
+	}
+
+	if err := db.Model(&models.Review{}).Where("id = ?", seed.ReviewID).Update("visibility", models.VisibilityPrivate).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := view(); len(got) != 0 {
+		t.Fatalf("expected a review made private to drop out, got %v", got)
+	}
+}
+
+func TestFilterSnapshotReviews(t *testing.T) {
+	reviews := []*reviewsv1.ReviewProto{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+	got := services.FilterSnapshotReviews(reviews, []string{"c", "a"})
+	if len(got) != 2 || got[0].Id != "a" || got[1].Id != "c" {
+		t.Fatalf("expected a and c in published order, got %v", got)
+	}
+}
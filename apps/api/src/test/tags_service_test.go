@@ -1,11 +1,14 @@
 package test
 
 import (
+	"api/src/internal/models"
 	"api/src/internal/utils"
 	"api/src/services"
 	tagsv1 "api/src/generated/tags/v1"
 	"context"
+	"reflect"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 )
@@ -43,3 +46,205 @@ func TestRequiredTags_Slugs_Unique(t *testing.T) {
 		}
 	}
 }
+
+func TestTagRPCs_NoSession(t *testing.T) {
+	svc := &services.TagsService{}
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"CreateTag": func() error {
+			_, err := svc.CreateTag(ctx, connect.NewRequest(&tagsv1.CreateTagRequest{Slug: "ramen", Label: "Ramen", Category: "Cuisine"}))
+			return err
+		},
+		"UpdateTag": func() error {
+			_, err := svc.UpdateTag(ctx, connect.NewRequest(&tagsv1.UpdateTagRequest{Slug: "thai"}))
+			return err
+		},
+		"DeprecateTag": func() error {
+			_, err := svc.DeprecateTag(ctx, connect.NewRequest(&tagsv1.DeprecateTagRequest{Slug: "thai"}))
+			return err
+		},
+		"MergeTags": func() error {
+			_, err := svc.MergeTags(ctx, connect.NewRequest(&tagsv1.MergeTagsRequest{SourceSlugs: []string{"thai"}, TargetSlug: "asian"}))
+			return err
+		},
+		"ListPopularPersonalTags": func() error {
+			_, err := svc.ListPopularPersonalTags(ctx, connect.NewRequest(&tagsv1.ListPopularPersonalTagsRequest{}))
+			return err
+		},
+		"PromotePersonalTag": func() error {
+			_, err := svc.PromotePersonalTag(ctx, connect.NewRequest(&tagsv1.PromotePersonalTagRequest{Slug: "near-office", Label: "Near office", Category: "Occasion"}))
+			return err
+		},
+		"ListPersonalTags": func() error {
+			_, err := svc.ListPersonalTags(ctx, connect.NewRequest(&tagsv1.ListPersonalTagsRequest{}))
+			return err
+		},
+		"CreatePersonalTag": func() error {
+			_, err := svc.CreatePersonalTag(ctx, connect.NewRequest(&tagsv1.CreatePersonalTagRequest{Label: "Near office"}))
+			return err
+		},
+		"DeletePersonalTag": func() error {
+			_, err := svc.DeletePersonalTag(ctx, connect.NewRequest(&tagsv1.DeletePersonalTagRequest{Slug: "near-office"}))
+			return err
+		},
+	}
+	for name, call := range calls {
+		if code := connect.CodeOf(call()); code != connect.CodeUnauthenticated {
+			t.Errorf("%s: expected Unauthenticated, got %v", name, code)
+		}
+	}
+}
+
+func TestGetTagStats_NilDB_ReturnsError(t *testing.T) {
+	svc := &services.TagsService{}
+	_, err := svc.GetTagStats(context.Background(), connect.NewRequest(&tagsv1.GetTagStatsRequest{}))
+	if connect.CodeOf(err) != connect.CodeInternal {
+		t.Errorf("expected Internal, got %v", err)
+	}
+}
+
+func TestSortTagStats(t *testing.T) {
+	stats := []*tagsv1.TagStat{
+		{Slug: "thai", ReviewCount: 1},
+		{Slug: "casual", ReviewCount: 2, WishlistCount: 1},
+		{Slug: "brunch", WishlistCount: 1},
+		{Slug: "italian", ReviewCount: 3},
+	}
+	services.SortTagStats(stats)
+	var got []string
+	for _, st := range stats {
+		got = append(got, st.Slug)
+	}
+	if want := []string{"casual", "italian", "brunch", "thai"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTaggedToProto_SortsSlugs(t *testing.T) {
+	tags := []models.Tag{{Slug: "thai"}, {Slug: "casual"}, {Slug: "date-night"}}
+	want := []string{"casual", "date-night", "thai"}
+
+	review := models.Review{Tags: tags}
+	if got := review.ToProto().Tags; !reflect.DeepEqual(got, want) {
+		t.Errorf("review tags = %v, want %v", got, want)
+	}
+	item := models.WishlistItem{Tags: tags}
+	if got := item.ToProto().Tags; !reflect.DeepEqual(got, want) {
+		t.Errorf("wishlist tags = %v, want %v", got, want)
+	}
+	if got := (&models.Review{}).ToProto().Tags; got == nil || len(got) != 0 {
+		t.Errorf("untagged review tags = %#v, want an empty list", got)
+	}
+}
+
+func TestTagToProto_Deprecated(t *testing.T) {
+	tag := models.Tag{Slug: "thai"}
+	if tag.ToProto().Deprecated {
+		t.Error("expected an active tag")
+	}
+	now := time.Now()
+	tag.DeprecatedAt = &now
+	if !tag.ToProto().Deprecated {
+		t.Error("expected a deprecated tag")
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	want := []models.Tag{{Slug: "ramen", Label: "Ramen", Category: "Cuisine", Translations: []models.TagTranslation{
+		{TagSlug: "ramen", Locale: "de", Label: "Ramen"},
+		{TagSlug: "ramen", Locale: "pl", Label: "Ramen (zupa)"},
+	}}}
+	for name, raw := range map[string]string{
+		"yaml": "- slug: ramen\n  label: Ramen\n  category: Cuisine\n  labels: {pl: Ramen (zupa), de: Ramen}\n",
+		"json": `[{"slug": "ramen", "label": "Ramen", "category": "Cuisine", "labels": {"pl": "Ramen (zupa)", "de": "Ramen"}}]`,
+	} {
+		got, err := utils.ParseTags([]byte(raw))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, err := utils.ParseTags([]byte("- slug: ramen\n  lable: Ramen\n")); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if tags, err := utils.ParseTags(nil); err != nil || len(tags) != 0 {
+		t.Errorf("empty file: got %v, %v", tags, err)
+	}
+}
+
+func TestNormalizeTagLocale(t *testing.T) {
+	cases := map[string]string{
+		"pl":      "pl",
+		"PL":      "pl",
+		"pl-PL":   "pl",
+		"en_US":   "en",
+		" fil ":   "fil",
+		"":        "",
+		"polish":  "",
+		"p1":      "",
+		"*":       "",
+		"pl-PL-x": "pl",
+	}
+	for in, want := range cases {
+		if got := models.NormalizeTagLocale(in); got != want {
+			t.Errorf("NormalizeTagLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRequiredTags_HavePolishLabels(t *testing.T) {
+	for _, tag := range utils.RequiredTags {
+		found := false
+		for _, tr := range tag.Translations {
+			if tr.Locale == "pl" && tr.Label != "" && tr.TagSlug == tag.Slug {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("tag %q has no Polish label", tag.Slug)
+		}
+	}
+}
+
+func TestImportTags_RejectsInvalidTranslations(t *testing.T) {
+	for name, tr := range map[string]models.TagTranslation{
+		"english":      {Locale: "en", Label: "Ramen"},
+		"region":       {Locale: "pl-PL", Label: "Ramen"},
+		"empty label":  {Locale: "pl", Label: " "},
+		"not a locale": {Locale: "polish", Label: "Ramen"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tag := models.Tag{Slug: "ramen", Label: "Ramen", Category: "Cuisine", Translations: []models.TagTranslation{tr}}
+			if err := services.ImportTags(context.Background(), nil, nil, []models.Tag{tag}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPersonalTagSlug(t *testing.T) {
+	for label, want := range map[string]string{
+		"Near office":          "near-office",
+		"team-lunch-approved":  "team-lunch-approved",
+		"  Pho  & Bún!! 2024 ": "pho-b-n-2024",
+		"!!!":                  "",
+		"":                     "",
+	} {
+		if got := models.PersonalTagSlug(label); got != want {
+			t.Errorf("PersonalTagSlug(%q) = %q, want %q", label, got, want)
+		}
+	}
+}
+
+func TestReviewToPublicProto_HidesPersonalTags(t *testing.T) {
+	review := models.Review{PersonalTags: []models.PersonalTag{{Slug: "near-office", Label: "Near office"}}}
+	if got := review.ToProto().PersonalTags; len(got) != 1 || got[0].Slug != "near-office" {
+		t.Fatalf("ToProto personal tags = %v", got)
+	}
+	if got := review.ToPublicProto().PersonalTags; len(got) != 0 {
+		t.Errorf("ToPublicProto personal tags = %v, want none", got)
+	}
+}
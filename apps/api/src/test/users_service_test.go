@@ -0,0 +1,119 @@
+package test
+
+import (
+	circlesv1 "api/src/generated/circles/v1"
+	usersv1 "api/src/generated/users/v1"
+	"api/src/internal/models"
+	"api/src/services"
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+)
+
+func TestUserService_GetUser_NoSession(t *testing.T) {
+	svc := &services.UserService{}
+	req := connect.NewRequest(&usersv1.GetUserRequest{Id: "some-id"})
+	_, err := svc.GetUser(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestUserService_GetPrivacySettings_NoSession(t *testing.T) {
+	svc := &services.UserService{}
+	req := connect.NewRequest(&usersv1.GetPrivacySettingsRequest{})
+	_, err := svc.GetPrivacySettings(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for missing session, got nil")
+	}
+	connectErr, ok := err.(*connect.Error)
+	if !ok {
+		t.Fatalf("expected *connect.Error, got %T", err)
+	}
+	if connectErr.Code() != connect.CodeUnauthenticated {
+		t.Fatalf("expected CodeUnauthenticated, got %v", connectErr.Code())
+	}
+}
+
+func TestPrivacySettings_Defaults(t *testing.T) {
+	p := models.DefaultPrivacySettings("u1")
+	if !p.DiscoverableByUsername || !p.DiscoverableByEmail || !p.ShowEmailToFriends {
+		t.Fatalf("expected defaults to keep the user discoverable, got %+v", p)
+	}
+	if p.FriendRequestPolicy != models.FriendRequestPolicyEveryone {
+		t.Fatalf("expected default policy %q, got %q", models.FriendRequestPolicyEveryone, p.FriendRequestPolicy)
+	}
+	if p.DefaultVisibilityProto() != circlesv1.Visibility_VISIBILITY_FRIENDS {
+		t.Fatalf("expected default visibility FRIENDS, got %v", p.DefaultVisibilityProto())
+	}
+	if p.SharePersonalTags {
+		t.Fatal("expected personal tags to be private by default")
+	}
+}
+
+func TestPrivacySettings_EmailVisibleTo(t *testing.T) {
+	p := models.DefaultPrivacySettings("u1")
+	if p.EmailVisibleTo(false) {
+		t.Fatal("expected email to be hidden from non-friends")
+	}
+	if !p.EmailVisibleTo(true) {
+		t.Fatal("expected email to be visible to friends by default")
+	}
+	p.ShowEmailToFriends = false
+	if p.EmailVisibleTo(true) {
+		t.Fatal("expected email to be hidden from friends when disabled")
+	}
+}
+
+func TestFriendRequestPolicy_RoundTrip(t *testing.T) {
+	cases := map[usersv1.FriendRequestPolicy]string{
+		usersv1.FriendRequestPolicy_FRIEND_REQUEST_POLICY_EVERYONE:           models.FriendRequestPolicyEveryone,
+		usersv1.FriendRequestPolicy_FRIEND_REQUEST_POLICY_FRIENDS_OF_FRIENDS: models.FriendRequestPolicyFriendsOfFriends,
+		usersv1.FriendRequestPolicy_FRIEND_REQUEST_POLICY_NOBODY:             models.FriendRequestPolicyNobody,
+	}
+	for v, stored := range cases {
+		if got := models.FriendRequestPolicyFromProto(v); got != stored {
+			t.Errorf("FriendRequestPolicyFromProto(%v) = %q, want %q", v, got, stored)
+		}
+		if got := models.FriendRequestPolicyToProto(stored); got != v {
+			t.Errorf("FriendRequestPolicyToProto(%q) = %v, want %v", stored, got, v)
+		}
+	}
+}
+
+func TestFriendRequest_ToProto_HidesCounterpartEmail(t *testing.T) {
+	senderEmail, receiverEmail := "a@example.com", "b@example.com"
+	fr := models.FriendRequest{
+		SenderID:   "a",
+		ReceiverID: "b",
+		Status:     models.FriendRequestStatusPending,
+		Sender:     models.User{Email: &senderEmail},
+		Receiver:   models.User{Email: &receiverEmail},
+	}
+
+	p := fr.ToProto("b", nil)
+	if p.SenderEmail != "" {
+		t.Fatalf("expected pending request to hide sender email, got %q", p.SenderEmail)
+	}
+	if p.ReceiverEmail != receiverEmail {
+		t.Fatalf("expected viewer's own email, got %q", p.ReceiverEmail)
+	}
+
+	fr.Status = models.FriendRequestStatusAccepted
+	hidden := models.DefaultPrivacySettings("a")
+	hidden.ShowEmailToFriends = false
+	if got := fr.ToProto("b", map[string]models.PrivacySettings{"a": hidden}).SenderEmail; got != "" {
+		t.Fatalf("expected sender email to respect show_email_to_friends, got %q", got)
+	}
+	if got := fr.ToProto("b", nil).SenderEmail; got != senderEmail {
+		t.Fatalf("expected accepted friend's email by default, got %q", got)
+	}
+}
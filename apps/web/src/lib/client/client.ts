@@ -8,6 +8,9 @@ import { ReviewsService } from '$lib/client/generated/reviews/v1/reviews_service
 import { TagsService } from '$lib/client/generated/tags/v1/tags_service_pb';
 import { WishlistService } from '$lib/client/generated/wishlist/v1/wishlist_service_pb';
 import { FriendshipService } from '$lib/client/generated/friendship/v1/friendship_service_pb';
+import { CirclesService } from '$lib/client/generated/circles/v1/circles_service_pb';
+import { SharedListsService } from '$lib/client/generated/shared_lists/v1/shared_lists_service_pb';
+import { NotificationsService } from '$lib/client/generated/notifications/v1/notifications_service_pb';
 
 const baseUrl = import.meta.env.VITE_API_URL || 'http://localhost:3001';
 const transport = createConnectTransport({
@@ -25,5 +28,8 @@ const reviews = createClient(ReviewsService, transport);
 const tags = createClient(TagsService, transport);
 const wishlist = createClient(WishlistService, transport);
 const friendship = createClient(FriendshipService, transport);
+const circles = createClient(CirclesService, transport);
+const sharedLists = createClient(SharedListsService, transport);
+const notifications = createClient(NotificationsService, transport);
 
-export default { restaurants, users, googleMaps, auth, reviews, tags, wishlist, friendship };
+export default { restaurants, users, googleMaps, auth, reviews, tags, wishlist, friendship, circles, sharedLists, notifications };
@@ -0,0 +1,36 @@
+import client from '$lib/client/client';
+import type { TagProto } from '$lib/client/generated/tags/v1/tag_pb';
+import { getLocale } from '$lib/paraglide/runtime';
+
+let tags = $state<TagProto[]>([]);
+let loadedLocale: string | null = null;
+let pending: Promise<TagProto[]> | null = null;
+
+export const tagCatalog = {
+	get all() {
+		return tags;
+	},
+	// Fetches the tags in the current locale once; later calls share the result.
+	load(): Promise<TagProto[]> {
+		const locale = getLocale();
+		if (pending && loadedLocale === locale) return pending;
+		loadedLocale = locale;
+		pending = client.tags
+			.listTags({ locale })
+			.then((res) => {
+				tags = res.tags;
+				return res.tags;
+			})
+			.catch((err) => {
+				pending = null;
+				throw err;
+			});
+		return pending;
+	},
+	// The localized label for a slug, or the slug itself until tags have loaded (which
+	// the first call starts).
+	label(slug: string): string {
+		if (!pending || loadedLocale !== getLocale()) this.load().catch(() => {});
+		return tags.find((t) => t.slug === slug)?.label ?? slug;
+	}
+};
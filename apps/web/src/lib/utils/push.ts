@@ -0,0 +1,49 @@
+import client from '$lib/client/client';
+
+/** Whether this browser can receive Web Push at all. */
+export function pushSupported(): boolean {
+	return typeof window !== 'undefined' && 'serviceWorker' in navigator && 'PushManager' in window;
+}
+
+function base64UrlToBytes(value: string): Uint8Array<ArrayBuffer> {
+	const base64 = (value + '='.repeat((4 - (value.length % 4)) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+	return Uint8Array.from(atob(base64), (c) => c.charCodeAt(0));
+}
+
+/**
+ * Asks for notification permission, subscribes this device and registers it with the API.
+ * Returns false if the server has push disabled or the user declined.
+ */
+export async function enablePushNotifications(): Promise<boolean> {
+	if (!pushSupported()) return false;
+	const config = await client.notifications.getPushConfig({});
+	if (!config.enabled) return false;
+	if ((await Notification.requestPermission()) !== 'granted') return false;
+
+	const registration = await navigator.serviceWorker.ready;
+	const subscription =
+		(await registration.pushManager.getSubscription()) ??
+		(await registration.pushManager.subscribe({
+			userVisibleOnly: true,
+			applicationServerKey: base64UrlToBytes(config.vapidPublicKey)
+		}));
+
+	const json = subscription.toJSON();
+	await client.notifications.registerPushSubscription({
+		endpoint: subscription.endpoint,
+		p256dh: json.keys?.p256dh ?? '',
+		auth: json.keys?.auth ?? '',
+		userAgent: navigator.userAgent
+	});
+	return true;
+}
+
+/** Unsubscribes this device and tells the API to stop pushing to it. */
+export async function disablePushNotifications(): Promise<void> {
+	if (!pushSupported()) return;
+	const registration = await navigator.serviceWorker.ready;
+	const subscription = await registration.pushManager.getSubscription();
+	if (!subscription) return;
+	await client.notifications.unregisterPushSubscription({ endpoint: subscription.endpoint });
+	await subscription.unsubscribe();
+}
@@ -0,0 +1,33 @@
+/// <reference types="@sveltejs/kit" />
+/// <reference no-default-lib="true"/>
+/// <reference lib="esnext" />
+/// <reference lib="webworker" />
+
+// Only handles Web Push; nothing is cached.
+const sw = self as unknown as ServiceWorkerGlobalScope;
+
+type PushPayload = { title: string; body: string; url: string; tag: string };
+
+sw.addEventListener('push', (event) => {
+	if (!event.data) return;
+	const payload = event.data.json() as PushPayload;
+	event.waitUntil(
+		sw.registration.showNotification(payload.title, {
+			body: payload.body,
+			tag: payload.tag,
+			icon: '/resto-rate-logo.svg',
+			data: { url: payload.url }
+		})
+	);
+});
+
+sw.addEventListener('notificationclick', (event) => {
+	event.notification.close();
+	const url: string = event.notification.data?.url ?? '/';
+	event.waitUntil(
+		sw.clients.matchAll({ type: 'window', includeUncontrolled: true }).then((windows) => {
+			const existing = windows.find((w) => w.url === url);
+			return existing ? existing.focus() : sw.clients.openWindow(url);
+		})
+	);
+});